@@ -0,0 +1,275 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/renderer"
+	"github.com/gdamore/tcell/v2"
+)
+
+var benchCommand = &command{
+	name:     "bench",
+	synopsis: "bench [options]",
+	summary: "Measure how fast this terminal can display frames, using the real\n" +
+		"rendering path with synthetic images, and suggest a -fps setting.\n" +
+		"Press Q or Esc to stop early.",
+	setup: setupBench,
+}
+
+// Synthetic content with different amounts of change per frame
+type benchPattern struct {
+	name string
+	draw func(img *image.RGBA, n int)
+}
+
+var benchPatterns = []benchPattern{
+	{"noise", drawNoise},
+	{"gradient", drawGradient},
+	{"static", drawStatic},
+}
+
+// Share of the usable screen area for each size step
+var benchScales = []float64{0.5, 1}
+
+// Frame rates offered as suggestions, highest first
+var benchSuggestions = []float64{30, 24, 20, 15, 12, 10, 8, 5}
+
+type benchResult struct {
+	pattern string
+	cols    int
+	rows    int
+	cache   bool
+	frames  int
+	fps     float64
+	showP50 time.Duration
+	showP95 time.Duration
+}
+
+func setupBench(fs *flag.FlagSet) func(*globalOptions, []string) int {
+	perTest := fs.Duration("duration", time.Second, "How long each measurement runs")
+
+	return func(g *globalOptions, args []string) int {
+		if len(args) != 0 {
+			return usageError(fs, "bench takes no arguments")
+		}
+		if *perTest <= 0 {
+			return usageError(fs, "-duration must be positive")
+		}
+
+		results, err := runBench(*perTest)
+		if err != nil {
+			return fail(err)
+		}
+		printBenchResults(results)
+		return exitOK
+	}
+}
+
+func runBench(perTest time.Duration) ([]benchResult, error) {
+	r, err := renderer.New()
+	if err != nil {
+		return nil, err
+	}
+	// Restore the terminal before anything is printed, even on panic
+	defer r.Close()
+
+	quit := make(chan struct{})
+	var quitOnce sync.Once
+	stop := func() { quitOnce.Do(func() { close(quit) }) }
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigChan)
+	go func() {
+		select {
+		case <-sigChan:
+			stop()
+		case <-quit:
+		}
+	}()
+
+	go func() {
+		for {
+			ev := r.Screen().PollEvent()
+			if ev == nil {
+				return
+			}
+			if key, ok := ev.(*tcell.EventKey); ok {
+				switch {
+				case key.Key() == tcell.KeyEscape, key.Key() == tcell.KeyCtrlC,
+					key.Rune() == 'q', key.Rune() == 'Q':
+					stop()
+				}
+			}
+		}
+	}()
+
+	var results []benchResult
+
+	screenW, screenH := r.Size()
+	for _, scale := range benchScales {
+		cols := max(int(float64(screenW)*scale), 2)
+		rows := max(int(float64(screenH-1)*scale), 1)
+		img := image.NewRGBA(image.Rect(0, 0, cols, rows*2))
+
+		for _, pattern := range benchPatterns {
+			for _, cache := range []bool{true, false} {
+				res := benchOne(r, img, pattern, cache, perTest, quit)
+				res.cols, res.rows = cols, rows
+				results = append(results, res)
+
+				select {
+				case <-quit:
+					return results, nil
+				default:
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+// Renders the pattern as fast as the terminal accepts it for d
+func benchOne(r *renderer.Renderer, img *image.RGBA, pattern benchPattern,
+	cache bool, d time.Duration, quit <-chan struct{}) benchResult {
+	res := benchResult{pattern: pattern.name, cache: cache}
+	r.Clear()
+	r.InvalidateCache()
+
+	var shows []time.Duration
+	start := time.Now()
+loop:
+	for time.Since(start) < d {
+		select {
+		case <-quit:
+			break loop
+		default:
+		}
+
+		pattern.draw(img, res.frames)
+		if !cache {
+			r.InvalidateCache()
+		}
+		r.RenderImage(img, 0, 0)
+		r.DrawText(0, img.Bounds().Dy()/2, fmt.Sprintf(" bench: %s %dx%d cache %s ",
+			pattern.name, img.Bounds().Dx(), img.Bounds().Dy()/2, onOff(cache)),
+			tcell.StyleDefault.Reverse(true))
+
+		t := time.Now()
+		r.Show()
+		shows = append(shows, time.Since(t))
+		res.frames++
+	}
+
+	elapsed := time.Since(start)
+	if elapsed > 0 {
+		res.fps = float64(res.frames) / elapsed.Seconds()
+	}
+	if len(shows) > 0 {
+		slices.Sort(shows)
+		res.showP50 = shows[len(shows)/2]
+		res.showP95 = shows[len(shows)*95/100]
+	}
+	return res
+}
+
+func printBenchResults(results []benchResult) {
+	if len(results) == 0 {
+		fmt.Println("No measurements taken")
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "pattern\tsize\tcache\tfps\tshow p50\tshow p95\t")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%dx%d\t%s\t%.1f\t%.1fms\t%.1fms\t\n", r.pattern, r.cols, r.rows,
+			onOff(r.cache), r.fps, msec(r.showP50), msec(r.showP95))
+	}
+	tw.Flush()
+
+	// Full-size gradient with the cache on is closest to real video: nearly
+	// every cell changes, but colors shift smoothly
+	best := results[0]
+	for _, r := range results {
+		if r.pattern == "gradient" && r.cache && r.cols*r.rows >= best.cols*best.rows {
+			best = r
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("Your terminal sustains ~%.0f fps at %dx%d.\n", best.fps, best.cols, best.rows)
+	// Leave headroom for decoding and frame-to-frame variation
+	for _, s := range benchSuggestions {
+		if s <= best.fps*0.85 {
+			if s < benchSuggestions[0] {
+				fmt.Printf("Suggested: pixlgo play -fps %.0f FILE\n", s)
+			} else {
+				fmt.Println("No frame rate cap needed.")
+			}
+			return
+		}
+	}
+	fmt.Println("Suggested: pixlgo play -fps 5 -max-cpu 50 FILE, or a smaller terminal window")
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func msec(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func drawNoise(img *image.RGBA, _ int) {
+	pix := img.Pix
+	for i := 0; i+8 <= len(pix); i += 8 {
+		v := rand.Uint64() | 0xff000000ff000000
+		for b := range 8 {
+			pix[i+b] = byte(v >> (8 * b))
+		}
+	}
+}
+
+func drawGradient(img *image.RGBA, n int) {
+	b := img.Bounds()
+	w, h := max(b.Dx(), 1), max(b.Dy(), 1)
+	for y := range b.Dy() {
+		off := y * img.Stride
+		for x := range b.Dx() {
+			img.Pix[off] = byte(x*255/w + n*3)
+			img.Pix[off+1] = byte(y*255/h + n*2)
+			img.Pix[off+2] = byte((x+y)*128/(w+h) + n)
+			img.Pix[off+3] = 255
+			off += 4
+		}
+	}
+}
+
+// Fixed background with a small square moving across it
+func drawStatic(img *image.RGBA, n int) {
+	b := img.Bounds()
+	drawGradient(img, 0)
+	const size = 8
+	span := max(b.Dx()-size, 1)
+	x0 := n % span
+	y0 := b.Dy()/2 - size/2
+	for y := max(y0, 0); y < min(y0+size, b.Dy()); y++ {
+		for x := x0; x < x0+size && x < b.Dx(); x++ {
+			off := img.PixOffset(x, y)
+			img.Pix[off], img.Pix[off+1], img.Pix[off+2] = byte(n*7), 255, byte(255-n*7)
+		}
+	}
+}
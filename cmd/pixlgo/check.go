@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/renderer"
+	"github.com/0bVdnt/PixlGo/internal/video"
+)
+
+var checkCommand = &command{
+	name:     "check",
+	synopsis: "check [options]",
+	summary: "Check ffmpeg, ffprobe and the terminal, with a remedy for each problem.\n" +
+		"Exits non-zero if any check fails.",
+	setup: setupCheck,
+}
+
+type checkStatus string
+
+const (
+	checkPass checkStatus = "pass"
+	checkWarn checkStatus = "warn"
+	checkFail checkStatus = "fail"
+)
+
+// One line of the report
+type checkResult struct {
+	Name   string      `json:"name"`
+	Status checkStatus `json:"status"`
+	Detail string      `json:"detail"`
+	Remedy string      `json:"remedy,omitempty"`
+}
+
+const checkTimeout = 5 * time.Second
+
+func setupCheck(fs *flag.FlagSet) func(*globalOptions, []string) int {
+	asJSON := fs.Bool("json", false, "Print JSON instead of a report")
+
+	return func(g *globalOptions, args []string) int {
+		if len(args) != 0 {
+			return usageError(fs, "check takes no arguments")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+		defer cancel()
+
+		var results []checkResult
+		results = append(results, checkTools(ctx)...)
+		results = append(results, checkTerminal()...)
+
+		if *asJSON {
+			data, _ := json.MarshalIndent(results, "", "  ")
+			fmt.Println(string(data))
+		} else {
+			printCheckReport(results)
+		}
+
+		for _, r := range results {
+			if r.Status == checkFail {
+				return exitError
+			}
+		}
+		return exitOK
+	}
+}
+
+func printCheckReport(results []checkResult) {
+	width := 0
+	for _, r := range results {
+		width = max(width, len(r.Name))
+	}
+	for _, r := range results {
+		fmt.Printf("[%-4s] %-*s  %s\n", strings.ToUpper(string(r.Status)), width, r.Name, r.Detail)
+		if r.Remedy != "" && r.Status != checkPass {
+			fmt.Printf("       %*s  -> %s\n", width, "", r.Remedy)
+		}
+	}
+}
+
+func checkTools(ctx context.Context) []checkResult {
+	var results []checkResult
+	ffmpegFound := false
+
+	for _, name := range []string{"ffmpeg", "ffprobe"} {
+		t := video.DetectTool(ctx, name)
+		r := checkResult{Name: name}
+		switch {
+		case !t.Found:
+			r.Status = checkFail
+			r.Detail = "not found"
+			r.Remedy = "install FFmpeg and make sure " + name + " is on PATH or next to pixlgo"
+		case !t.MeetsMinimum():
+			r.Status = checkWarn
+			r.Detail = fmt.Sprintf("%s (%s)", t.Version, t.Path)
+			r.Remedy = fmt.Sprintf("upgrade to FFmpeg %d or newer", video.MinFFmpegMajor)
+		default:
+			r.Status = checkPass
+			r.Detail = fmt.Sprintf("%s (%s)", t.Version, t.Path)
+		}
+		results = append(results, r)
+		ffmpegFound = ffmpegFound || name == "ffmpeg" && t.Found
+	}
+	if !ffmpegFound {
+		return results
+	}
+
+	filters := checkResult{Name: "ffmpeg filters"}
+	if have, err := video.FFmpegFilters(ctx); err != nil {
+		filters.Status = checkWarn
+		filters.Detail = "could not list filters: " + err.Error()
+	} else {
+		var missing []string
+		for _, f := range video.RequiredFilters {
+			if !have[f] {
+				missing = append(missing, f)
+			}
+		}
+		if len(missing) > 0 {
+			filters.Status = checkFail
+			filters.Detail = "missing: " + strings.Join(missing, ", ")
+			filters.Remedy = "install a full FFmpeg build rather than a minimal one"
+		} else {
+			filters.Status = checkPass
+			filters.Detail = strings.Join(video.RequiredFilters, ", ")
+		}
+	}
+	results = append(results, filters)
+
+	hw := checkResult{Name: "hwaccels", Status: checkPass}
+	if accels, err := video.FFmpegHWAccels(ctx); err != nil || len(accels) == 0 {
+		hw.Status = checkWarn
+		hw.Detail = "none"
+		hw.Remedy = "optional; software decoding works but uses more CPU"
+	} else {
+		hw.Detail = strings.Join(accels, ", ")
+	}
+	return append(results, hw)
+}
+
+func checkTerminal() []checkResult {
+	var results []checkResult
+	term := os.Getenv("TERM")
+
+	tty := checkResult{Name: "terminal", Status: checkPass, Detail: orNA(term)}
+	if prog := os.Getenv("TERM_PROGRAM"); prog != "" {
+		tty.Detail += " (" + prog + ")"
+	}
+	if !isTerminal(os.Stdout) {
+		tty.Status = checkWarn
+		tty.Detail += ", stdout is not a terminal"
+		tty.Remedy = "run pixlgo check directly in the terminal you play videos in"
+	}
+	results = append(results, tty)
+
+	colors := checkResult{Name: "colors"}
+	colorterm := strings.ToLower(os.Getenv("COLORTERM"))
+	switch {
+	case colorterm == "truecolor" || colorterm == "24bit" || os.Getenv("WT_SESSION") != "":
+		colors.Status, colors.Detail = checkPass, "24-bit"
+	case strings.Contains(term, "256color"):
+		colors.Status, colors.Detail = checkWarn, "256 colors"
+		colors.Remedy = "use a true color terminal, or set COLORTERM=truecolor if yours is one"
+	default:
+		colors.Status, colors.Detail = checkWarn, "unknown, assuming 256 or fewer"
+		colors.Remedy = "use a true color terminal, or set COLORTERM=truecolor if yours is one"
+	}
+	results = append(results, colors)
+
+	utf8 := checkResult{Name: "utf-8", Status: checkPass, Detail: "enabled"}
+	if runtime.GOOS != "windows" {
+		locale := firstNonEmpty(os.Getenv("LC_ALL"), os.Getenv("LC_CTYPE"), os.Getenv("LANG"))
+		l := strings.ToLower(locale)
+		if !strings.Contains(l, "utf-8") && !strings.Contains(l, "utf8") {
+			utf8.Status = checkWarn
+			utf8.Detail = "locale " + orNA(locale)
+			utf8.Remedy = "set LANG to a UTF-8 locale, e.g. export LANG=en_US.UTF-8"
+		}
+	}
+	results = append(results, utf8)
+
+	block := checkResult{Name: "half block width"}
+	if w, err := renderer.MeasureWidth("▀"); err != nil {
+		block.Status, block.Detail = checkWarn, "not measured: "+err.Error()
+	} else if w != 1 {
+		block.Status = checkFail
+		block.Detail = fmt.Sprintf("'▀' is %d cells wide", w)
+		block.Remedy = "disable ambiguous-width characters as wide in the terminal settings"
+	} else {
+		block.Status, block.Detail = checkPass, "'▀' is 1 cell wide"
+	}
+	results = append(results, block)
+
+	gfx := checkResult{Name: "graphics", Status: checkPass}
+	if g, err := renderer.DetectGraphics(); err != nil {
+		gfx.Detail = "unknown: " + err.Error()
+	} else if g.PassthroughBlocked {
+		gfx.Status, gfx.Detail = checkWarn, "tmux passthrough disabled"
+		gfx.Remedy = "add `set -g allow-passthrough on` to tmux.conf for sixel/kitty graphics"
+	} else {
+		var protos []string
+		if g.Sixel {
+			protos = append(protos, "sixel")
+		}
+		if g.Kitty {
+			protos = append(protos, "kitty")
+		}
+		gfx.Detail = orNA(strings.Join(protos, ", "))
+	}
+	results = append(results, gfx)
+
+	mux := checkResult{Name: "multiplexer", Status: checkPass, Detail: "none"}
+	switch m := renderer.DetectMultiplexer(); m {
+	case renderer.MuxTmux:
+		mux.Status, mux.Detail = checkWarn, m.String()
+		mux.Remedy = "add `set -as terminal-features ',*:RGB'` to tmux.conf for true color; tmux also slows large frames"
+	case renderer.MuxScreen:
+		mux.Status, mux.Detail = checkWarn, m.String()
+		mux.Remedy = "screen lacks true color support; run pixlgo outside it"
+	}
+	return append(results, mux)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func firstNonEmpty(values ...string) string {
+	i := slices.IndexFunc(values, func(s string) bool { return s != "" })
+	if i < 0 {
+		return ""
+	}
+	return values[i]
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/0bVdnt/PixlGo/internal/recording"
+	"github.com/0bVdnt/PixlGo/internal/renderer"
+	"github.com/0bVdnt/PixlGo/internal/video"
+)
+
+var convertCommand = &command{
+	name:     "convert",
+	synopsis: "convert [options] FILE -o OUTPUT",
+	summary: "Render a video, or a slice of it, to a terminal recording as fast as\n" +
+		"decoding allows: an asciinema v2 .cast file, or an .ans file that\n" +
+		"'pixlgo play' replays. Ctrl-C stops early and keeps what was written.",
+	setup: setupConvert,
+}
+
+const (
+	defaultConvertCols = 80
+	defaultConvertFPS  = 15
+)
+
+func setupConvert(fs *flag.FlagSet) func(*globalOptions, []string) int {
+	output := fs.String("o", "", "Output file")
+	format := fs.String("format", "", "Output format: cast or ans (default: from the -o extension)")
+	start := &timestampFlag{}
+	end := &timestampFlag{}
+	fs.Var(start, "start", "Start position")
+	fs.Var(end, "end", "End position (default: end of video)")
+	size := fs.String("size", strconv.Itoa(defaultConvertCols), "Size in cells: COLS or COLSxROWS (rows follow the aspect ratio if omitted)")
+	fps := fs.Float64("fps", defaultConvertFPS, "Frames per second (capped at the source rate)")
+	mode := fs.String("mode", "color", "Rendering: color (half blocks) or ascii")
+
+	return func(g *globalOptions, args []string) int {
+		if len(args) != 1 {
+			return usageError(fs, "expected exactly one file")
+		}
+		if *output == "" {
+			return usageError(fs, "no output file given (-o)")
+		}
+		if *format == "" {
+			*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*output)), ".")
+		}
+		if *format != "cast" && *format != "ans" {
+			return usageError(fs, "unknown format %q (use cast or ans)", *format)
+		}
+		render, pxPerRow, err := renderMode(*mode)
+		if err != nil {
+			return usageError(fs, "%v", err)
+		}
+		if end.d > 0 && end.d <= start.d {
+			return usageError(fs, "-end must be after -start")
+		}
+		if *fps <= 0 {
+			return usageError(fs, "-fps must be positive")
+		}
+		path := args[0]
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		meta, err := video.Probe(path)
+		if err == nil {
+			err = meta.RequireVideo()
+		}
+		if err != nil {
+			return fail(err, "file", path)
+		}
+		cols, rows, err := parseCellSize(*size, meta.DisplayAspect())
+		if err != nil {
+			return usageError(fs, "%v", err)
+		}
+		// ffmpeg scales to even pixel sizes; keep the cell grid in step
+		cols = max(cols&^1, 4)
+		if pxPerRow == 1 {
+			rows = max(rows&^1, 4)
+		}
+
+		span := meta.Duration - start.d
+		if end.d > 0 {
+			span = end.d - start.d
+		}
+		config := video.StreamConfig{
+			Width:       cols,
+			Height:      rows * pxPerRow,
+			StartPos:    start.d,
+			TargetFPS:   min(*fps, meta.FPS),
+			StreamIndex: meta.StreamIndex,
+			ColorRange:  meta.ColorRange,
+			Rotation:    meta.Rotation,
+		}
+		if end.d > 0 {
+			config.Duration = span
+		}
+
+		file, err := os.Create(*output)
+		if err != nil {
+			return fail(err)
+		}
+		defer file.Close()
+
+		var rec recording.Writer
+		if *format == "cast" {
+			rec, err = recording.NewCastWriter(file, cols, rows, filepath.Base(path))
+		} else {
+			rec, err = recording.NewANSWriter(file, cols, rows)
+		}
+		if err != nil {
+			return fail(err, "file", *output)
+		}
+
+		frames := 0
+		lastPct := -1
+		err = video.DecodeAll(ctx, path, config, func(f *video.Frame) error {
+			if err := rec.WriteFrame(f.Timestamp-start.d, render(f.Image)); err != nil {
+				return err
+			}
+			frames++
+			if span > 0 {
+				if pct := int(100 * (f.Timestamp - start.d) / span); pct != lastPct && pct <= 100 {
+					lastPct = pct
+					fmt.Fprintf(os.Stderr, "\rConverting: %3d%%", pct)
+				}
+			}
+			return nil
+		})
+		if lastPct >= 0 {
+			fmt.Fprintln(os.Stderr)
+		}
+
+		if cerr := rec.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		switch {
+		case errors.Is(err, context.Canceled):
+			fmt.Fprintf(os.Stderr, "Interrupted: wrote %d frames to %s\n", frames, *output)
+			return interrupted(exitInterrupted, os.Interrupt)
+		case err != nil:
+			return fail(err, "file", path)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d frames (%dx%d cells) to %s\n", frames, cols, rows, *output)
+		return exitOK
+	}
+}
+
+// Returns the text renderer for a mode and how many pixel rows fit a cell
+func renderMode(mode string) (func(*image.RGBA) string, int, error) {
+	switch mode {
+	case "color":
+		return renderer.RenderColor, 2, nil
+	case "ascii":
+		return renderer.RenderASCII, 1, nil
+	}
+	return nil, 0, fmt.Errorf("unknown mode %q (use color or ascii)", mode)
+}
+
+// Parses "COLS" or "COLSxROWS". Missing rows follow the aspect ratio with
+// cells twice as tall as wide.
+func parseCellSize(s string, aspect float64) (int, int, error) {
+	colStr, rowStr, hasRows := strings.Cut(strings.ToLower(s), "x")
+	cols, err := strconv.Atoi(colStr)
+	if err != nil || cols < 2 {
+		return 0, 0, fmt.Errorf("invalid size %q", s)
+	}
+	if hasRows {
+		rows, err := strconv.Atoi(rowStr)
+		if err != nil || rows < 2 {
+			return 0, 0, fmt.Errorf("invalid size %q", s)
+		}
+		return cols, rows, nil
+	}
+	if aspect <= 0 {
+		aspect = 16.0 / 9
+	}
+	return cols, max(int(float64(cols)/aspect/2+0.5), 2), nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+
+	"github.com/0bVdnt/PixlGo/internal/video"
+)
+
+// Exit codes. Scripts can rely on these; keep them stable.
+const (
+	exitOK          = 0   // played to completion, or the user quit
+	exitError       = 1   // anything not covered below
+	exitUsage       = 2   // bad arguments
+	exitInput       = 3   // input not found or unreadable
+	exitNoFFmpeg    = 4   // ffmpeg or ffprobe missing
+	exitDecode      = 5   // probing or decoding failed
+	exitInterrupted = 130 // SIGINT; other signals use 128+signo too
+)
+
+// Short names for the codes in JSON errors
+var exitNames = map[int]string{
+	exitError:    "error",
+	exitUsage:    "usage",
+	exitInput:    "input",
+	exitNoFFmpeg: "ffmpeg_missing",
+	exitDecode:   "decode",
+}
+
+// Set by -json-errors: errors are collected and the last one is printed as
+// a single JSON object on stderr just before exiting
+var (
+	jsonErrors bool
+	pending    *errorReport
+)
+
+type errorReport struct {
+	Code    int            `json:"code"`
+	Name    string         `json:"error"`
+	Message string         `json:"message"`
+	Context map[string]any `json:"context,omitempty"`
+}
+
+// Maps an error to its exit code
+func exitCodeFor(err error) int {
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(err, video.ErrFFmpegNotFound), errors.Is(err, exec.ErrNotFound):
+		return exitNoFFmpeg
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, fs.ErrPermission):
+		return exitInput
+	case errors.Is(err, video.ErrDecodeFailed), errors.Is(err, video.ErrNoVideoStream),
+		errors.Is(err, context.DeadlineExceeded), errors.As(err, &exitErr):
+		return exitDecode
+	}
+	return exitError
+}
+
+// Reports err and returns the exit code it maps to. context is key/value
+// pairs; a "file" key also prefixes the text message.
+func fail(err error, context ...any) int {
+	return failCode(exitCodeFor(err), err, context...)
+}
+
+// Reports err with an explicit exit code and returns the code. Every error
+// that ends a command goes through here.
+func failCode(code int, err error, context ...any) int {
+	report := &errorReport{Code: code, Name: exitNames[code], Message: err.Error()}
+	if report.Name == "" {
+		report.Name = "signal"
+	}
+	for i := 0; i+1 < len(context); i += 2 {
+		if report.Context == nil {
+			report.Context = map[string]any{}
+		}
+		report.Context[fmt.Sprint(context[i])] = context[i+1]
+	}
+
+	if jsonErrors {
+		pending = report
+		return code
+	}
+	if file, ok := report.Context["file"]; ok {
+		fmt.Fprintf(os.Stderr, "Error: %v: %s\n", file, report.Message)
+	} else {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", report.Message)
+	}
+	return code
+}
+
+// Records an exit caused by a signal. Only JSON mode reports it; on a
+// terminal the interruption speaks for itself.
+func interrupted(code int, sig os.Signal) int {
+	if jsonErrors {
+		failCode(code, fmt.Errorf("interrupted by %v", sig))
+	}
+	return code
+}
+
+// Prints the pending JSON error, unless the command ended successfully
+func flushErrors(code int) {
+	if pending == nil || code == exitOK {
+		return
+	}
+	data, _ := json.Marshal(pending)
+	fmt.Fprintf(os.Stderr, "%s\n", data)
+	pending = nil
+}
+
+// Whether -json-errors appears anywhere in args, so even flag parsing
+// errors before it is reached are reported as JSON
+func wantsJSONErrors(args []string) bool {
+	for _, a := range args {
+		if a == "--" {
+			break
+		}
+		if a == "-json-errors" || a == "--json-errors" || a == "-json-errors=true" || a == "--json-errors=true" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/renderer"
+	"github.com/0bVdnt/PixlGo/internal/video"
+)
+
+var frameCommand = &command{
+	name:     "frame",
+	synopsis: "frame [options] FILE",
+	summary: "Extract a single frame as PNG/JPEG (chosen by the -o extension),\n" +
+		"or print it as ANSI half blocks with -ansi.",
+	setup: setupFrame,
+}
+
+// Default ANSI width in columns when -width isn't given
+const ansiDefaultWidth = 80
+
+func setupFrame(fs *flag.FlagSet) func(*globalOptions, []string) int {
+	at := &timestampFlag{}
+	fs.Var(at, "t", "Position of the frame: seconds, [h:]m:s or a duration like 1m23s")
+	output := fs.String("o", "", "Output image file (.png, .jpg); - writes PNG to stdout")
+	width := fs.Int("width", 0, "Scale to this width, keeping the aspect ratio (default: original size, or 80 with -ansi)")
+	ansi := fs.Bool("ansi", false, "Print the frame as ANSI half blocks to stdout instead of writing an image")
+
+	return func(g *globalOptions, args []string) int {
+		if len(args) != 1 {
+			return usageError(fs, "expected exactly one file")
+		}
+		if *ansi == (*output != "") {
+			return usageError(fs, "need exactly one of -o or -ansi")
+		}
+		path := args[0]
+
+		var encode func(io.Writer, image.Image) error
+		if !*ansi {
+			var err error
+			if encode, err = imageEncoder(*output); err != nil {
+				return usageError(fs, "%v", err)
+			}
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		meta, err := video.Probe(path)
+		if err == nil {
+			err = meta.RequireVideo()
+		}
+		if err != nil {
+			return fail(err, "file", path)
+		}
+
+		pos := at.d
+		if meta.Duration > 0 && pos >= meta.Duration {
+			// Seeking to the very end yields nothing; step back like the player
+			clamped := max(meta.Duration-time.Second, 0)
+			fmt.Fprintf(os.Stderr, "Warning: %s is past the end (%s), using %s\n",
+				formatDuration(pos), formatDuration(meta.Duration), formatDuration(clamped))
+			pos = clamped
+		}
+
+		w := *width
+		if w <= 0 {
+			w = meta.DisplayWidth
+			if *ansi {
+				w = min(w, ansiDefaultWidth)
+			}
+		}
+		h := int(float64(w)/meta.DisplayAspect() + 0.5)
+
+		frame, err := video.ExtractSingleFrame(ctx, path, meta, pos, w, h)
+		if err != nil {
+			return fail(err, "file", path)
+		}
+
+		if *ansi {
+			fmt.Print(renderer.RenderColor(frame.Image))
+			return exitOK
+		}
+		if err := writeImage(*output, frame.Image, encode); err != nil {
+			return fail(err)
+		}
+		return exitOK
+	}
+}
+
+// Picks the encoder from the output file extension
+func imageEncoder(output string) (func(io.Writer, image.Image) error, error) {
+	if output == "-" {
+		return png.Encode, nil
+	}
+	switch strings.ToLower(filepath.Ext(output)) {
+	case ".png":
+		return png.Encode, nil
+	case ".jpg", ".jpeg":
+		return func(w io.Writer, img image.Image) error {
+			return jpeg.Encode(w, img, &jpeg.Options{Quality: 90})
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported image format %q (use .png or .jpg)", filepath.Ext(output))
+}
+
+func writeImage(output string, img image.Image, encode func(io.Writer, image.Image) error) error {
+	if output == "-" {
+		return encode(os.Stdout, img)
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := encode(file, img); err != nil {
+		file.Close()
+		return fmt.Errorf("%s: %w", output, err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/recording"
+	"github.com/0bVdnt/PixlGo/internal/renderer"
+	"github.com/0bVdnt/PixlGo/internal/video"
+)
+
+var gifCommand = &command{
+	name:     "gif",
+	synopsis: "gif [options] FILE -o OUTPUT.gif",
+	summary: "Record a segment as an animated GIF of how it looks in the terminal:\n" +
+		"each half-block cell is drawn as pixels and the result quantized to 256 colors.",
+	setup: setupGIF,
+}
+
+const (
+	defaultGIFCols     = 60
+	defaultGIFScale    = 4
+	defaultGIFFPS      = 10
+	defaultGIFDuration = 5 * time.Second
+)
+
+func setupGIF(fs *flag.FlagSet) func(*globalOptions, []string) int {
+	output := fs.String("o", "", "Output GIF file")
+	start := &timestampFlag{}
+	length := &timestampFlag{d: defaultGIFDuration}
+	fs.Var(start, "start", "Start position")
+	fs.Var(length, "duration", "Length of the segment")
+	size := fs.String("size", strconv.Itoa(defaultGIFCols), "Size in cells: COLS or COLSxROWS (rows follow the aspect ratio if omitted)")
+	scale := fs.Int("scale", defaultGIFScale, "Pixels per cell horizontally (cells are twice as tall)")
+	fps := fs.Float64("fps", defaultGIFFPS, "Maximum frames per second")
+
+	return func(g *globalOptions, args []string) int {
+		if len(args) != 1 {
+			return usageError(fs, "expected exactly one file")
+		}
+		if *output == "" {
+			return usageError(fs, "no output file given (-o)")
+		}
+		if length.d <= 0 {
+			return usageError(fs, "-duration must be positive")
+		}
+		if *scale < 1 || *fps <= 0 {
+			return usageError(fs, "-scale and -fps must be positive")
+		}
+		path := args[0]
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		meta, err := video.Probe(path)
+		if err == nil {
+			err = meta.RequireVideo()
+		}
+		if err != nil {
+			return fail(err, "file", path)
+		}
+		cols, rows, err := parseCellSize(*size, meta.DisplayAspect())
+		if err != nil {
+			return usageError(fs, "%v", err)
+		}
+		cols = max(cols&^1, 4)
+
+		targetFPS := min(*fps, meta.FPS)
+		config := video.StreamConfig{
+			Width:       cols,
+			Height:      rows * 2,
+			StartPos:    start.d,
+			TargetFPS:   targetFPS,
+			StreamIndex: meta.StreamIndex,
+			ColorRange:  meta.ColorRange,
+			Rotation:    meta.Rotation,
+			Duration:    length.d,
+		}
+
+		file, err := os.Create(*output)
+		if err != nil {
+			return fail(err)
+		}
+		defer file.Close()
+
+		frameDur := time.Duration(float64(time.Second) / targetFPS)
+		enc := recording.NewGIFWriter(file, frameDur)
+		var paletted *image.Paletted
+		frames := 0
+
+		err = video.DecodeAll(ctx, path, config, func(f *video.Frame) error {
+			pixels := renderer.Rasterize(f.Image, *scale)
+			if paletted == nil {
+				paletted = image.NewPaletted(pixels.Bounds(), palette.Plan9)
+			}
+			// Plain nearest-color mapping keeps cell edges crisp and stops
+			// dither noise from changing between frames
+			draw.Draw(paletted, paletted.Bounds(), pixels, image.Point{}, draw.Src)
+			frames++
+			fmt.Fprintf(os.Stderr, "\rEncoding: %3d%%", min(100, int(100*(f.Timestamp-start.d)/length.d)))
+			return enc.WriteFrame(paletted, f.Timestamp-start.d)
+		})
+		if frames > 0 {
+			fmt.Fprintln(os.Stderr)
+		}
+
+		stopped := errors.Is(err, context.Canceled)
+		if err != nil && !stopped {
+			return fail(err, "file", path)
+		}
+		if err := enc.Close(); err != nil {
+			return fail(err, "file", *output)
+		}
+		if stopped {
+			fmt.Fprintf(os.Stderr, "Interrupted: wrote %d frames to %s\n", frames, *output)
+			return interrupted(exitInterrupted, os.Interrupt)
+		}
+		b := paletted.Bounds()
+		fmt.Fprintf(os.Stderr, "Wrote %d frames (%dx%d px) to %s\n", frames, b.Dx(), b.Dy(), *output)
+		return exitOK
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/logger"
+	"github.com/0bVdnt/PixlGo/internal/video"
+)
+
+// Options shared by every subcommand
+type globalOptions struct {
+	debug       bool
+	logFile     string
+	logLevel    string
+	logFormat   string
+	logMaxSize  int64
+	logBackups  int
+	configPath  string
+	showVersion bool
+
+	// Binaries and extra options for every ffmpeg/ffprobe run
+	ffmpegPath  string
+	ffprobePath string
+	ffmpegArgs  string
+	ffprobeArgs string
+	ytdlpPath   string
+
+	// Request headers and cookie file for http(s) inputs
+	httpHeaders headerFlags
+	cookieFile  string
+
+	// Bound on either side of decoded frames
+	maxDimension int
+
+	// Flags given before the subcommand; the config file doesn't override them
+	explicit map[string]bool
+}
+
+// Registers the shared flags on fs. Defaults are the current values so a
+// command FlagSet doesn't undo what the global parse already set.
+func (g *globalOptions) register(fs *flag.FlagSet) {
+	if g.logFormat == "" {
+		g.logLevel = os.Getenv("PIXLGO_LOG_LEVEL")
+		g.logFormat = "text"
+		g.logMaxSize = logger.DefaultMaxSize >> 20
+		g.logBackups = logger.DefaultBackups
+		g.maxDimension = video.DefaultMaxDimension
+	}
+
+	fs.BoolVar(&g.debug, "debug", g.debug, "Enable debug logging to "+logPath())
+	fs.StringVar(&g.logFile, "log", g.logFile, "Enable logging to this file")
+	fs.StringVar(&g.logLevel, "log-level", g.logLevel,
+		"Minimum log level: debug, info, warn, error (default info, or debug with -debug; env PIXLGO_LOG_LEVEL)")
+	fs.StringVar(&g.logFormat, "log-format", g.logFormat, "Log file format: text or json")
+	fs.Int64Var(&g.logMaxSize, "log-max-size", g.logMaxSize, "Rotate the log after this many MB (0 disables)")
+	fs.IntVar(&g.logBackups, "log-backups", g.logBackups, "Number of rotated log files to keep")
+	fs.StringVar(&g.configPath, "config", g.configPath, "Read default option values from this file")
+	fs.BoolVar(&g.showVersion, "version", g.showVersion, "Show version")
+	fs.BoolVar(&jsonErrors, "json-errors", jsonErrors, "Report a failure as one JSON object on stderr")
+	fs.StringVar(&g.ffmpegPath, "ffmpeg", g.ffmpegPath, "Use this ffmpeg binary")
+	fs.StringVar(&g.ffprobePath, "ffprobe", g.ffprobePath, "Use this ffprobe binary")
+	fs.StringVar(&g.ffmpegArgs, "ffmpeg-args", g.ffmpegArgs, "Extra ffmpeg options placed before -i, e.g. \"-probesize 10M\" (quotes group words)")
+	fs.StringVar(&g.ffprobeArgs, "ffprobe-args", g.ffprobeArgs, "Extra ffprobe options, e.g. \"-probesize 10M\"")
+	fs.StringVar(&g.ytdlpPath, "ytdlp", g.ytdlpPath, "Resolve web video pages such as YouTube links with this yt-dlp binary (default yt-dlp if installed; none disables)")
+	fs.Var(&g.httpHeaders, "http-header", "Send this \"Key: Value\" header with http(s) inputs, e.g. an Authorization token (repeatable)")
+	fs.StringVar(&g.cookieFile, "cookies", g.cookieFile, "Send the cookies in this file (Netscape cookies.txt or Set-Cookie lines) with http(s) inputs")
+	fs.IntVar(&g.maxDimension, "max-dimension", g.maxDimension,
+		"Bound on either side of decoded frames; larger ones are scaled down keeping their aspect ratio")
+}
+
+func globalUsage() string {
+	return "" +
+		"  -debug                Enable debug logging to " + logPath() + "\n" +
+		"  -log FILE             Enable logging to FILE\n" +
+		"  -log-level LEVEL      Minimum log level: debug, info, warn, error (default info, debug with -debug)\n" +
+		"  -log-format FORMAT    Log file format: text or json (default text)\n" +
+		"  -log-max-size MB      Rotate the log after this many MB (default 4, 0 disables)\n" +
+		"  -log-backups N        Number of rotated log files to keep (default 3)\n" +
+		"  -config FILE          Read default option values (name = value lines) from FILE\n" +
+		"  -version              Show version\n" +
+		"  -json-errors          Report a failure as one JSON object on stderr\n" +
+		"  -ffmpeg PATH          Use this ffmpeg binary\n" +
+		"  -ffprobe PATH         Use this ffprobe binary\n" +
+		"  -ffmpeg-args ARGS     Extra ffmpeg options placed before -i, e.g. \"-probesize 10M\"\n" +
+		"  -ffprobe-args ARGS    Extra ffprobe options\n" +
+		"  -ytdlp PATH           yt-dlp binary for web video pages (default yt-dlp if installed; none disables)\n" +
+		"  -http-header HEADER   Send \"Key: Value\" with http(s) inputs (repeatable)\n" +
+		"  -cookies FILE         Send the cookies in FILE with http(s) inputs\n" +
+		"  -max-dimension N      Bound on either side of decoded frames (default 4096)\n"
+}
+
+// Sets flags that weren't given on the command line from the config file.
+// Lines are "name = value"; blank lines and # comments are ignored.
+func (g *globalOptions) applyConfig(fs *flag.FlagSet) error {
+	if g.configPath == "" {
+		return nil
+	}
+
+	file, err := os.Open(g.configPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	explicit := map[string]bool{}
+	for name := range g.explicit {
+		explicit[name] = true
+	}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected name = value", g.configPath, lineNum)
+		}
+		name = strings.TrimLeft(strings.TrimSpace(name), "-")
+		value = strings.TrimSpace(value)
+
+		// Options for other commands are allowed in a shared file
+		if fs.Lookup(name) == nil || explicit[name] {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("%s:%d: %s: %v", g.configPath, lineNum, name, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// Hands -ffmpeg, -ffprobe, their -args, -ytdlp, the HTTP options and
+// -max-dimension to the video package and checks that binaries given
+// explicitly exist and run. The HTTP options only go to the hosts of the
+// URLs among files.
+func (g *globalOptions) configureTools(files []string) error {
+	if g.maxDimension < 16 {
+		return fmt.Errorf("-max-dimension must be at least 16, got %d", g.maxDimension)
+	}
+	video.SetMaxDimension(g.maxDimension)
+
+	ffmpegArgs, err := splitArgs(g.ffmpegArgs)
+	if err != nil {
+		return fmt.Errorf("-ffmpeg-args: %w", err)
+	}
+	ffprobeArgs, err := splitArgs(g.ffprobeArgs)
+	if err != nil {
+		return fmt.Errorf("-ffprobe-args: %w", err)
+	}
+	cookies := ""
+	if g.cookieFile != "" {
+		if cookies, err = video.ReadCookies(g.cookieFile); err != nil {
+			return fmt.Errorf("-cookies: %w", err)
+		}
+	}
+	video.SetTools(video.Tools{
+		FFmpeg:      g.ffmpegPath,
+		FFprobe:     g.ffprobePath,
+		FFmpegArgs:  ffmpegArgs,
+		FFprobeArgs: ffprobeArgs,
+		YTDLP:       g.ytdlpPath,
+
+		HTTPHeaders: g.httpHeaders,
+		Cookies:     cookies,
+		CookieFile:  g.cookieFile,
+		HTTPHosts:   video.URLHosts(files),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for name, path := range map[string]string{"ffmpeg": g.ffmpegPath, "ffprobe": g.ffprobePath} {
+		if path == "" {
+			continue
+		}
+		if _, err := video.CheckTool(ctx, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Warns when -http-header or -cookies were given but none of files is a
+// URL they would be sent to
+func (g *globalOptions) warnUnusedHTTP(files []string) {
+	if len(g.httpHeaders) == 0 && g.cookieFile == "" {
+		return
+	}
+	for _, f := range files {
+		if video.IsURL(f) {
+			return
+		}
+	}
+	fmt.Fprintln(os.Stderr, "Warning: -http-header and -cookies only apply to http(s) URLs, ignoring them")
+}
+
+// A repeatable flag collecting "Key: Value" request headers
+type headerFlags []string
+
+func (h *headerFlags) String() string {
+	if h == nil {
+		return ""
+	}
+	// Values may be tokens; only say how many there are
+	return fmt.Sprintf("%d headers", len(*h))
+}
+
+func (h *headerFlags) Set(s string) error {
+	key, value, ok := strings.Cut(s, ":")
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if !ok || key == "" || strings.ContainsAny(key, " \t") || strings.ContainsAny(s, "\r\n") {
+		return fmt.Errorf("invalid header %q, want \"Key: Value\"", s)
+	}
+	*h = append(*h, key+": "+value)
+	return nil
+}
+
+// Splits a command line into words like a POSIX shell would, minus
+// expansions: whitespace separates, quotes group, backslash escapes outside
+// single quotes
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in %q", s)
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
+
+// Creates the logger selected by -debug/-log, or a no-op logger
+func (g *globalOptions) openLogger() *logger.Logger {
+	path := g.logFile
+	if path == "" && g.debug {
+		path = logPath()
+	}
+	if path == "" {
+		return logger.Noop()
+	}
+
+	log, err := logger.New(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not create log file: %v\n", err)
+		return logger.Noop()
+	}
+
+	level, err := g.level()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v, using info\n", err)
+	}
+	log.SetLevel(level)
+	format, err := logger.ParseFormat(g.logFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v, using text\n", err)
+	}
+	log.SetFormat(format)
+	log.SetRotation(g.logMaxSize<<20, g.logBackups)
+	return log
+}
+
+// Returns the minimum log level: -log-level or PIXLGO_LOG_LEVEL when
+// given, else debug for -debug and info otherwise
+func (g *globalOptions) level() (logger.Level, error) {
+	switch {
+	case g.logLevel != "":
+		return logger.ParseLevel(g.logLevel)
+	case g.debug:
+		return logger.LevelDebug, nil
+	}
+	return logger.LevelInfo, nil
+}
+
+// Default debug log location (/tmp/pixlgo.log on Unix)
+func logPath() string {
+	return filepath.Join(os.TempDir(), "pixlgo.log")
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0bVdnt/PixlGo/internal/logger"
+)
+
+// Parses args into fresh global options, with PIXLGO_LOG_LEVEL set to env
+// and a config file holding config when they aren't empty
+func parseGlobal(t *testing.T, args []string, env, config string) *globalOptions {
+	t.Helper()
+	t.Setenv("PIXLGO_LOG_LEVEL", env)
+	if config != "" {
+		path := filepath.Join(t.TempDir(), "pixlgo.conf")
+		if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		args = append([]string{"-config", path}, args...)
+	}
+	g := &globalOptions{}
+	fs := flag.NewFlagSet("pixlgo", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	g.register(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.applyConfig(fs); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// -debug logs Debug records unless a level was chosen some other way
+func TestLogLevel(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		env    string
+		config string
+		want   logger.Level
+	}{
+		{"default", nil, "", "", logger.LevelInfo},
+		{"debug", []string{"-debug"}, "", "", logger.LevelDebug},
+		{"log file", []string{"-log", "x.log"}, "", "", logger.LevelInfo},
+		{"debug and flag", []string{"-debug", "-log-level", "warn"}, "", "", logger.LevelWarn},
+		{"debug and env", []string{"-debug"}, "error", "", logger.LevelError},
+		{"debug and config", []string{"-debug"}, "", "log-level = info\n", logger.LevelInfo},
+		{"debug from config", nil, "", "debug = true\n", logger.LevelDebug},
+		{"flag over env", []string{"-log-level", "debug"}, "warn", "", logger.LevelDebug},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := parseGlobal(t, tt.args, tt.env, tt.config)
+			got, err := g.level()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("level %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDebugLogHasDebugRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pixlgo.log")
+	g := parseGlobal(t, []string{"-debug", "-log", path}, "", "")
+	log := g.openLogger()
+	log.Debug("Stream: pix_fmt=yuv420p")
+	log.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "pix_fmt=yuv420p") {
+		t.Errorf("debug record missing from the log:\n%s", data)
+	}
+}
@@ -1,97 +1,221 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
-	"os/signal"
-	"syscall"
-
-	"github.com/0bVdnt/PixlGo/internal/logger"
-	"github.com/0bVdnt/PixlGo/internal/player"
+	"strings"
 )
 
-var (
-	debugMode bool
-	version   = "0.1.0"
-)
+// A subcommand. setup registers the command's flags on fs and returns the
+// function that runs it with the remaining positional arguments.
+type command struct {
+	name     string
+	synopsis string
+	summary  string
+	setup    func(fs *flag.FlagSet) func(g *globalOptions, args []string) int
+}
+
+var commands = []*command{
+	playCommand,
+	probeCommand,
+	frameCommand,
+	convertCommand,
+	gifCommand,
+	thumbsCommand,
+	serveCommand,
+	checkCommand,
+	benchCommand,
+	versionCommand,
+}
+
+func lookupCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
 
 func main() {
-	flag.BoolVar(&debugMode, "debug", false, "Enable debug logging to /tmp/pixlgo.log")
-	showVersion := flag.Bool("version", false, "Show version")
-	flag.Parse()
+	code := run(os.Args[1:])
+	flushErrors(code)
+	os.Exit(code)
+}
+
+func run(args []string) int {
+	jsonErrors = wantsJSONErrors(args)
+	g := &globalOptions{}
+	gfs := flag.NewFlagSet("pixlgo", flag.ContinueOnError)
+	gfs.SetOutput(io.Discard)
+	g.register(gfs)
+
+	if err := gfs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printUsage(os.Stdout)
+			return exitOK
+		}
+		// Not a global flag: `pixlgo -threads 2 video.mp4` keeps meaning
+		// play, but before a command name it is a mistake
+		if name := firstNonFlag(args); name != "help" && lookupCommand(name) == nil {
+			return runCommand(g, playCommand, args)
+		}
+		failCode(exitUsage, err)
+		if !jsonErrors {
+			fmt.Fprintln(os.Stderr)
+			printUsage(os.Stderr)
+		}
+		return exitUsage
+	}
+
+	if g.showVersion {
+		printVersion()
+		return exitOK
+	}
+
+	g.explicit = map[string]bool{}
+	gfs.Visit(func(f *flag.Flag) { g.explicit[f.Name] = true })
+
+	rest := gfs.Args()
+	if len(rest) == 0 {
+		if jsonErrors {
+			return failCode(exitUsage, errors.New("no command or file given"))
+		}
+		printUsage(os.Stderr)
+		return exitUsage
+	}
 
-	if *showVersion {
-		fmt.Printf("pixlgo v%s\n", version)
-		os.Exit(0)
+	if rest[0] == "help" {
+		if len(rest) > 1 {
+			if c := lookupCommand(rest[1]); c != nil {
+				fs, _ := newCommandFlagSet(g, c)
+				fs.SetOutput(os.Stdout)
+				fs.Usage()
+				return exitOK
+			}
+		}
+		printUsage(os.Stdout)
+		return exitOK
 	}
 
-	args := flag.Args()
-	if len(args) < 1 {
-		printUsage()
-		os.Exit(1)
+	if c := lookupCommand(rest[0]); c != nil {
+		return runCommand(g, c, rest[1:])
 	}
-	videoPath := args[0]
-
-	// Setup logging
-	var log *logger.Logger
-	var err error
-
-	if debugMode {
-		log, err = logger.New("/tmp/pixlgo.log")
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: could not create log file: %v\n", err)
-			log = logger.Noop()
-		} else {
-			defer log.Close()
+
+	// `pixlgo FILE` is shorthand for `pixlgo play FILE`
+	return runCommand(g, playCommand, rest)
+}
+
+// Returns the first argument that isn't a flag, or "" if there is none. A
+// flag's separate value counts too; no command name is a flag value.
+func firstNonFlag(args []string) string {
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			return arg
 		}
-	} else {
-		log = logger.Noop()
 	}
+	return ""
+}
 
-	log.Log("pixlgo: v%s starting", version)
-	log.Log("Video: %s", videoPath)
+// Returns the command's FlagSet with shared and command flags registered
+func newCommandFlagSet(g *globalOptions, c *command) (*flag.FlagSet, func(*globalOptions, []string) int) {
+	fs := flag.NewFlagSet("pixlgo "+c.name, flag.ContinueOnError)
+	// Parse errors are reported by runCommand
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: pixlgo %s\n\n%s\n\nOptions:\n", c.synopsis, c.summary)
+		fs.PrintDefaults()
+	}
+	g.register(fs)
+	return fs, c.setup(fs)
+}
 
-	// Create player
-	p, err := player.New(player.Config{
-		VideoPath: videoPath,
-		Logger:    log,
-	})
+func runCommand(g *globalOptions, c *command, args []string) int {
+	fs, runFn := newCommandFlagSet(g, c)
 
+	positional, err := parseInterspersed(fs, args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stdout)
+			fs.Usage()
+			return exitOK
+		}
+		return usageError(fs, "%v", err)
+	}
+	if g.showVersion {
+		printVersion()
+		return exitOK
+	}
+	// Before the config file, which may set them for every run
+	g.warnUnusedHTTP(positional)
+	if err := g.applyConfig(fs); err != nil {
+		return failCode(exitUsage, fmt.Errorf("config: %w", err))
+	}
+	if err := g.configureTools(positional); err != nil {
+		return fail(err)
 	}
 
-	// Signal handling
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		<-sigChan
-		log.Log("Signal received")
-		p.Stop()
-	}()
+	return runFn(g, positional)
+}
 
-	// Run player
-	p.Run()
+// Parses flags that may follow positional arguments, as in
+// `pixlgo frame FILE -t 10 -o out.png`. Everything after "--" is positional.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		// flag.Parse consumes a "--" terminator itself
+		if len(args) > len(rest) && args[len(args)-len(rest)-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
 
-	log.Log("Exiting")
+// Reports a usage error followed by the command's usage and returns the
+// usage exit code
+func usageError(fs *flag.FlagSet, format string, args ...any) int {
+	failCode(exitUsage, fmt.Errorf(format, args...))
+	if !jsonErrors {
+		fmt.Fprintln(os.Stderr)
+		fs.SetOutput(os.Stderr)
+		fs.Usage()
+	}
+	return exitUsage
+}
+
+func printUsage(out io.Writer) {
+	var sb strings.Builder
+	sb.WriteString("pixlgo - Terminal video player\n\n")
+	sb.WriteString("Usage:\n")
+	sb.WriteString("  pixlgo [global options] <command> [options] [args]\n")
+	sb.WriteString("  pixlgo [options] <video-file>      (same as: pixlgo play <video-file>)\n\n")
+	sb.WriteString("Commands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(&sb, "  %-9s %s\n", c.name, firstLine(c.summary))
+	}
+	sb.WriteString("  help      Show help for a command\n\n")
+	sb.WriteString("Global options:\n")
+	sb.WriteString(globalUsage())
+	sb.WriteString("\nRun 'pixlgo help <command>' for command options.\n")
+	fmt.Fprint(out, sb.String())
 }
 
-func printUsage() {
-	fmt.Println("pixlgo - Terminal video player")
-	fmt.Println()
-	fmt.Println("Usage: pixlgo [options] <video-file>")
-	fmt.Println()
-	fmt.Println("Options:")
-	fmt.Println("  -debug    Enable debug logging to /tmp/pixlgo.log")
-	fmt.Println("  -version  Show version")
-	fmt.Println()
-	fmt.Println("Controls:")
-	fmt.Println("  Space	   Pause/Resume")
-	fmt.Println("  Q/Esc	   Quit")
-	fmt.Println("  Left/Right  Seek ±5s")
-	fmt.Println("  Up/Down 	   Seek ±30s")
-	fmt.Println("  R           Restart")
-	fmt.Println("  Home/End    Go to start/end")
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
 }
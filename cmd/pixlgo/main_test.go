@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/0bVdnt/PixlGo/internal/fakeff"
+)
+
+func TestMain(m *testing.M) {
+	fakeff.Main()
+	os.Exit(m.Run())
+}
+
+func TestFirstNonFlag(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"video.mp4"}, "video.mp4"},
+		{[]string{"-threads", "2", "video.mp4"}, "2"},
+		{[]string{"-bogus", "probe", "x"}, "probe"},
+		{[]string{"-bogus=1", "-v", "version"}, "version"},
+		{[]string{"-x", "-"}, "-"},
+		{[]string{"-x", "--", "probe"}, ""},
+	}
+	for _, tt := range tests {
+		if got := firstNonFlag(tt.args); got != tt.want {
+			t.Errorf("firstNonFlag(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+// A bad flag in front of a command is reported instead of trying to play
+// the command name as a file
+func TestRunBadGlobalFlagBeforeCommand(t *testing.T) {
+	for _, args := range [][]string{
+		{"-bogus", "version"},
+		{"-bogus", "probe", "clip.mp4"},
+		{"-no-such-flag=1", "help"},
+	} {
+		if code := run(args); code != exitUsage {
+			t.Errorf("run(%q) = %d, want %d", args, code, exitUsage)
+		}
+	}
+}
@@ -0,0 +1,578 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/0bVdnt/PixlGo/internal/chapters"
+	"github.com/0bVdnt/PixlGo/internal/control"
+	"github.com/0bVdnt/PixlGo/internal/logger"
+	"github.com/0bVdnt/PixlGo/internal/metrics"
+	"github.com/0bVdnt/PixlGo/internal/player"
+	"github.com/0bVdnt/PixlGo/internal/progress"
+	"github.com/0bVdnt/PixlGo/internal/recording"
+	"github.com/0bVdnt/PixlGo/internal/resume"
+	"github.com/0bVdnt/PixlGo/internal/subtitle"
+	"github.com/0bVdnt/PixlGo/internal/timecode"
+	"github.com/0bVdnt/PixlGo/internal/video"
+	"github.com/0bVdnt/PixlGo/internal/web"
+)
+
+var playCommand = &command{
+	name:     "play",
+	synopsis: "play [options] FILE...",
+	summary: "Play video files, or .ans recordings made by convert, one after another.\n" +
+		"This is the default command: 'pixlgo FILE' is the same as 'pixlgo play FILE'.\n" +
+		"A FILE of - reads piped video from standard input.\n\n" +
+		"Controls:\n" +
+		"  Space       Pause/Resume\n" +
+		"  Q/Esc       Quit\n" +
+		"  Left/Right  Seek ±5s\n" +
+		"  Up/Down     Seek ±30s\n" +
+		"  R           Restart\n" +
+		"  Home/End    Go to start/end\n" +
+		"  G           Go to a position, or a frame with :frame N\n" +
+		"  y / Y       Copy position / file @ position to the clipboard\n" +
+		"  L           Toggle auto levels\n" +
+		"  M / m       Add a named marker / list, jump to and export markers\n" +
+		"  i           Toggle stats (frame size, rates, memory)\n" +
+		"  W           Toggle half-width columns\n" +
+		"  + / -       Volume up/down by 5% (0-150%)\n" +
+		"  U           Mute/unmute\n" +
+		"  C           Toggle subtitles\n" +
+		"  [ / ]       Previous / next chapter (also PgUp / PgDn)\n" +
+		"  F2 / `      Toggle log overlay\n\n" +
+		"Signals (not on Windows):\n" +
+		"  SIGUSR1     Pause/Resume\n" +
+		"  SIGUSR2     Next file, or seek +30s on the last one",
+	setup: setupPlay,
+}
+
+func setupPlay(fs *flag.FlagSet) func(*globalOptions, []string) int {
+	seekKeepAlive := fs.Duration("seek-keepalive", player.DefaultSeekKeepAlive,
+		"Skip forward in the running decoder for seeks up to this distance (0 disables)")
+	threads := fs.Int("threads", 0, "Cap ffmpeg decode threads (0 = one per CPU)")
+	metricsPath := fs.String("metrics", "", "Write per-frame timing metrics to this CSV file")
+	maxCPU := fs.Int("max-cpu", 0, "Rough CPU budget in percent; lowers FPS and interlaces rendering (0 = unlimited)")
+	maxFPS := fs.Float64("fps", 0, "Cap the frame rate, e.g. as suggested by 'pixlgo bench' (0 = automatic)")
+	resumeNative := fs.Bool("resume", false, "Start where playback last stopped and remember the position on quit")
+	watchLater := fs.Bool("watch-later", false, "Also read and write mpv's watch_later resume files (-resume takes precedence)")
+	follow := fs.Bool("follow", false, "Keep playing a file that is still being written, waiting at its end for more data")
+	followTimeout := fs.Duration("follow-timeout", player.DefaultFollowTimeout, "With -follow, end once the file stopped growing for this long")
+	notify := fs.Bool("notify", false, "Send a desktop notification when a video ends or fails")
+	clipboard := fs.Bool("clipboard", true, "Allow y/Y to copy the position to the clipboard via OSC 52")
+	inputFIFO := fs.String("input-fifo", "", "Read commands (pause, seek +30, seek-to 1:02:03, status, quit, ...) from this named pipe")
+	replyFIFO := fs.String("input-fifo-reply", "", "Write replies to -input-fifo commands here instead of the log")
+	autoLevels := fs.Bool("auto-levels", false, "Start with auto levels on, stretching dark or washed-out frames to full brightness (toggle with L)")
+	tsOverlay := fs.String("timestamp-overlay", "", "Burn the media timestamp into this corner of the frame: top-left, top-right, bottom-left or bottom-right")
+	tsFormat := fs.String("timestamp-format", timecode.DefaultLayout, "Layout for -timestamp-overlay: %H hours, %M minutes, %S seconds, %L milliseconds, %s total seconds")
+	tsScale := fs.Int("timestamp-scale", 0, "Pixel size of the -timestamp-overlay font (0 = fit to the frame width)")
+	markerList := fs.Bool("marker-list", false, "Marker export also writes a \"00:12:34 Title\" chapter list for video descriptions")
+	fresh := fs.Bool("fresh", false, "Don't restore the settings a file was last played with")
+	enqueue := fs.Bool("enqueue", false, "Add the files to the playlist of an already running instance instead of playing them here")
+	replace := fs.Bool("replace", false, "Like -enqueue, but switch the running instance to the first file immediately")
+	socketPath := fs.String("socket", control.SocketPath(), "Control socket for -enqueue/-replace and other instances (empty disables)")
+	maxArea := fs.String("max-frame-area", "1920x1080", "Cap on the decoded frame size to save memory on huge terminals: WxH, a pixel count, or 0 for none")
+	progressFD := fs.Int("progress-fd", 0, "Write JSON progress records (about 2 per second, plus a final exit record) to this file descriptor")
+	progressFile := fs.String("progress-file", "", "Like -progress-fd, but write to this file or named pipe")
+	loopAnimated := fs.Bool("loop-animated", true, "Repeat animated GIF, APNG and WebP images (a playlist still moves on after one pass)")
+	exactSeek := fs.Bool("exact-seek", true, "Seek frame-accurately while paused, decoding from 2s before the target (false = fast keyframe seeks)")
+	loop := fs.Int("loop", 0, "Play each file this many extra times without restarting ffmpeg (-1 = forever)")
+	windowTitle := fs.Bool("title", true, "Set the terminal window title to the playing file (title = false in the config file if your shell manages titles)")
+	debugViews := fs.Bool("debug-views", false, "Enable debug views: H toggles a motion heatmap of which cells the diff cache redraws")
+	vuMeter := fs.Bool("vu-meter", false, "Show audio levels in the status bar, decoded by a second ffmpeg process")
+	probeTimeout := fs.Duration("probe-timeout", video.DefaultProbeTimeout, "Give up probing a file after this long; slow probes show a spinner that Esc cancels (0 = no limit)")
+	start := &timestampFlag{}
+	end := &timestampFlag{}
+	fs.Var(start, "start", "Play only from this position on; seeks stay after it")
+	fs.Var(end, "end", "Stop playing at this position, e.g. 5:00")
+	segLength := fs.Duration("duration", 0, "Stop playing this long after -start, e.g. 30s (instead of -end)")
+	hwAccel := fs.String("hwaccel", "none", "Hardware decoding: auto, vaapi, videotoolbox, cuda, qsv, d3d11va, ... or none; falls back to software if it fails")
+	hwDevice := fs.String("hwaccel-device", "", "Device for -hwaccel, e.g. /dev/dri/renderD128")
+	subtitles := fs.Bool("subs", false, "Burn embedded text subtitles into the video (toggle with C; needs ffmpeg with libass)")
+	subTrack := fs.Int("sub-track", 0, "Subtitle track for -subs and C, counted from 0 among the subtitle streams")
+	subFile := fs.String("sub", "", "Show subtitles from this SRT file as text above the progress bar (toggle with C; one video file only)")
+	videoStream := fs.Int("video-stream", 0, "Video stream to play, the N of v:N in 'pixlgo probe' (0 = first that isn't cover art)")
+	concat := fs.Bool("concat", false, "Play the files as one video joined end to end, e.g. part1.mp4 part2.mp4 (same codec and size)")
+	stdinSize := fs.String("stdin-size", "", "Picture size WxH of video piped to -, for formats whose first data doesn't tell")
+	stdinFPS := fs.Float64("stdin-fps", 0, "Frame rate of video piped to -, overriding the probed rate")
+	audioTrack := fs.Int("audio-track", 0, "Audio track to play, counted from 0 among the audio streams ('pixlgo probe' lists them)")
+	noAudio := fs.Bool("no-audio", false, "Play without sound; otherwise audio plays through ffplay when it is installed")
+	halfWidth := fs.Bool("halfwidth", false, "Decode at half the terminal width and draw each column twice, for very wide terminals (toggle with W)")
+	startFrame := fs.Int("start-frame", 0, "Start each file at this frame number, counted from 0 (clamped to the last frame)")
+	httpAddr := fs.String("http", "", "Serve /status, /frame.png and /frame.txt on this address, e.g. :8080")
+
+	return func(g *globalOptions, files []string) int {
+		if len(files) == 0 {
+			return usageError(fs, "no video file given")
+		}
+		corner, err := player.ParseCorner(*tsOverlay)
+		if err != nil {
+			return usageError(fs, "-timestamp-overlay: %v", err)
+		}
+		if *segLength < 0 || *segLength > 0 && end.d > 0 {
+			return usageError(fs, "-duration must be positive, and not combined with -end")
+		}
+		if *segLength > 0 {
+			end.d = start.d + *segLength
+		}
+		if end.d > 0 && end.d <= start.d {
+			return usageError(fs, "-end must be after -start")
+		}
+		if !video.ValidHWAccel(*hwAccel) {
+			return usageError(fs, "-hwaccel: invalid method %q", *hwAccel)
+		}
+		if *subTrack < 0 || *audioTrack < 0 || *videoStream < 0 {
+			return usageError(fs, "-video-stream, -audio-track and -sub-track must not be negative")
+		}
+		var subs *subtitle.Track
+		if *subFile != "" {
+			if len(files) > 1 {
+				return usageError(fs, "-sub takes a single video file")
+			}
+			if subs, err = subtitle.Load(*subFile); err != nil {
+				return usageError(fs, "-sub: %v", err)
+			}
+		}
+		var stdinHint video.Metadata
+		if slices.Contains(files, video.StdinPath) && len(files) > 1 {
+			return usageError(fs, "- (standard input) must be the only file")
+		}
+		if *concat && (len(files) < 2 || slices.Contains(files, video.StdinPath) || *enqueue || *replace) {
+			return usageError(fs, "-concat needs two or more local files, and doesn't combine with -enqueue or -replace")
+		}
+		if *stdinSize != "" {
+			if stdinHint.Width, stdinHint.Height, err = parseSize(*stdinSize); err != nil {
+				return usageError(fs, "-stdin-size: %v", err)
+			}
+		}
+		if *stdinFPS < 0 {
+			return usageError(fs, "-stdin-fps must not be negative")
+		}
+		stdinHint.FPS = *stdinFPS
+		if *startFrame < 0 {
+			return usageError(fs, "-start-frame must not be negative")
+		}
+		if *tsScale < 0 {
+			return usageError(fs, "-timestamp-scale must not be negative")
+		}
+		probeLimit := *probeTimeout
+		if probeLimit == 0 {
+			probeLimit = -1
+		}
+		frameArea, err := parseArea(*maxArea)
+		if err != nil {
+			return usageError(fs, "-max-frame-area: %v", err)
+		}
+		if *progressFD < 0 || *progressFD > 0 && *progressFile != "" {
+			return usageError(fs, "-progress-fd must be a positive descriptor, and not combined with -progress-file")
+		}
+
+		log := g.openLogger()
+		defer log.Close()
+		log.Info("pixlgo starting", "version", version, "files", len(files))
+
+		if (*enqueue || *replace) && *socketPath != "" {
+			err := sendToInstance(*socketPath, files, *replace)
+			if err == nil {
+				return exitOK
+			}
+			if !errors.Is(err, control.ErrNoInstance) {
+				return fail(fmt.Errorf("enqueue: %w", err), "socket", *socketPath)
+			}
+			log.Info("No running instance, playing here", "socket", *socketPath)
+		}
+
+		// Panics inside Run are handled by the player; this covers setup
+		defer func() {
+			if r := recover(); r != nil {
+				player.ReportCrash(log, r, debug.Stack())
+				os.Exit(player.CrashExitCode)
+			}
+		}()
+
+		var rec *metrics.Recorder
+		if *metricsPath != "" {
+			var err error
+			rec, err = metrics.NewRecorder(*metricsPath)
+			if err != nil {
+				return fail(fmt.Errorf("metrics: %w", err))
+			}
+		}
+
+		// Signal handling: the first signal goes through the normal shutdown
+		// path (stop ffmpeg, restore terminal); a second one forces the exit
+		var current atomic.Pointer[player.Player]
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sigChan := make(chan os.Signal, 2)
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+		defer signal.Stop(sigChan)
+		shutdown := watchShutdown(sigChan, func() {
+			cancel()
+			if p := current.Load(); p != nil {
+				p.Stop()
+			}
+		}, os.Exit, log)
+
+		if *httpAddr != "" {
+			srv, err := web.Start(*httpAddr, current.Load, log)
+			if err != nil {
+				return fail(fmt.Errorf("http: %w", err), "listen", *httpAddr)
+			}
+			defer srv.Close()
+		}
+
+		if *concat {
+			probeCtx, probeCancel := ctx, context.CancelFunc(func() {})
+			if probeLimit > 0 {
+				probeCtx, probeCancel = context.WithTimeout(ctx, probeLimit)
+			}
+			listPath, err := video.WriteConcatList(probeCtx, files)
+			probeCancel()
+			if err != nil {
+				return fail(err)
+			}
+			defer video.RemoveConcatList(listPath)
+			log.Info("Playing files as one", "parts", len(files), "list", listPath)
+			files = []string{listPath}
+		}
+
+		list := &playlist{files: files}
+		exec := list.exec(current.Load)
+		if *inputFIFO != "" {
+			fifo, err := control.OpenFIFO(*inputFIFO, *replyFIFO, log)
+			if err != nil {
+				return fail(fmt.Errorf("input fifo: %w", err), "file", *inputFIFO)
+			}
+			defer fifo.Close()
+			go fifo.Serve(exec)
+		}
+		defer handleControlSignals(exec, list.more, log)()
+		if *socketPath != "" {
+			// Best effort: a second standalone instance just doesn't listen
+			sock, err := control.Listen(*socketPath, log)
+			if err != nil {
+				log.Info("Control socket not available", "socket", *socketPath, "err", err)
+			} else {
+				defer sock.Close()
+				go sock.Serve(exec)
+			}
+		}
+
+		state := resume.Open(resume.DefaultPath())
+		resumes := openResumeStores(state, *resumeNative, *watchLater)
+
+		var prog *progress.Reporter
+		if *progressFD > 0 || *progressFile != "" {
+			open, err := progressOutput(*progressFD, *progressFile)
+			if err != nil {
+				return fail(fmt.Errorf("progress: %w", err))
+			}
+			prog = progress.Start(open, current.Load, log)
+		}
+
+		status := exitOK
+		quit := false
+		for {
+			videoPath, ok := list.pop()
+			if !ok {
+				break
+			}
+			log.Info("Opening video", "video", videoPath)
+
+			if isRecording(videoPath) {
+				if err := replayRecording(ctx, videoPath); err != nil && ctx.Err() == nil {
+					status = fail(err, "file", videoPath)
+				}
+				if ctx.Err() != nil {
+					break
+				}
+				continue
+			}
+
+			// Resume points, settings and markers are kept under this
+			stateKey := video.StateKey(videoPath)
+			var restore *player.Settings
+			changed := false
+			if !*fresh {
+				restore = savedSettings(state, stateKey)
+			}
+			p, err := player.New(player.Config{
+				VideoPath:     videoPath,
+				Logger:        log,
+				SeekKeepAlive: *seekKeepAlive,
+				Threads:       *threads,
+				MaxCPU:        *maxCPU,
+				MaxFPS:        *maxFPS,
+				Metrics:       rec,
+				ExitOnEnd:     list.more(),
+				StartPos:      resumes.position(stateKey),
+				StartFrame:    *startFrame,
+				SegmentStart:  start.d,
+				SegmentEnd:    end.d,
+
+				Follow:           *follow,
+				FollowTimeout:    *followTimeout,
+				Notify:           *notify,
+				DisableClipboard: !*clipboard,
+
+				TimestampOverlay: corner,
+				TimestampFormat:  *tsFormat,
+				TimestampScale:   *tsScale,
+				AutoLevels:       *autoLevels,
+
+				Restore: restore,
+				OnSettingsChange: func(st player.Settings) {
+					changed = true
+					saveSettings(state, stateKey, st, log)
+				},
+
+				Markers:      state.Markers(stateKey),
+				MarkerList:   *markerList,
+				MaxFrameArea: frameArea,
+				ProbeTimeout: probeLimit,
+				Context:      ctx,
+				VUMeter:      *vuMeter,
+				Playlist:     list,
+				DebugViews:   *debugViews,
+				LoopAnimated: *loopAnimated,
+				Loop:         *loop,
+				ExactSeek:    *exactSeek,
+				WindowTitle:  *windowTitle,
+				HalfWidth:    *halfWidth,
+				NoAudio:      *noAudio,
+				AudioTrack:   *audioTrack,
+				VideoStream:  *videoStream,
+				StdinHint:    stdinHint,
+
+				HWAccel:       *hwAccel,
+				HWAccelDevice: *hwDevice,
+				Subtitles:     *subtitles,
+				SubtitleTrack: *subTrack,
+
+				ExternalSubtitles: subs,
+
+				OnMarkersChange: func(markers []chapters.Marker) {
+					if err := state.SetMarkers(stateKey, markers); err != nil {
+						log.Warn("could not save markers", "video", videoPath, "err", err)
+					}
+				},
+			})
+			if errors.Is(err, player.ErrCancelled) {
+				// Esc while probing skips to the next file, or quits
+				log.Info("Probing cancelled", "video", videoPath)
+				if list.more() {
+					continue
+				}
+				quit = true
+				break
+			}
+			if err != nil && ctx.Err() != nil {
+				// A signal interrupted startup; the exit code says which
+				break
+			}
+			if err != nil {
+				status = fail(err, "file", videoPath)
+				continue
+			}
+
+			current.Store(p)
+			if st := p.Status(); st.DurationKnown {
+				list.setDuration(videoPath, st.Duration)
+			}
+			if list.more() {
+				// Queued while opening
+				p.SetExitOnEnd(true)
+			}
+			if shutdown.code() != 0 {
+				// Signalled while opening; Run still restores the terminal
+				p.Stop()
+			}
+			p.Run()
+			resumes.save(stateKey, p.Status(), log)
+			// Only files whose settings were touched; command line defaults
+			// aren't worth remembering per file
+			if changed || restore != nil {
+				saveSettings(state, stateKey, p.Settings(), log)
+			}
+			if err := p.Err(); err != nil {
+				// Reported now that the terminal is restored
+				status = fail(err, "file", videoPath)
+			}
+
+			// Quitting or a signal stops the whole list
+			if !p.Finished() || shutdown.code() != 0 {
+				quit = !p.Finished()
+				break
+			}
+		}
+
+		// Terminal is restored by now, so the summary is visible
+		rec.Close(os.Stdout)
+
+		reason, code := "ended", status
+		switch {
+		case shutdown.code() != 0:
+			reason, code = "signal", shutdown.code()
+		case status != exitOK:
+			reason = "error"
+		case quit:
+			reason = "quit"
+		}
+		prog.Finish(reason, code)
+
+		log.Infof("Exiting")
+		if code := shutdown.code(); code != 0 {
+			return interrupted(code, shutdown.signal())
+		}
+		return status
+	}
+}
+
+// Parses a picture size given as WxH
+func parseSize(s string) (int, int, error) {
+	ws, hs, _ := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "x")
+	w, err1 := strconv.Atoi(ws)
+	h, err2 := strconv.Atoi(hs)
+	if err1 != nil || err2 != nil || w <= 0 || h <= 0 {
+		return 0, 0, fmt.Errorf("invalid size %q, want WxH", s)
+	}
+	return w, h, nil
+}
+
+// Parses a frame area given as WxH or a pixel count. Zero means no cap,
+// returned as -1 for player.Config.
+func parseArea(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if ws, hs, ok := strings.Cut(strings.ToLower(s), "x"); ok {
+		w, err1 := strconv.Atoi(ws)
+		h, err2 := strconv.Atoi(hs)
+		if err1 != nil || err2 != nil || w <= 0 || h <= 0 {
+			return 0, fmt.Errorf("invalid size %q, want WxH", s)
+		}
+		return w * h, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid area %q", s)
+	}
+	if n == 0 {
+		return -1, nil
+	}
+	return n, nil
+}
+
+// Returns an opener for the progress output. A descriptor is checked now so
+// a typo fails right away; a file is opened on first write, since opening a
+// named pipe waits for its reader.
+func progressOutput(fd int, path string) (func() (io.WriteCloser, error), error) {
+	if path != "" {
+		return func() (io.WriteCloser, error) {
+			return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+		}, nil
+	}
+	file := os.NewFile(uintptr(fd), fmt.Sprintf("fd %d", fd))
+	if file == nil {
+		return nil, fmt.Errorf("invalid descriptor %d", fd)
+	}
+	if _, err := file.Stat(); err != nil {
+		return nil, fmt.Errorf("descriptor %d: %w", fd, err)
+	}
+	return func() (io.WriteCloser, error) { return file, nil }, nil
+}
+
+// Hands files to the instance listening on socketPath. With replace the
+// first one plays immediately and the rest go to the end of the playlist.
+func sendToInstance(socketPath string, files []string, replace bool) error {
+	for i, file := range files {
+		verb := "enqueue"
+		if replace && i == 0 {
+			verb = "replace"
+		}
+		if _, err := control.Send(socketPath, verb+" "+absPath(file)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reports whether path is an .ans recording made by convert
+func isRecording(path string) bool {
+	if !strings.EqualFold(filepath.Ext(path), ".ans") {
+		return false
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	return recording.IsANS(file)
+}
+
+func replayRecording(ctx context.Context, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return recording.Replay(ctx, file, os.Stdout)
+}
+
+// The signal that started shutting down, if any
+type shutdownSignal struct {
+	sig      atomic.Value
+	exitCode atomic.Int32
+}
+
+// Returns the exit status for the signal, 0 before any arrived
+func (s *shutdownSignal) code() int {
+	return int(s.exitCode.Load())
+}
+
+func (s *shutdownSignal) signal() os.Signal {
+	sig, _ := s.sig.Load().(os.Signal)
+	return sig
+}
+
+// Handles the signals from sigs: the first is recorded and goes through
+// the normal shutdown path, stop, which cancels opening and stops the
+// player so ffmpeg is stopped and the terminal restored; a second one
+// forces exit with the first one's status
+func watchShutdown(sigs <-chan os.Signal, stop func(), exit func(int), log *logger.Logger) *shutdownSignal {
+	s := &shutdownSignal{}
+	go func() {
+		sig := <-sigs
+		log.Infof("Signal received: %v", sig)
+		// Recorded first, so whatever stop wakes sees why
+		s.sig.Store(sig)
+		s.exitCode.Store(int32(signalExitCode(sig)))
+		stop()
+
+		sig = <-sigs
+		log.Warnf("Second signal (%v) during shutdown, forcing exit", sig)
+		log.Close()
+		exit(s.code())
+	}()
+	return s
+}
+
+// Conventional shell exit status for death by signal (128 + number)
+func signalExitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/logger"
+)
+
+// Stands in for the player: counts Stop calls and notes the exit status
+// shutdown had recorded by the time Stop came
+type fakePlayer struct {
+	shutdown *shutdownSignal
+	stops    chan int
+}
+
+func (f *fakePlayer) Stop() {
+	f.stops <- f.shutdown.code()
+}
+
+func TestWatchShutdown(t *testing.T) {
+	sigs := make(chan os.Signal, 2)
+	exits := make(chan int, 1)
+	p := &fakePlayer{stops: make(chan int, 2)}
+	p.shutdown = watchShutdown(sigs, func() { p.Stop() }, func(code int) { exits <- code }, logger.Noop())
+	if p.shutdown.code() != 0 || p.shutdown.signal() != nil {
+		t.Fatalf("code %d, signal %v before any signal", p.shutdown.code(), p.shutdown.signal())
+	}
+
+	sigs <- syscall.SIGTERM
+	select {
+	case code := <-p.stops:
+		// The main loop must find the reason once Run returns
+		if code != 128+int(syscall.SIGTERM) {
+			t.Errorf("stopped with status %d recorded, want %d", code, 128+int(syscall.SIGTERM))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("first signal didn't stop the player")
+	}
+	if p.shutdown.signal() != syscall.SIGTERM {
+		t.Errorf("signal %v, want SIGTERM", p.shutdown.signal())
+	}
+	select {
+	case code := <-exits:
+		t.Fatalf("first signal forced exit %d", code)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// A second one forces the exit, keeping the first one's status
+	sigs <- syscall.SIGINT
+	select {
+	case code := <-exits:
+		if code != 128+int(syscall.SIGTERM) {
+			t.Errorf("exit %d, want %d", code, 128+int(syscall.SIGTERM))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("second signal didn't force the exit")
+	}
+	if len(p.stops) != 0 {
+		t.Error("second signal stopped the player again")
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/control"
+	"github.com/0bVdnt/PixlGo/internal/player"
+)
+
+// Files play works through; other instances can add to it over the control
+// socket while it plays
+type playlist struct {
+	mu    sync.Mutex
+	files []string
+	next  int
+
+	// Durations of files opened so far, for the playlist overlay
+	durations map[string]time.Duration
+}
+
+// Returns the next file to play and advances, or false at the end
+func (l *playlist) pop() (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.next >= len(l.files) {
+		return "", false
+	}
+	l.next++
+	return l.files[l.next-1], true
+}
+
+// Reports whether files are left after the current one
+func (l *playlist) more() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.next < len(l.files)
+}
+
+// Appends path and returns how many files are waiting
+func (l *playlist) add(path string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.files = append(l.files, path)
+	return len(l.files) - l.next
+}
+
+// Queues path to play right after the current file
+func (l *playlist) insertNext(path string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.files = append(l.files[:l.next], append([]string{path}, l.files[l.next:]...)...)
+}
+
+// Remembers the probed duration of path
+func (l *playlist) setDuration(path string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.durations == nil {
+		l.durations = map[string]time.Duration{}
+	}
+	l.durations[path] = d
+}
+
+// Returns every file for the overlay, the playing one marked
+func (l *playlist) Entries() []player.PlaylistEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries := make([]player.PlaylistEntry, len(l.files))
+	for i, f := range l.files {
+		entries[i] = player.PlaylistEntry{Path: f, Duration: l.durations[f], Current: i == l.next-1}
+	}
+	return entries
+}
+
+// Returns the index of the playing file and the number of files
+func (l *playlist) Position() (int, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return max(l.next-1, 0), len(l.files)
+}
+
+// Makes file i the next one pop returns
+func (l *playlist) Jump(i int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if i >= 0 && i < len(l.files) {
+		l.next = i
+	}
+}
+
+// Removes file i unless it is the one playing
+func (l *playlist) Remove(i int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	switch {
+	case i < 0 || i >= len(l.files):
+		return errors.New("no such entry")
+	case i == l.next-1:
+		return errors.New("can't remove the playing file")
+	}
+	l.files = append(l.files[:i], l.files[i+1:]...)
+	if i < l.next {
+		l.next--
+	}
+	return nil
+}
+
+// One line per file, the upcoming ones marked with their queue position
+func (l *playlist) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var sb strings.Builder
+	for i, f := range l.files {
+		mark := "  "
+		switch {
+		case i == l.next-1:
+			mark = "> "
+		case i >= l.next:
+			mark = fmt.Sprintf("%d ", i-l.next+1)
+		}
+		if i > 0 {
+			sb.WriteString("; ")
+		}
+		sb.WriteString(mark + f)
+	}
+	return sb.String()
+}
+
+// Runs playlist verbs (enqueue PATH, replace PATH, playlist) and passes
+// everything else on to the playing file's player
+func (l *playlist) exec(current func() *player.Player) control.ExecFunc {
+	return func(line string) (string, error) {
+		name, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+		arg = strings.TrimSpace(arg)
+		p := current()
+
+		switch strings.ToLower(name) {
+		case "enqueue", "replace":
+			if arg == "" {
+				return "", fmt.Errorf("%s needs a file", name)
+			}
+			if strings.EqualFold(name, "replace") {
+				l.insertNext(arg)
+				if p != nil {
+					p.Exec("next")
+				}
+				return "ok", nil
+			}
+			n := l.add(arg)
+			if p != nil {
+				// The last file would otherwise stay on its end screen
+				p.SetExitOnEnd(true)
+			}
+			return fmt.Sprintf("queued (%d waiting)", n), nil
+		case "playlist":
+			return l.String(), nil
+		}
+		if p == nil {
+			return "", player.ErrStopped
+		}
+		return p.Exec(line)
+	}
+}
+
+// Makes path usable from another working directory. URLs pass unchanged.
+func absPath(path string) string {
+	if strings.Contains(path, "://") {
+		return path
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
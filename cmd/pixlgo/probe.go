@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/video"
+)
+
+var probeCommand = &command{
+	name:     "probe",
+	synopsis: "probe [options] FILE...",
+	summary: "Print container, stream and chapter information for video files.\n" +
+		"Exits non-zero if any file can't be probed or has no video or audio.",
+	setup: setupProbe,
+}
+
+const probeTimeout = 10 * time.Second
+
+func setupProbe(fs *flag.FlagSet) func(*globalOptions, []string) int {
+	asJSON := fs.Bool("json", false, "Print JSON instead of a table")
+
+	return func(g *globalOptions, files []string) int {
+		if len(files) == 0 {
+			return usageError(fs, "no file given")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		status := exitOK
+		for i, path := range files {
+			if ctx.Err() != nil {
+				return interrupted(exitInterrupted, os.Interrupt)
+			}
+
+			pctx, cancel := context.WithTimeout(ctx, probeTimeout)
+			info, err := video.ProbeInfo(pctx, path)
+			cancel()
+
+			if *asJSON {
+				// One object per file so several files stay line-parseable
+				printProbeJSON(os.Stdout, path, info, err)
+			} else {
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Printf("== %s ==\n", path)
+				if err == nil {
+					printProbeTable(os.Stdout, info)
+				}
+			}
+
+			switch {
+			case err != nil:
+				status = fail(err, "file", path)
+			case !info.Playable():
+				status = failCode(exitDecode, errors.New("no video or audio streams"), "file", path)
+			}
+		}
+		return status
+	}
+}
+
+func printProbeJSON(out io.Writer, path string, info *video.MediaInfo, err error) {
+	doc := struct {
+		File  string           `json:"file"`
+		Error string           `json:"error,omitempty"`
+		Info  *video.MediaInfo `json:"info,omitempty"`
+	}{File: path, Info: info}
+	if err != nil {
+		doc.Error = err.Error()
+	}
+	data, _ := json.Marshal(doc)
+	fmt.Fprintf(out, "%s\n", data)
+}
+
+func printProbeTable(out io.Writer, info *video.MediaInfo) {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintf(tw, "Container\t%s\n", orNA(info.Format))
+	fmt.Fprintf(tw, "Duration\t%s\n", formatDuration(info.Duration))
+	if info.BitRate > 0 {
+		fmt.Fprintf(tw, "Bitrate\t%s\n", formatBitRate(info.BitRate))
+	}
+
+	for _, v := range info.Video {
+		kind := "Video"
+		if v.AttachedPic {
+			kind = "Cover art"
+		}
+		fmt.Fprintf(tw, "%s #%d (v:%d)\t%s, %dx%d, %.3g fps, %s, %s\n", kind, v.Index, v.Number,
+			v.Codec, v.Width, v.Height, v.FPS, orNA(v.PixFmt), formatBitRate(v.BitRate))
+	}
+	for _, a := range info.Audio {
+		layout := a.ChannelLayout
+		if layout == "" {
+			layout = fmt.Sprintf("%d ch", a.Channels)
+		}
+		fmt.Fprintf(tw, "Audio #%d\t%s, %s, %s, %d Hz, %s\n", a.Index,
+			a.Codec, orNA(a.Language), layout, a.SampleRate, formatBitRate(a.BitRate))
+	}
+	for _, s := range info.Subtitles {
+		fmt.Fprintf(tw, "Subtitle #%d\t%s, %s\n", s.Index, s.Codec,
+			strings.TrimSuffix(orNA(s.Language)+", "+s.Title, ", "))
+	}
+	for i, c := range info.Chapters {
+		fmt.Fprintf(tw, "Chapter %d\t%s - %s  %s\n", i+1,
+			formatDuration(c.Start), formatDuration(c.End), c.Title)
+	}
+}
+
+func formatDuration(d time.Duration) string {
+	if d <= 0 {
+		return "N/A"
+	}
+	total := int(d.Seconds())
+	ms := int(d.Milliseconds() % 1000)
+	return fmt.Sprintf("%d:%02d:%02d.%03d", total/3600, total/60%60, total%60, ms)
+}
+
+func formatBitRate(bps int64) string {
+	if bps <= 0 {
+		return "N/A"
+	}
+	return fmt.Sprintf("%d kb/s", bps/1000)
+}
+
+func orNA(s string) string {
+	if s == "" {
+		return "N/A"
+	}
+	return s
+}
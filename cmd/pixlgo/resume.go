@@ -0,0 +1,84 @@
+package main
+
+import (
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/logger"
+	"github.com/0bVdnt/PixlGo/internal/player"
+	"github.com/0bVdnt/PixlGo/internal/resume"
+)
+
+// A place resume positions are kept
+type resumeStore interface {
+	Get(video string) (time.Duration, bool)
+	Set(video string, pos time.Duration) error
+	Delete(video string) error
+}
+
+// The enabled resume stores, highest precedence first
+type resumeStores []resumeStore
+
+// Returns the enabled stores; state is pixlgo's own file, also used for
+// per-file settings
+func openResumeStores(state *resume.Store, native, mpv bool) resumeStores {
+	var stores resumeStores
+	if native {
+		stores = append(stores, state)
+	}
+	if mpv {
+		if dir := resume.MPVDir(); dir != "" {
+			stores = append(stores, resume.OpenMPV(dir))
+		}
+	}
+	return stores
+}
+
+// Returns the saved position from the first store that has one
+func (rs resumeStores) position(video string) time.Duration {
+	for _, s := range rs {
+		if pos, ok := s.Get(video); ok {
+			return pos
+		}
+	}
+	return 0
+}
+
+// Records where playback of video stopped in every store. Finished videos
+// and positions near either end are forgotten instead; live streams and
+// piped input have no position worth keeping.
+func (rs resumeStores) save(video string, st player.Status, log *logger.Logger) {
+	if len(rs) == 0 || st.State == player.StateError || st.Live {
+		return
+	}
+	done := st.State == player.StateEnded ||
+		st.Position < resume.MinRemaining ||
+		st.DurationKnown && st.Duration-st.Position < resume.MinRemaining
+	for _, s := range rs {
+		var err error
+		if done {
+			err = s.Delete(video)
+		} else {
+			err = s.Set(video, st.Position)
+		}
+		if err != nil {
+			log.Warn("could not save resume position", "video", video, "err", err)
+		}
+	}
+}
+
+// Returns the settings saved for video, or nil when there are none
+func savedSettings(state *resume.Store, video string) *player.Settings {
+	saved, ok := state.Settings(video)
+	if !ok {
+		return nil
+	}
+	return &player.Settings{AutoLevels: saved.AutoLevels}
+}
+
+// Remembers the settings video was played with; defaults are forgotten
+func saveSettings(state *resume.Store, video string, st player.Settings, log *logger.Logger) {
+	err := state.SetSettings(video, resume.Settings{AutoLevels: st.AutoLevels})
+	if err != nil {
+		log.Warn("could not save settings", "video", video, "err", err)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/player"
+	"github.com/0bVdnt/PixlGo/internal/server"
+	"github.com/0bVdnt/PixlGo/internal/video"
+	"github.com/0bVdnt/PixlGo/internal/web"
+)
+
+var serveCommand = &command{
+	name:     "serve",
+	synopsis: "serve [options] FILE",
+	summary: "Stream a video, looping, to every telnet or raw TCP client that connects,\n" +
+		"e.g. 'telnet host 2323' or 'nc host 2323'. The file is decoded once;\n" +
+		"each client gets frames sized to its window when telnet reports one.\n" +
+		"Clients quit with q or Ctrl-C.",
+	setup: setupServe,
+}
+
+// Width in pixels the shared frames are decoded at; clients scale from it
+const serveSourceWidth = 320
+
+func setupServe(fs *flag.FlagSet) func(*globalOptions, []string) int {
+	listen := fs.String("listen", ":2323", "Address to listen on")
+	size := fs.String("size", "80x24", "Window size in cells for clients that don't report one: COLS or COLSxROWS")
+	maxClients := fs.Int("max-clients", 32, "Maximum simultaneous clients (0 = unlimited)")
+	idleTimeout := fs.Duration("idle-timeout", 30*time.Second, "Disconnect clients that accept no output for this long")
+	fps := fs.Float64("fps", 0, "Frames per second (default: automatic, capped at the source rate)")
+	httpAddr := fs.String("http", "", "Serve /metrics and /debug/vars on this address, e.g. :8080")
+
+	return func(g *globalOptions, args []string) int {
+		if len(args) != 1 {
+			return usageError(fs, "expected exactly one file")
+		}
+		if *maxClients < 0 {
+			return usageError(fs, "-max-clients must not be negative")
+		}
+		if *fps < 0 {
+			return usageError(fs, "-fps must not be negative")
+		}
+		path := args[0]
+
+		log := g.openLogger()
+		defer log.Close()
+
+		meta, err := video.Probe(path)
+		if err == nil {
+			err = meta.RequireVideo()
+		}
+		if err != nil {
+			return fail(err, "file", path)
+		}
+		cols, rows, err := parseCellSize(*size, meta.DisplayAspect())
+		if err != nil {
+			return usageError(fs, "%v", err)
+		}
+
+		width := serveSourceWidth
+		height := int(float64(width)/meta.DisplayAspect()+0.5) &^ 1
+		config := video.StreamConfig{
+			Width:       width,
+			Height:      height,
+			TargetFPS:   video.DefaultTargetFPS(cols, rows*2, meta.FPS),
+			StreamIndex: meta.StreamIndex,
+			ColorRange:  meta.ColorRange,
+			Rotation:    meta.Rotation,
+		}
+		if *fps > 0 {
+			config.TargetFPS = min(*fps, meta.FPS)
+		}
+
+		ln, err := net.Listen("tcp", *listen)
+		if err != nil {
+			return fail(err, "listen", *listen)
+		}
+		fmt.Fprintf(os.Stderr, "Serving %s on %s (Ctrl-C to stop)\n", path, ln.Addr())
+		log.Info("serve starting", "file", path, "listen", ln.Addr().String(),
+			"fps", config.TargetFPS, "max_clients", *maxClients)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		src := server.NewSource()
+		srcErr := make(chan error, 1)
+		go func() {
+			srcErr <- src.Run(ctx, path, config)
+			cancel()
+		}()
+
+		srv := server.New(src, server.Config{
+			MaxClients:  *maxClients,
+			IdleTimeout: *idleTimeout,
+			Cols:        cols,
+			Rows:        rows,
+			Logger:      log,
+		})
+		if *httpAddr != "" {
+			hs, err := web.Start(*httpAddr, func() *player.Player { return nil }, log)
+			if err != nil {
+				return fail(fmt.Errorf("http: %w", err), "listen", *httpAddr)
+			}
+			defer hs.Close()
+			hs.AddGauge("serve_clients", "Connected telnet/TCP clients.", func() float64 {
+				return float64(srv.Clients())
+			})
+		}
+
+		if err := srv.Serve(ctx, ln); err != nil {
+			return fail(err, "listen", *listen)
+		}
+		if err := <-srcErr; err != nil && !errors.Is(err, context.Canceled) {
+			return fail(err, "file", path)
+		}
+		return exitOK
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !(aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || zos)
+
+package main
+
+import (
+	"github.com/0bVdnt/PixlGo/internal/control"
+	"github.com/0bVdnt/PixlGo/internal/logger"
+)
+
+// SIGUSR1 and SIGUSR2 don't exist here
+func handleControlSignals(exec control.ExecFunc, more func() bool, log *logger.Logger) func() {
+	return func() {}
+}
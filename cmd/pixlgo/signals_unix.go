@@ -0,0 +1,45 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || zos
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/0bVdnt/PixlGo/internal/control"
+	"github.com/0bVdnt/PixlGo/internal/logger"
+)
+
+// Turns SIGUSR1 into a pause toggle and SIGUSR2 into next file, or a 30s
+// seek on the last one. Commands go through exec, so they run on the
+// player's main loop like any other control command. Returns a function
+// that stops listening.
+func handleControlSignals(exec control.ExecFunc, more func() bool, log *logger.Logger) func() {
+	sigs := make(chan os.Signal, 4)
+	done := make(chan struct{})
+	signal.Notify(sigs, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				cmd := "toggle"
+				if sig == syscall.SIGUSR2 {
+					cmd = "seek +30"
+					if more() {
+						cmd = "next"
+					}
+				}
+				if _, err := exec(cmd); err != nil {
+					log.Debug("signal command failed", "signal", sig, "command", cmd, "err", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigs)
+		close(done)
+	}
+}
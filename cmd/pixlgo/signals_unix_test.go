@@ -0,0 +1,96 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || zos
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/fakeff"
+	"github.com/0bVdnt/PixlGo/internal/logger"
+	"github.com/0bVdnt/PixlGo/internal/player"
+	"github.com/0bVdnt/PixlGo/internal/video"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Runs a player for a fake two-minute clip on a simulation screen; done
+// closes when Run returns
+func runTestPlayer(t *testing.T) (p *player.Player, path string, done <-chan struct{}) {
+	t.Helper()
+	probe, _ := filepath.Abs(filepath.Join("testdata", "clip.json"))
+	exe := fakeff.Setenv(t, map[string]string{"PROBE": probe, "FRAMES": "100000", "INTERVAL": "5ms"})
+	video.SetTools(video.Tools{FFmpeg: exe, FFprobe: exe})
+	t.Cleanup(func() { video.SetTools(video.Tools{}) })
+
+	path = filepath.Join(t.TempDir(), "clip.mp4")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := player.New(player.Config{VideoPath: path, Screen: tcell.NewSimulationScreen("UTF-8"), NoAudio: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		p.Run()
+	}()
+	t.Cleanup(func() {
+		p.Stop()
+		<-finished
+	})
+	return p, path, finished
+}
+
+// Polls until cond holds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestControlSignals(t *testing.T) {
+	p, path, done := runTestPlayer(t)
+	list := &playlist{files: []string{path}}
+	list.pop()
+	stop := handleControlSignals(list.exec(func() *player.Player { return p }), list.more, logger.Noop())
+	defer stop()
+
+	state := func(want player.State) func() bool {
+		return func() bool { return p.Status().State == want }
+	}
+	waitFor(t, "playback", state(player.StatePlaying))
+
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	waitFor(t, "pause on SIGUSR1", state(player.StatePaused))
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	waitFor(t, "resume on SIGUSR1", state(player.StatePlaying))
+
+	// Without a next file SIGUSR2 seeks ahead
+	before := p.Status().Position
+	syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+	waitFor(t, "seek on SIGUSR2", func() bool { return p.Status().Position >= before+29*time.Second })
+	if p.Status().State == player.StateEnded {
+		t.Error("seek ended playback")
+	}
+
+	// With one it moves on
+	list.add(path)
+	syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGUSR2 didn't move to the next file")
+	}
+	if !p.Finished() {
+		t.Error("Run returned without finishing")
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/0bVdnt/PixlGo/internal/sheet"
+	"github.com/0bVdnt/PixlGo/internal/video"
+)
+
+var thumbsCommand = &command{
+	name:     "thumbs",
+	synopsis: "thumbs [options] FILE",
+	summary: "Make a contact sheet of evenly spaced frames with timestamps,\n" +
+		"as an image (-o) or printed to the terminal (-ansi).",
+	setup: setupThumbs,
+}
+
+const (
+	defaultSheetWidth = 1280
+	sheetGap          = 4
+)
+
+func setupThumbs(fs *flag.FlagSet) func(*globalOptions, []string) int {
+	output := fs.String("o", "", "Output image file (.png, .jpg); - writes PNG to stdout")
+	grid := fs.String("grid", "4x4", "Thumbnails as COLSxROWS")
+	width := fs.Int("width", 0, "Sheet width in pixels, or columns with -ansi (default 1280, or 80 with -ansi)")
+	ansi := fs.Bool("ansi", false, "Print the sheet as ANSI half blocks to stdout instead of writing an image")
+
+	return func(g *globalOptions, args []string) int {
+		if len(args) != 1 {
+			return usageError(fs, "expected exactly one file")
+		}
+		if *ansi == (*output != "") {
+			return usageError(fs, "need exactly one of -o or -ansi")
+		}
+		cols, rows, err := parseGrid(*grid)
+		if err != nil {
+			return usageError(fs, "%v", err)
+		}
+
+		var encode func(io.Writer, image.Image) error
+		if !*ansi {
+			if encode, err = imageEncoder(*output); err != nil {
+				return usageError(fs, "%v", err)
+			}
+		}
+		path := args[0]
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		meta, err := video.Probe(path)
+		if err == nil {
+			err = meta.RequireVideo()
+		}
+		if err != nil {
+			return fail(err, "file", path)
+		}
+
+		// A clip with fewer frames than cells would only repeat itself
+		frameCount := int(meta.Duration.Seconds() * meta.FPS)
+		if meta.Duration <= 0 || meta.AttachedPic {
+			frameCount = 1
+		}
+		if fc, fr := sheet.FitGrid(cols, rows, frameCount); fc*fr < cols*rows {
+			fmt.Fprintf(os.Stderr, "Warning: video is short, using a %dx%d grid\n", fc, fr)
+			cols, rows = fc, fr
+		}
+
+		sheetW := *width
+		var tileW int
+		if *ansi {
+			if sheetW <= 0 {
+				sheetW = ansiDefaultWidth
+			}
+			// One space between thumbnails
+			tileW = (sheetW - (cols - 1)) / cols
+		} else {
+			if sheetW <= 0 {
+				sheetW = defaultSheetWidth
+			}
+			tileW = (sheetW - sheetGap*(cols+1)) / cols
+		}
+		if tileW < 4 {
+			return usageError(fs, "-width %d is too small for %d columns", sheetW, cols)
+		}
+		tileH := int(float64(tileW)/meta.DisplayAspect() + 0.5)
+
+		timestamps := sheet.Timestamps(meta.Duration, cols*rows)
+		frames, err := video.ExtractFrames(ctx, path, meta, timestamps, tileW, tileH)
+		if err != nil {
+			return fail(err, "file", path)
+		}
+
+		s := &sheet.Sheet{Cols: cols, Frames: frames}
+		for _, f := range frames {
+			s.Labels = append(s.Labels, sheetLabel(f.Timestamp.Seconds()))
+		}
+
+		if *ansi {
+			fmt.Print(s.ANSI())
+			return exitOK
+		}
+		if err := writeImage(*output, s.Image(sheetGap), encode); err != nil {
+			return fail(err)
+		}
+		return exitOK
+	}
+}
+
+// Parses "COLSxROWS"
+func parseGrid(s string) (int, int, error) {
+	c, r, ok := strings.Cut(strings.ToLower(s), "x")
+	cols, err1 := strconv.Atoi(c)
+	rows, err2 := strconv.Atoi(r)
+	if !ok || err1 != nil || err2 != nil || cols < 1 || rows < 1 || cols*rows > 100 {
+		return 0, 0, fmt.Errorf("invalid grid %q (want COLSxROWS, at most 100 cells)", s)
+	}
+	return cols, rows, nil
+}
+
+// Formats a thumbnail timestamp as h:mm:ss or m:ss
+func sheetLabel(sec float64) string {
+	total := int(sec)
+	if total >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", total/3600, total/60%60, total%60)
+	}
+	return fmt.Sprintf("%d:%02d", total/60, total%60)
+}
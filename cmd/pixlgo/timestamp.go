@@ -0,0 +1,28 @@
+package main
+
+import (
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/timecode"
+)
+
+// A flag.Value holding a timestamp in any form timecode.Parse accepts
+type timestampFlag struct {
+	d time.Duration
+}
+
+func (t *timestampFlag) String() string {
+	if t == nil {
+		return "0"
+	}
+	return formatDuration(t.d)
+}
+
+func (t *timestampFlag) Set(s string) error {
+	d, err := timecode.Parse(s)
+	if err != nil {
+		return err
+	}
+	t.d = d
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/video"
+)
+
+// Set at build time, e.g.
+//
+//	go build -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.date=$(date -u +%FT%TZ)"
+//
+// Anything left empty is filled in from the module build info when possible.
+const defaultVersion = "0.1.0"
+
+var (
+	version = defaultVersion
+	commit  = ""
+	date    = ""
+)
+
+var versionCommand = &command{
+	name:     "version",
+	synopsis: "version",
+	summary:  "Print version, build and FFmpeg information (same as -version).",
+	setup: func(fs *flag.FlagSet) func(*globalOptions, []string) int {
+		return func(_ *globalOptions, args []string) int {
+			if len(args) != 0 {
+				return usageError(fs, "version takes no arguments")
+			}
+			printVersion()
+			return exitOK
+		}
+	},
+}
+
+// Version and build details, with fallbacks from debug.ReadBuildInfo
+type buildDetails struct {
+	version  string
+	commit   string
+	date     string
+	modified bool
+	goVer    string
+}
+
+func readBuildDetails() buildDetails {
+	b := buildDetails{version: version, commit: commit, date: date, goVer: runtime.Version()}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return b
+	}
+
+	// `go install ...@v1.2.3` records the module version; local builds get
+	// pseudo-versions that say less than the commit line below
+	if v := info.Main.Version; isReleaseVersion(v) && version == defaultVersion {
+		b.version = v
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if b.commit == "" {
+				b.commit = s.Value
+			}
+		case "vcs.time":
+			if b.date == "" {
+				b.date = s.Value
+			}
+		case "vcs.modified":
+			b.modified = s.Value == "true"
+		}
+	}
+	return b
+}
+
+const versionToolTimeout = 3 * time.Second
+
+func printVersion() {
+	b := readBuildDetails()
+	fmt.Printf("pixlgo v%s\n", trimV(b.version))
+
+	commitStr := orNA(b.commit)
+	if len(commitStr) > 12 {
+		commitStr = commitStr[:12]
+	}
+	if b.modified {
+		commitStr += " (modified)"
+	}
+	fmt.Printf("  commit:   %s\n", commitStr)
+	fmt.Printf("  built:    %s\n", orNA(b.date))
+	fmt.Printf("  go:       %s %s/%s\n", b.goVer, runtime.GOOS, runtime.GOARCH)
+
+	ctx, cancel := context.WithTimeout(context.Background(), versionToolTimeout)
+	defer cancel()
+	for _, name := range []string{"ffmpeg", "ffprobe"} {
+		fmt.Printf("  %-9s %s\n", name+":", describeTool(video.DetectTool(ctx, name)))
+	}
+}
+
+func describeTool(t video.ToolInfo) string {
+	switch {
+	case !t.Found:
+		return "not found"
+	case t.Version == "":
+		return "unknown version (" + t.Path + ")"
+	case !t.MeetsMinimum():
+		return fmt.Sprintf("%s (%s), older than the minimum %d.x", t.Version, t.Path, video.MinFFmpegMajor)
+	}
+	return fmt.Sprintf("%s (%s)", t.Version, t.Path)
+}
+
+var pseudoVersion = regexp.MustCompile(`\d{14}-[0-9a-f]{12}`)
+
+func isReleaseVersion(v string) bool {
+	return v != "" && v != "(devel)" && !strings.Contains(v, "+") && !pseudoVersion.MatchString(v)
+}
+
+func trimV(v string) string {
+	if len(v) > 1 && v[0] == 'v' {
+		return v[1:]
+	}
+	return v
+}
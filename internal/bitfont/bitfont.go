@@ -0,0 +1,67 @@
+// Package bitfont draws text with a chunky 3x5 pixel font that stays
+// readable when every pixel is half a terminal cell.
+package bitfont
+
+import (
+	"image"
+	"image/color"
+)
+
+// 3x5 bitmap glyphs for timestamps, one row per string, '#' set
+var glyphs = map[rune][5]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", "..#", "..#"},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	':': {"...", ".#.", "...", ".#.", "..."},
+	'.': {"...", "...", "...", "...", ".#."},
+	' ': {"...", "...", "...", "...", "..."},
+}
+
+// Glyph size in pixels at scale 1
+const (
+	GlyphW = 3
+	GlyphH = 5
+)
+
+// Returns the size of text drawn at the given scale, with one column of
+// spacing between glyphs
+func TextSize(text string, scale int) (int, int) {
+	n := len([]rune(text))
+	if n == 0 {
+		return 0, 0
+	}
+	return (n*(GlyphW+1) - 1) * scale, GlyphH * scale
+}
+
+// Draws text with its top-left corner at (x, y). Unknown runes are skipped.
+func Draw(img *image.RGBA, x, y int, text string, scale int, c color.RGBA) {
+	for _, r := range text {
+		if g, ok := glyphs[r]; ok {
+			for gy, row := range g {
+				for gx, bit := range row {
+					if bit == '#' {
+						Fill(img, image.Rect(x+gx*scale, y+gy*scale, x+(gx+1)*scale, y+(gy+1)*scale), c)
+					}
+				}
+			}
+		}
+		x += (GlyphW + 1) * scale
+	}
+}
+
+// Fills r, clipped to img, with c
+func Fill(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	r = r.Intersect(img.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Package chapters holds user markers and writes them out as chapter lists:
+// an FFMETADATA file ffmpeg reads with -map_metadata, and the plain
+// "00:12:34 Title" list video sites accept in descriptions.
+package chapters
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A named point in a video
+type Marker struct {
+	Pos   time.Duration
+	Title string
+}
+
+// Sorts markers by position, keeping the order of equal ones
+func Sort(markers []Marker) {
+	sort.SliceStable(markers, func(i, j int) bool {
+		return markers[i].Pos < markers[j].Pos
+	})
+}
+
+// Writes markers as an FFMETADATA1 file with one chapter per marker, each
+// ending where the next begins. The last one ends at duration, or at its own
+// start when the duration is unknown (zero). markers must be sorted.
+func WriteFFMetadata(w io.Writer, markers []Marker, duration time.Duration) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(";FFMETADATA1\n")
+	for i, m := range markers {
+		end := max(duration, m.Pos)
+		if i+1 < len(markers) {
+			end = markers[i+1].Pos
+		}
+		fmt.Fprintf(bw, "\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=%d\nEND=%d\ntitle=%s\n",
+			m.Pos.Milliseconds(), end.Milliseconds(), escapeMetadata(m.Title))
+	}
+	return bw.Flush()
+}
+
+// Escapes the characters FFMETADATA treats specially with a backslash
+func escapeMetadata(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case '=', ';', '#', '\\', '\n':
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// Writes markers as "00:12:34 Title" lines. Sites require the list to start
+// at zero, so a "Start" entry is added when the first marker is later.
+// markers must be sorted.
+func WriteList(w io.Writer, markers []Marker) error {
+	bw := bufio.NewWriter(w)
+	if len(markers) > 0 && markers[0].Pos >= time.Second {
+		bw.WriteString("00:00:00 Start\n")
+	}
+	for _, m := range markers {
+		d := m.Pos.Truncate(time.Second)
+		title := strings.ReplaceAll(m.Title, "\n", " ")
+		fmt.Fprintf(bw, "%02d:%02d:%02d %s\n",
+			d/time.Hour, (d%time.Hour)/time.Minute, (d%time.Minute)/time.Second, title)
+	}
+	return bw.Flush()
+}
+
+// Writes video's markers next to it as <name>.ffmetadata and, with list set,
+// <name>.chapters.txt. Returns the paths written.
+func Export(video string, markers []Marker, duration time.Duration, list bool) ([]string, error) {
+	if strings.Contains(video, "://") {
+		return nil, fmt.Errorf("can't write next to a URL")
+	}
+	sorted := append([]Marker(nil), markers...)
+	Sort(sorted)
+
+	base := strings.TrimSuffix(video, filepath.Ext(video))
+	paths := []string{base + ".ffmetadata"}
+	if err := writeFile(paths[0], func(w io.Writer) error {
+		return WriteFFMetadata(w, sorted, duration)
+	}); err != nil {
+		return nil, err
+	}
+	if list {
+		path := base + ".chapters.txt"
+		if err := writeFile(path, func(w io.Writer) error {
+			return WriteList(w, sorted)
+		}); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
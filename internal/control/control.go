@@ -0,0 +1,29 @@
+// Package control lets other programs drive the player with plain-text
+// commands, one per line, as accepted by player.Exec.
+package control
+
+import (
+	"strings"
+
+	"github.com/0bVdnt/PixlGo/internal/logger"
+)
+
+// Runs one command and returns its reply
+type ExecFunc func(line string) (string, error)
+
+// Runs line unless it is blank or a # comment and returns the reply to send
+// back, if any. Failed and unknown commands are logged and answered with an
+// "error: ..." reply.
+func handleLine(exec ExecFunc, line string, log *logger.Logger) (string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", false
+	}
+	out, err := exec(line)
+	if err != nil {
+		log.Warn("control command failed", "command", line, "err", err)
+		return "error: " + err.Error(), true
+	}
+	log.Debug("control command", "command", line, "reply", out)
+	return out, true
+}
@@ -0,0 +1,20 @@
+//go:build !(aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || zos)
+
+package control
+
+import (
+	"errors"
+
+	"github.com/0bVdnt/PixlGo/internal/logger"
+)
+
+type FIFO struct{}
+
+// Named pipes of the Unix kind don't exist here
+func OpenFIFO(path, replyPath string, log *logger.Logger) (*FIFO, error) {
+	return nil, errors.New("input fifo is not supported on this platform")
+}
+
+func (f *FIFO) Serve(exec ExecFunc) {}
+
+func (f *FIFO) Close() {}
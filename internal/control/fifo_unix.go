@@ -0,0 +1,102 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || zos
+
+package control
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/0bVdnt/PixlGo/internal/logger"
+	"golang.org/x/sys/unix"
+)
+
+// Reads newline-delimited commands from a named pipe, mpv input-file style:
+//
+//	echo 'seek +30' > /tmp/pixlgo.fifo
+type FIFO struct {
+	path      string
+	replyPath string
+	created   bool
+	file      *os.File
+	log       *logger.Logger
+	closeOnce sync.Once
+}
+
+// Opens path for reading, creating the FIFO if it doesn't exist. Replies go
+// to replyPath when set (a FIFO nobody reads from drops them), otherwise to
+// the log.
+func OpenFIFO(path, replyPath string, log *logger.Logger) (*FIFO, error) {
+	if log == nil {
+		log = logger.Noop()
+	}
+	f := &FIFO{path: path, replyPath: replyPath, log: log}
+
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if err := unix.Mkfifo(path, 0o600); err != nil {
+			return nil, fmt.Errorf("create fifo: %w", err)
+		}
+		f.created = true
+	case err != nil:
+		return nil, err
+	case info.Mode()&fs.ModeNamedPipe == 0:
+		return nil, fmt.Errorf("%s exists and is not a fifo", path)
+	}
+
+	// Opening read-write keeps a writer attached ourselves, so the open
+	// doesn't block and reads never see EOF when script writers come and go
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		f.remove()
+		return nil, err
+	}
+	f.file = file
+	return f, nil
+}
+
+// Runs commands until Close
+func (f *FIFO) Serve(exec ExecFunc) {
+	scanner := bufio.NewScanner(f.file)
+	for scanner.Scan() {
+		if reply, ok := handleLine(exec, scanner.Text(), f.log); ok {
+			f.reply(reply)
+		}
+	}
+}
+
+func (f *FIFO) reply(msg string) {
+	if f.replyPath == "" {
+		f.log.Info("control reply", "reply", msg)
+		return
+	}
+	// Non-blocking so a reply FIFO without a reader can't stall commands
+	out, err := os.OpenFile(f.replyPath, os.O_WRONLY|os.O_APPEND|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		f.log.Debug("control reply dropped", "path", f.replyPath, "err", err)
+		return
+	}
+	defer out.Close()
+	if _, err := out.WriteString(msg + "\n"); err != nil {
+		f.log.Debug("control reply dropped", "path", f.replyPath, "err", err)
+	}
+}
+
+// Stops Serve and removes the FIFO if OpenFIFO created it
+func (f *FIFO) Close() {
+	f.closeOnce.Do(func() {
+		f.file.Close()
+		f.remove()
+	})
+}
+
+func (f *FIFO) remove() {
+	if f.created {
+		os.Remove(f.path)
+	}
+}
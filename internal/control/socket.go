@@ -0,0 +1,138 @@
+package control
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/logger"
+)
+
+var (
+	// Another live instance already listens on the socket
+	ErrInstanceRunning = errors.New("another instance is running")
+	// Nothing listens on the socket, or it is stale
+	ErrNoInstance = errors.New("no running instance")
+)
+
+// How long a client waits to connect and for a reply
+const socketTimeout = 2 * time.Second
+
+// Returns the well-known socket path running instances listen on:
+// $XDG_RUNTIME_DIR/pixlgo.sock, or a per-user name in the temp dir
+func SocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "pixlgo.sock")
+	}
+	name := "pixlgo.sock"
+	if uid := os.Getuid(); uid >= 0 {
+		name = fmt.Sprintf("pixlgo-%d.sock", uid)
+	}
+	return filepath.Join(os.TempDir(), name)
+}
+
+// A Unix domain socket accepting commands, one per line, each answered with
+// one reply line
+type Socket struct {
+	path      string
+	ln        net.Listener
+	log       *logger.Logger
+	closeOnce sync.Once
+}
+
+// Listens on path. A socket file left behind by a crashed instance is
+// replaced; one with a live instance behind it returns ErrInstanceRunning,
+// and anything that isn't a socket is left alone and returns an error.
+func Listen(path string, log *logger.Logger) (*Socket, error) {
+	if log == nil {
+		log = logger.Noop()
+	}
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		conn, err := net.DialTimeout("unix", path, socketTimeout)
+		if err == nil {
+			conn.Close()
+			return nil, ErrInstanceRunning
+		}
+		log.Debug("removing stale control socket", "path", path, "err", err)
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	// Only the owner may drive the player
+	ln, err := listenPrivate(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Socket{path: path, ln: ln, log: log}, nil
+}
+
+// Returns the socket's path
+func (s *Socket) Path() string {
+	return s.path
+}
+
+// Runs commands from every client until Close
+func (s *Socket) Serve(exec ExecFunc) {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.serveConn(conn, exec)
+	}
+}
+
+func (s *Socket) serveConn(conn net.Conn, exec ExecFunc) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		if reply, ok := handleLine(exec, scanner.Text(), s.log); ok {
+			if _, err := conn.Write([]byte(reply + "\n")); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Stops Serve and removes the socket file
+func (s *Socket) Close() {
+	s.closeOnce.Do(func() {
+		// Closing a Unix listener removes the file it created
+		s.ln.Close()
+	})
+}
+
+// Sends one command to the instance listening on path and returns its
+// reply. Replies starting with "error: " come back as errors. Returns
+// ErrNoInstance when nothing answers.
+func Send(path, line string) (string, error) {
+	conn, err := net.DialTimeout("unix", path, socketTimeout)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrNoInstance, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(socketTimeout))
+
+	if _, err := conn.Write([]byte(strings.TrimSpace(line) + "\n")); err != nil {
+		return "", err
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	reply = strings.TrimSuffix(reply, "\n")
+	if msg, ok := strings.CutPrefix(reply, "error: "); ok {
+		return "", errors.New(msg)
+	}
+	return reply, nil
+}
@@ -0,0 +1,11 @@
+//go:build !(aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || zos)
+
+package control
+
+import "net"
+
+// Listens on a Unix socket at path. Windows has no umask or socket file
+// modes; the file takes the ACL of the directory it is created in.
+func listenPrivate(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,62 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || zos
+
+package control
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenPrivate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pixlgo.sock")
+	s, err := Listen(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	fi, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0o600 {
+		t.Errorf("socket mode %o, want 600", perm)
+	}
+	if _, err := Listen(path, nil); !errors.Is(err, ErrInstanceRunning) {
+		t.Errorf("second Listen = %v, want ErrInstanceRunning", err)
+	}
+}
+
+// A socket nothing listens on anymore is replaced
+func TestListenStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pixlgo.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+
+	s, err := Listen(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+}
+
+// Whatever else sits at the path is not the player's to delete
+func TestListenNotSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pixlgo.sock")
+	if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := Listen(path, nil); err == nil {
+		s.Close()
+		t.Fatal("Listen replaced a regular file")
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "data" {
+		t.Errorf("file changed: %q, %v", data, err)
+	}
+}
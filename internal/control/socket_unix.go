@@ -0,0 +1,32 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || zos
+
+package control
+
+import (
+	"net"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// The umask is process-wide, so narrowing it is serialized
+var umaskMu sync.Mutex
+
+// Listens on a Unix socket at path that only the owner can connect to. The
+// umask is narrowed while the socket file is created, so it is never open to
+// anyone else, not even between net.Listen and the chmod.
+func listenPrivate(path string) (net.Listener, error) {
+	umaskMu.Lock()
+	old := syscall.Umask(0o077)
+	ln, err := net.Listen("unix", path)
+	syscall.Umask(old)
+	umaskMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
@@ -0,0 +1,225 @@
+// Package fakeff lets a test binary stand in for ffmpeg and ffprobe, so
+// tests of the code driving them run without either installed.
+package fakeff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Environment variable that makes Main act as the fake tool
+const envTool = "PIXLGO_FAKE_TOOL"
+
+// Runs the fake tool and exits when the binary was started as one. Call it
+// first thing in TestMain.
+func Main() {
+	if os.Getenv(envTool) != "" {
+		os.Exit(run(os.Args[1:]))
+	}
+}
+
+// Sets the PIXLGO_FAKE_* settings, keys without the prefix, for the rest of
+// t, and returns the binary to use as ffmpeg and ffprobe. The settings:
+//
+//	ARGS      file that gets a line with the arguments per run
+//	PROBE     ffprobe JSON printed by runs with -show_entries, which then
+//	          exit without doing anything else
+//	PACKETS   file printed instead of PROBE by runs asking for packet entries
+//	FLAT      default-writer output printed by runs with -show_streams; runs
+//	          with -show_entries fail then, as ffprobe builds without it do
+//	STDOUT    file copied to stdout
+//	STDERR    file copied to stderr
+//	FRAMES    raw rgb24 frames to write, sized by the scale filter; -vframes
+//	          lowers the count as in ffmpeg
+//	INTERVAL  pause between frames
+//	STALL     one extra pause halfway through the frames
+//	HANG      close stdout once the output is written, then keep running
+//	          until killed
+//	EXIT      exit status
+func Setenv(t *testing.T, env map[string]string) string {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(envTool, "1")
+	for key, value := range env {
+		t.Setenv("PIXLGO_FAKE_"+key, value)
+	}
+	return exe
+}
+
+var scaleSize = regexp.MustCompile(`scale=(\d+):(\d+)`)
+
+func run(args []string) int {
+	if path := os.Getenv("PIXLGO_FAKE_ARGS"); path != "" {
+		// As bytes, which JSON keeps intact even when not UTF-8
+		raw := make([][]byte, len(args))
+		for i, arg := range args {
+			raw[i] = []byte(arg)
+		}
+		line, _ := json.Marshal(raw)
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err == nil {
+			f.Write(append(line, '\n'))
+			f.Close()
+		}
+	}
+	if path := os.Getenv("PIXLGO_FAKE_FLAT"); path != "" {
+		if slices.Contains(args, "-show_entries") {
+			fmt.Fprintln(os.Stderr, "Unrecognized option 'show_entries'.")
+			fmt.Fprintln(os.Stderr, "Error splitting the argument list: Option not found")
+			return 1
+		}
+		if slices.Contains(args, "-show_streams") {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			os.Stdout.Write(data)
+			return 0
+		}
+	}
+	if path := os.Getenv("PIXLGO_FAKE_PROBE"); path != "" && slices.Contains(args, "-show_entries") {
+		if packets := os.Getenv("PIXLGO_FAKE_PACKETS"); packets != "" && slices.ContainsFunc(args, func(arg string) bool {
+			return strings.HasPrefix(arg, "packet=")
+		}) {
+			path = packets
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		os.Stdout.Write(data)
+		return 0
+	}
+	for _, out := range []struct {
+		key  string
+		file *os.File
+	}{{"PIXLGO_FAKE_STDOUT", os.Stdout}, {"PIXLGO_FAKE_STDERR", os.Stderr}} {
+		if path := os.Getenv(out.key); path != "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			out.file.Write(data)
+		}
+	}
+
+	frames, _ := strconv.Atoi(os.Getenv("PIXLGO_FAKE_FRAMES"))
+	interval, _ := time.ParseDuration(os.Getenv("PIXLGO_FAKE_INTERVAL"))
+	stall, _ := time.ParseDuration(os.Getenv("PIXLGO_FAKE_STALL"))
+	if frames > 0 {
+		w, h := 2, 2
+		for i, arg := range args {
+			if m := scaleSize.FindStringSubmatch(arg); m != nil {
+				w, _ = strconv.Atoi(m[1])
+				h, _ = strconv.Atoi(m[2])
+			}
+			if arg == "-vframes" && i+1 < len(args) {
+				if n, err := strconv.Atoi(args[i+1]); err == nil {
+					frames = min(frames, n)
+				}
+			}
+		}
+		frame := make([]byte, w*h*3)
+		for i := range frames {
+			for j := range frame {
+				frame[j] = byte(i)
+			}
+			if _, err := os.Stdout.Write(frame); err != nil {
+				return 1
+			}
+			time.Sleep(interval)
+			if i == frames/2 {
+				time.Sleep(stall)
+			}
+		}
+	}
+
+	if os.Getenv("PIXLGO_FAKE_HANG") != "" {
+		// Only a kill ends it, as with an ffmpeg stuck on a dead input
+		os.Stdout.Close()
+		signal.Ignore(syscall.SIGINT, syscall.SIGTERM)
+		for {
+			time.Sleep(time.Hour)
+		}
+	}
+	code, _ := strconv.Atoi(os.Getenv("PIXLGO_FAKE_EXIT"))
+	return code
+}
+
+// Returns the argument lists recorded in the ARGS file, oldest run first
+func Args(t *testing.T, path string) [][]string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var runs [][]string
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var raw [][]byte
+		if err := dec.Decode(&raw); err != nil {
+			t.Fatal(err)
+		}
+		args := make([]string, len(raw))
+		for i, arg := range raw {
+			args[i] = string(arg)
+		}
+		runs = append(runs, args)
+	}
+	return runs
+}
+
+// Returns the PIDs of this process's children, zombies included, or skips
+// the test where /proc can't tell
+func ChildProcesses(t *testing.T) []int {
+	t.Helper()
+	stats, _ := filepath.Glob("/proc/[0-9]*/stat")
+	if len(stats) == 0 {
+		t.Skip("no /proc to list child processes")
+	}
+	var pids []int
+	for _, path := range stats {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		// pid (comm) state ppid ...; comm may hold spaces and parentheses
+		fields := strings.Fields(string(data[bytes.LastIndexByte(data, ')')+1:]))
+		if len(fields) > 1 && fields[1] == strconv.Itoa(os.Getpid()) {
+			pid, _ := strconv.Atoi(strings.Fields(string(data))[0])
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// Waits up to a few seconds for the goroutine count to drop back to n
+func WaitGoroutines(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > n {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("%d goroutines, want at most %d:\n%s", runtime.NumGoroutine(), n, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// How long DumpCrash waits for the mutex before giving up on the ring
+const crashLockWait = 100 * time.Millisecond
+
+// Writes the panic reason, stack trace and the in-memory log ring to
+// dir/pixlgo-crash-<timestamp>.log and returns the file path. Safe to call
+// from a panicking goroutine; if one died while holding the lock, the file
+// has the stack without the log entries.
+func (l *Logger) DumpCrash(dir string, reason any, stack []byte) (string, error) {
+	locked := false
+	deadline := time.Now().Add(crashLockWait)
+	for time.Now().Before(deadline) {
+		if l.mu.TryLock() {
+			locked = true
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	var lines []string
+	if locked {
+		lines = l.tail(0)
+		if l.writer != nil && l.file != nil {
+			l.writer.Flush()
+		}
+		l.mu.Unlock()
+	}
+
+	name := fmt.Sprintf("pixlgo-crash-%s.log", time.Now().Format("20060102-150405"))
+	path := filepath.Join(dir, name)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "pixlgo crash at %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&sb, "reason: %v\n\n", reason)
+	sb.WriteString("--- stack ---\n")
+	sb.Write(stack)
+	if !locked {
+		sb.WriteString("\n--- log entries unavailable, the logger stayed locked ---\n")
+	} else {
+		fmt.Fprintf(&sb, "\n--- last %d log entries ---\n", len(lines))
+	}
+	for _, line := range lines {
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
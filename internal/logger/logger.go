@@ -1,23 +1,163 @@
 package logger
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
+	"strings"
 	"sync"
 	"time"
 )
 
-// Thread safe debug logging
-type Logger struct {
+// Severity of a log message
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+func (l Level) slogLevel() slog.Level {
+	switch l {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelWarn:
+		return slog.LevelWarn
+	case LevelError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// Parses a level name (debug, info, warn, error)
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info", "":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q", s)
+}
+
+// Output encoding of the log file
+type Format int
+
+const (
+	FormatText Format = iota
+	FormatJSON
+)
+
+// Parses a format name (text, json)
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "text", "":
+		return FormatText, nil
+	case "json":
+		return FormatJSON, nil
+	}
+	return FormatText, fmt.Errorf("unknown log format %q", s)
+}
+
+const (
+	DefaultMaxSize = 4 << 20
+	DefaultBackups = 3
+
+	flushInterval = 500 * time.Millisecond
+	ringSize      = 500
+)
+
+// A log call, formatted for the ring as soon as it is made so later changes
+// to what was logged don't show up in it
+type entry struct {
+	time   time.Time
+	level  Level
+	printf bool
+	format string
+	args   []any
+	attrs  []any
+}
+
+func (e entry) message() string {
+	if e.printf {
+		return fmt.Sprintf(e.format, e.args...)
+	}
+	return e.format
+}
+
+func (e entry) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[%s] %-5s %s", e.time.Format("15:04:05.000"), e.level, e.message())
+
+	fields := e.attrs
+	if !e.printf {
+		fields = append(fields[:len(fields):len(fields)], e.args...)
+	}
+	r := slog.NewRecord(e.time, 0, "", 0)
+	r.Add(fields...)
+	r.Attrs(func(a slog.Attr) bool {
+		fmt.Fprintf(&sb, " %s=%v", a.Key, a.Value)
+		return true
+	})
+	return sb.String()
+}
+
+// State shared by a Logger and everything derived from it with With
+type core struct {
 	mu      sync.Mutex
 	file    *os.File
+	writer  *bufio.Writer
+	handler slog.Handler
 	enabled bool
+	level   Level
+
+	path    string
+	size    int64
+	maxSize int64
+	backups int
+
+	stopFlush chan struct{}
+	flushDone chan struct{}
+
+	ring     [ringSize]string
+	ringHead int
+	ringLen  int
+}
+
+// Thread safe debug logging
+type Logger struct {
+	*core
+	attrs []any
 }
 
 // Creates a new logger
 func New(path string) (*Logger, error) {
 	if path == "" {
-		return &Logger{enabled: false}, nil
+		return Noop(), nil
 	}
 
 	file, err := os.Create(path)
@@ -25,39 +165,245 @@ func New(path string) (*Logger, error) {
 		return nil, err
 	}
 
-	return &Logger{
-		file:    file,
-		enabled: true,
-	}, nil
+	c := &core{
+		file:      file,
+		writer:    bufio.NewWriter(file),
+		enabled:   true,
+		level:     LevelInfo,
+		path:      path,
+		maxSize:   DefaultMaxSize,
+		backups:   DefaultBackups,
+		stopFlush: make(chan struct{}),
+		flushDone: make(chan struct{}),
+	}
+	c.handler = newHandler(FormatText, sink{c})
+	go c.flushLoop(c.stopFlush, c.flushDone)
+	return &Logger{core: c}, nil
 }
 
 // returns a no-op logger
 func Noop() *Logger {
-	return &Logger{enabled: false}
+	return &Logger{core: &core{enabled: false}}
+}
+
+func newHandler(format Format, w sink) slog.Handler {
+	// Level filtering happens in Logger; the ring keeps every level
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	if format == FormatJSON {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
+
+// Sets the file encoding. Applies to derived loggers as well.
+func (l *Logger) SetFormat(format Format) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file != nil {
+		l.handler = newHandler(format, sink{l.core})
+	}
+}
+
+// Sets the size at which the log is rotated and how many old files to keep.
+// A maxSize of zero disables rotation.
+func (l *Logger) SetRotation(maxSize int64, backups int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.maxSize = maxSize
+	l.backups = max(backups, 0)
+}
+
+// Sets the minimum level that gets written
+func (l *Logger) SetLevel(level Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = level
 }
 
-// Writes formatted message with timestamp
+// Returns a logger that adds the given key/value pairs to every entry
+func (l *Logger) With(args ...any) *Logger {
+	attrs := make([]any, 0, len(l.attrs)+len(args))
+	attrs = append(attrs, l.attrs...)
+	attrs = append(attrs, args...)
+	return &Logger{core: l.core, attrs: attrs}
+}
+
+// Writes formatted message with timestamp at Info level
 func (l *Logger) Log(format string, args ...any) {
-	if !l.enabled || l.file == nil {
+	l.Logf(LevelInfo, format, args...)
+}
+
+// Writes formatted message with timestamp if level passes the filter
+func (l *Logger) Logf(level Level, format string, args ...any) {
+	l.write(entry{time: time.Now(), level: level, printf: true, format: format, args: args, attrs: l.attrs})
+}
+
+func (l *Logger) Debug(msg string, args ...any) { l.logAttrs(LevelDebug, msg, args) }
+func (l *Logger) Info(msg string, args ...any)  { l.logAttrs(LevelInfo, msg, args) }
+func (l *Logger) Warn(msg string, args ...any)  { l.logAttrs(LevelWarn, msg, args) }
+func (l *Logger) Error(msg string, args ...any) { l.logAttrs(LevelError, msg, args) }
+
+func (l *Logger) logAttrs(level Level, msg string, args []any) {
+	l.write(entry{time: time.Now(), level: level, format: msg, args: args, attrs: l.attrs})
+}
+
+func (l *Logger) write(e entry) {
+	line := e.String()
+
+	l.mu.Lock()
+	l.ring[l.ringHead] = line
+	l.ringHead = (l.ringHead + 1) % ringSize
+	if l.ringLen < ringSize {
+		l.ringLen++
+	}
+
+	handler := l.handler
+	enabled := l.enabled && l.file != nil && e.level >= l.level
+	l.mu.Unlock()
+
+	if !enabled || handler == nil {
 		return
 	}
+
+	// The handler writes through sink, which takes mu again
+	r := slog.NewRecord(e.time, e.level.slogLevel(), e.message(), 0)
+	r.Add(e.attrs...)
+	if !e.printf {
+		r.Add(e.args...)
+	}
+	handler.Handle(context.Background(), r)
+}
+
+// Returns up to n of the most recent log lines, oldest first
+func (l *Logger) Tail(n int) []string {
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	return l.tail(n)
+}
+
+// Caller holds mu
+func (l *Logger) tail(n int) []string {
+	if n <= 0 || n > l.ringLen {
+		n = l.ringLen
+	}
+	lines := make([]string, n)
+	start := l.ringHead - n
+	for i := range n {
+		lines[i] = l.ring[(start+i+ringSize)%ringSize]
+	}
+	return lines
+}
+
+// Routes handler output into the rotating, buffered log file
+type sink struct {
+	c *core
+}
+
+func (s sink) Write(p []byte) (int, error) {
+	c := s.c
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.enabled || c.file == nil {
+		return len(p), nil
+	}
 
-	timestamp := time.Now().Format("15:04:05.000")
-	msg := fmt.Sprintf(format, args...)
-	fmt.Fprintf(l.file, "[%s] %s\n", timestamp, msg)
-	l.file.Sync()
+	n, err := c.writer.Write(p)
+	c.size += int64(n)
+
+	if c.maxSize > 0 && c.size >= c.maxSize {
+		c.rotate()
+	}
+	return n, err
+}
+
+// Shifts path -> path.1 -> ... -> path.N and reopens path. Caller holds mu.
+func (c *core) rotate() {
+	c.writer.Flush()
+	c.file.Close()
+
+	if c.backups > 0 {
+		for i := c.backups - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", c.path, i), fmt.Sprintf("%s.%d", c.path, i+1))
+		}
+		os.Rename(c.path, c.path+".1")
+	}
+
+	file, err := os.Create(c.path)
+	if err != nil {
+		c.file = nil
+		c.enabled = false
+		return
+	}
+	c.file = file
+	c.writer.Reset(file)
+	c.size = 0
+}
+
+// Periodically flushes buffered lines so tail -f stays current, until stop
+// is closed. Takes the channels rather than reading them from c, where
+// Close clears them.
+func (c *core) flushLoop(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.Flush()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Writes buffered lines to the log file
+func (c *core) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.writer != nil && c.file != nil {
+		c.writer.Flush()
+	}
+}
+
+func (l *Logger) Debugf(format string, args ...any) { l.Logf(LevelDebug, format, args...) }
+func (l *Logger) Infof(format string, args ...any)  { l.Logf(LevelInfo, format, args...) }
+func (l *Logger) Warnf(format string, args ...any)  { l.Logf(LevelWarn, format, args...) }
+func (l *Logger) Errorf(format string, args ...any) { l.Logf(LevelError, format, args...) }
+
+// Returns a printf-style function that logs at the given level
+func (l *Logger) Func(level Level) func(format string, args ...any) {
+	return func(format string, args ...any) {
+		l.Logf(level, format, args...)
+	}
 }
 
 // Closes the log file
 func (l *Logger) Close() {
+	// The flush loop takes mu itself, so it is stopped outside the lock
+	l.mu.Lock()
+	stop, done := l.stopFlush, l.flushDone
+	l.stopFlush = nil
+	l.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	if l.file != nil {
+		l.writer.Flush()
 		l.file.Close()
+		l.file = nil
 	}
+	l.enabled = false
 }
 
 // Returns whether logging is enabled
 func (l *Logger) IsEnabled() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	return l.enabled
 }
@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRingFormatsAtLogTime(t *testing.T) {
+	l := Noop()
+	vals := []int{1, 2}
+	l.Infof("vals %v", vals)
+	l.Info("attrs", "vals", vals)
+	vals[0] = 99
+
+	lines := l.Tail(0)
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	for _, line := range lines {
+		// Not just "99", which the timestamp can hold
+		if strings.Contains(line, "[99 2]") {
+			t.Errorf("ring shows a later change: %q", line)
+		}
+	}
+}
+
+func TestRingKeepsNewest(t *testing.T) {
+	l := Noop()
+	for i := range ringSize + 10 {
+		l.Infof("line %d", i)
+	}
+	lines := l.Tail(3)
+	for i, line := range lines {
+		want := fmt.Sprintf("line %d", ringSize+7+i)
+		if !strings.HasSuffix(line, want) {
+			t.Errorf("line %d = %q, want suffix %q", i, line, want)
+		}
+	}
+}
+
+// Run with -race: writers, readers, a crash dump and Close overlap
+func TestConcurrentUse(t *testing.T) {
+	dir := t.TempDir()
+	l, err := New(filepath.Join(dir, "test.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data := map[string]int{"g": g}
+			for i := range 200 {
+				data["i"] = i
+				l.With("g", g).Info("tick", "data", data)
+				l.Debugf("tick %v", data)
+			}
+		}()
+	}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for range 50 {
+			l.Tail(20)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if _, err := l.DumpCrash(dir, "test", []byte("stack")); err != nil {
+			t.Error(err)
+		}
+	}()
+	wg.Wait()
+
+	var closers sync.WaitGroup
+	for range 2 {
+		closers.Add(1)
+		go func() {
+			defer closers.Done()
+			l.Close()
+		}()
+	}
+	closers.Wait()
+}
+
+func TestDumpCrash(t *testing.T) {
+	dir := t.TempDir()
+	l := Noop()
+	l.Warn("before the crash", "code", 7)
+
+	path, err := l.DumpCrash(dir, "boom", []byte("goroutine 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"reason: boom", "goroutine 1", "last 1 log entries", "before the crash code=7"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("crash file lacks %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestDumpCrashLockedLogger(t *testing.T) {
+	l := Noop()
+	l.Info("entry")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	path, err := l.DumpCrash(t.TempDir(), "boom", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "log entries unavailable") {
+		t.Errorf("crash file should say the ring was skipped:\n%s", data)
+	}
+}
@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// One displayed frame
+type Row struct {
+	Wall          time.Time
+	Timestamp     time.Duration
+	DecodeToStore time.Duration
+	StoreToRender time.Duration
+	Show          time.Duration
+	DroppedDelta  uint64
+	FPS           float64
+}
+
+var header = "wall,timestamp_ms,decode_to_store_ms,store_to_render_ms,show_ms,dropped_delta,fps"
+
+// Buffers rows and writes them to a CSV file from a background goroutine
+type Recorder struct {
+	file *os.File
+	rows chan Row
+	done chan struct{}
+
+	mu      sync.Mutex
+	samples [5][]float64
+	lost    uint64
+
+	recent []time.Time
+}
+
+// Creates a recorder writing to path
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Recorder{
+		file: file,
+		rows: make(chan Row, 256),
+		done: make(chan struct{}),
+	}
+	go r.writeLoop()
+	return r, nil
+}
+
+// Queues a row without blocking; rows are counted as lost if the writer lags.
+// Fills in FPS from the trailing second of records. Not safe for concurrent use.
+func (r *Recorder) Record(row Row) {
+	if r == nil {
+		return
+	}
+
+	cutoff := row.Wall.Add(-time.Second)
+	keep := 0
+	for keep < len(r.recent) && r.recent[keep].Before(cutoff) {
+		keep++
+	}
+	r.recent = append(r.recent[keep:], row.Wall)
+	row.FPS = float64(len(r.recent))
+
+	select {
+	case r.rows <- row:
+	default:
+		r.mu.Lock()
+		r.lost++
+		r.mu.Unlock()
+	}
+}
+
+func (r *Recorder) writeLoop() {
+	defer close(r.done)
+
+	w := bufio.NewWriter(r.file)
+	fmt.Fprintln(w, header)
+
+	for row := range r.rows {
+		values := [5]float64{
+			ms(row.Timestamp),
+			ms(row.DecodeToStore),
+			ms(row.StoreToRender),
+			ms(row.Show),
+			row.FPS,
+		}
+		fmt.Fprintf(w, "%s,%.3f,%.3f,%.3f,%.3f,%d,%.1f\n",
+			row.Wall.Format(time.RFC3339Nano),
+			values[0], values[1], values[2], values[3],
+			row.DroppedDelta, values[4])
+
+		r.mu.Lock()
+		for i, v := range values {
+			r.samples[i] = append(r.samples[i], v)
+		}
+		r.mu.Unlock()
+	}
+
+	w.Flush()
+}
+
+// Flushes pending rows, closes the file and writes a p50/p95 summary to out
+func (r *Recorder) Close(out io.Writer) {
+	if r == nil {
+		return
+	}
+	close(r.rows)
+	<-r.done
+	r.file.Close()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := [5]string{"timestamp_ms", "decode_to_store_ms", "store_to_render_ms", "show_ms", "fps"}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Metrics: %d frames written to %s", len(r.samples[0]), r.file.Name())
+	if r.lost > 0 {
+		fmt.Fprintf(&sb, " (%d rows lost)", r.lost)
+	}
+	sb.WriteByte('\n')
+	for i := 1; i < len(names); i++ {
+		p50, p95 := percentiles(r.samples[i])
+		fmt.Fprintf(&sb, "  %-20s p50=%8.2f  p95=%8.2f\n", names[i], p50, p95)
+	}
+	fmt.Fprint(out, sb.String())
+}
+
+func percentiles(values []float64) (p50, p95 float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	at := func(q float64) float64 {
+		return sorted[int(q*float64(len(sorted)-1))]
+	}
+	return at(0.50), at(0.95)
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
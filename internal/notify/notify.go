@@ -0,0 +1,38 @@
+// Package notify shows desktop notifications through the platform's
+// notification service, best effort.
+package notify
+
+import (
+	"errors"
+	"os/exec"
+)
+
+var ErrUnsupported = errors.New("no desktop notification service available")
+
+// Shows a notification with title and body. Returns as soon as the helper
+// process has started and never waits for it, so it can't hold up exit.
+// Does nothing when no notification service is available; the error is
+// only for logging.
+func Send(title, body string) error {
+	cmd := command(title, body)
+	if cmd == nil {
+		return ErrUnsupported
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// Reap in the background; the helper outlives us if we exit first
+	go cmd.Wait()
+	return nil
+}
+
+// Returns the first available helper from candidates, or nil
+func firstAvailable(candidates ...*exec.Cmd) *exec.Cmd {
+	for _, cmd := range candidates {
+		// exec.Command records a failed PATH lookup in Err
+		if cmd.Err == nil {
+			return cmd
+		}
+	}
+	return nil
+}
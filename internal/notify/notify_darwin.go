@@ -0,0 +1,12 @@
+package notify
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// Uses AppleScript's display notification
+func command(title, body string) *exec.Cmd {
+	script := "display notification " + strconv.Quote(body) + " with title " + strconv.Quote(title)
+	return firstAvailable(exec.Command("osascript", "-e", script))
+}
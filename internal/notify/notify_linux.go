@@ -0,0 +1,22 @@
+package notify
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Calls org.freedesktop.Notifications.Notify on the session bus with
+// gdbus, falling back to notify-send which does the same
+func command(title, body string) *exec.Cmd {
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" && os.Getenv("XDG_RUNTIME_DIR") == "" {
+		return nil
+	}
+	return firstAvailable(
+		exec.Command("gdbus", "call", "--session",
+			"--dest", "org.freedesktop.Notifications",
+			"--object-path", "/org/freedesktop/Notifications",
+			"--method", "org.freedesktop.Notifications.Notify",
+			"pixlgo", "0", "video-x-generic", title, body, "[]", "{}", "5000"),
+		exec.Command("notify-send", "--app-name=pixlgo", title, body),
+	)
+}
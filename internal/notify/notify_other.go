@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package notify
+
+import "os/exec"
+
+func command(title, body string) *exec.Cmd {
+	return nil
+}
@@ -0,0 +1,88 @@
+package player
+
+import (
+	"fmt"
+	"time"
+)
+
+// Within this much of a chapter's start, jumping back goes to the chapter
+// before it rather than the start of this one
+const chapterRestart = 3 * time.Second
+
+// Returns the index of the chapter playing at pos, or -1 before the first
+// one and for files without chapters
+func (p *Player) chapterAt(pos time.Duration) int {
+	current := -1
+	for i, c := range p.meta.Chapters {
+		if c.Start > pos {
+			break
+		}
+		current = i
+	}
+	return current
+}
+
+// Returns the title of the chapter playing. Caller holds p.mu.
+func (p *Player) currentChapter() string {
+	i := p.chapterAt(p.state.CurrentTime)
+	if i < 0 {
+		return ""
+	}
+	return p.chapterTitle(i)
+}
+
+// Seeks to the next chapter (dir 1) or back to the start of this one or
+// the one before (dir -1)
+func (p *Player) jumpChapter(dir int) {
+	chapters := p.meta.Chapters
+	if len(chapters) == 0 {
+		p.ShowOSD("No chapters")
+		return
+	}
+	p.mu.RLock()
+	ct := p.state.CurrentTime
+	p.mu.RUnlock()
+
+	// A small margin so landing a frame early doesn't pick the same chapter
+	current := p.chapterAt(ct + 100*time.Millisecond)
+	target := current + 1
+	if dir < 0 {
+		target = current
+		if current >= 0 && ct-chapters[current].Start < chapterRestart {
+			target = current - 1
+		}
+	}
+	switch {
+	case target >= len(chapters):
+		p.ShowOSD("Last chapter")
+		return
+	case target < 0:
+		p.Seek(p.segStart - ct)
+		p.ShowOSD("Start")
+		return
+	}
+
+	c := chapters[target]
+	p.Seek(c.Start - ct)
+	p.ShowOSD(fmt.Sprintf("Chapter %d/%d: %s", target+1, len(chapters), p.chapterTitle(target)))
+}
+
+// Returns a chapter's title, or "Chapter N" for untitled ones
+func (p *Player) chapterTitle(i int) string {
+	if title := p.meta.Chapters[i].Title; title != "" {
+		return title
+	}
+	return fmt.Sprintf("Chapter %d", i+1)
+}
+
+// Returns chapter starts as fractions of the span the progress bar covers
+func (p *Player) chapterTicks(start, end time.Duration) []float64 {
+	if end <= start {
+		return nil
+	}
+	var ticks []float64
+	for _, c := range p.meta.Chapters {
+		ticks = append(ticks, float64(c.Start-start)/float64(end-start))
+	}
+	return ticks
+}
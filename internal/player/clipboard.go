@@ -0,0 +1,31 @@
+package player
+
+import (
+	"path/filepath"
+
+	"github.com/0bVdnt/PixlGo/internal/timecode"
+)
+
+// Copies the current position, optionally as "file @ position", to the
+// system clipboard and confirms it in the status bar
+func (p *Player) copyPosition(withFile bool) {
+	if p.noClipboard {
+		p.ShowOSD("Clipboard disabled")
+		return
+	}
+
+	p.mu.RLock()
+	text := timecode.Format(p.state.CurrentTime)
+	p.mu.RUnlock()
+	if withFile {
+		text = filepath.Base(p.decoder.Path()) + " @ " + text
+	}
+
+	if err := p.render.SendOSC52([]byte(text)); err != nil {
+		p.logger.Warnf("Clipboard copy failed: %v", err)
+		p.ShowOSD("Copy failed: " + err.Error())
+		return
+	}
+	p.logger.Debugf("Copied to clipboard: %s", text)
+	p.ShowOSD("Copied " + text)
+}
@@ -0,0 +1,135 @@
+package player
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/chapters"
+	"github.com/0bVdnt/PixlGo/internal/timecode"
+)
+
+var (
+	ErrUnknownCommand = errors.New("unknown command")
+	ErrStopped        = errors.New("player stopped")
+)
+
+// Text commands accepted by Exec, for the usage reply
+const commandHelp = "pause, resume, toggle, seek [+|-]POS, seek-to POS, restart, next, marker [TITLE], export-markers, status, quit"
+
+// A command from a control interface, run on the main loop
+type commandRequest struct {
+	line  string
+	reply chan commandResult
+}
+
+type commandResult struct {
+	out  string
+	err  error
+	quit bool
+}
+
+// Runs one plain-text command such as "seek +30" or "seek-to 1:02:03" and
+// returns its reply. Safe to call from any goroutine: the command runs on
+// the main loop between frames, so every control interface behaves the
+// same as the keyboard.
+func (p *Player) Exec(line string) (string, error) {
+	req := commandRequest{line: line, reply: make(chan commandResult, 1)}
+	select {
+	case p.commands <- req:
+	case <-p.ctx.Done():
+		return "", ErrStopped
+	}
+	select {
+	case res := <-req.reply:
+		return res.out, res.err
+	case <-p.ctx.Done():
+		return "", ErrStopped
+	}
+}
+
+// Executes a command on the main loop. quit means Run should return.
+func (p *Player) execCommand(line string) commandResult {
+	name, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	arg = strings.TrimSpace(arg)
+
+	p.mu.RLock()
+	state := p.state.State
+	current := p.state.CurrentTime
+	p.mu.RUnlock()
+
+	switch strings.ToLower(name) {
+	case "pause":
+		if state == StatePlaying {
+			p.TogglePause()
+		}
+	case "resume", "play":
+		if state != StatePlaying && state != StateLoading {
+			p.TogglePause()
+		}
+	case "toggle", "cycle-pause":
+		p.TogglePause()
+	case "seek":
+		delta, err := parseSeekDelta(arg)
+		if err != nil {
+			return commandResult{err: err}
+		}
+		p.Seek(delta)
+	case "seek-to":
+		pos, err := timecode.Parse(arg)
+		if err != nil {
+			return commandResult{err: err}
+		}
+		p.Seek(pos - current)
+	case "restart":
+		p.render.Clear()
+		p.StartPlayback(p.segStart)
+	case "next":
+		// Run returns as if playback ended, so the caller moves on
+		p.finished = true
+		return commandResult{out: "ok", quit: true}
+	case "marker":
+		p.addMarker(chapters.Marker{Pos: current, Title: arg})
+	case "export-markers":
+		files, err := p.exportMarkers()
+		if err != nil {
+			return commandResult{err: err}
+		}
+		return commandResult{out: files}
+	case "status":
+		return commandResult{out: p.statusLine()}
+	case "quit", "stop":
+		return commandResult{out: "ok", quit: true}
+	default:
+		return commandResult{err: fmt.Errorf("%w %q (known: %s)", ErrUnknownCommand, name, commandHelp)}
+	}
+	return commandResult{out: "ok"}
+}
+
+// Parses "+30", "-10", "+1:30" or "2m"; unsigned values seek forward
+func parseSeekDelta(s string) (time.Duration, error) {
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign, s = -1, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	d, err := timecode.Parse(s)
+	if err != nil {
+		return 0, err
+	}
+	return sign * d, nil
+}
+
+// One-line status for the status command
+func (p *Player) statusLine() string {
+	st := p.Status()
+	duration := "unknown"
+	if st.DurationKnown {
+		duration = timecode.Format(st.Duration)
+	}
+	return fmt.Sprintf("state=%s position=%s duration=%s dropped=%d file=%q",
+		st.State, timecode.Format(st.Position), duration, st.Dropped, st.File)
+}
@@ -1,6 +1,13 @@
 package player
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/video"
+)
+
+const osdDuration = 2 * time.Second
 
 func (p *Player) TogglePause() {
 	p.mu.Lock()
@@ -9,7 +16,7 @@ func (p *Player) TogglePause() {
 	p.mu.Unlock()
 
 	switch state {
-	case StatePlaying:
+	case StatePlaying, StateReconnecting:
 		p.decoder.Stop()
 		p.mu.Lock()
 		p.state.State = StatePaused
@@ -21,41 +28,64 @@ func (p *Player) TogglePause() {
 }
 
 func (p *Player) Seek(delta time.Duration) {
+	p.seek(delta, true)
+}
+
+// Seeks by delta. Without allowSkip a playing stream always restarts at
+// the target, which lands exactly on it; skipping ahead in the running
+// stream only gets within a frame of the output rate.
+func (p *Player) seek(delta time.Duration, allowSkip bool) {
+	if p.meta.AttachedPic {
+		p.ShowOSD("Cover art only, nothing to seek")
+		return
+	}
+	if p.meta.Still {
+		p.ShowOSD("Still image, nothing to seek")
+		return
+	}
+	if p.meta.Path == video.StdinPath {
+		p.ShowOSD("Piped input, can't seek")
+		return
+	}
+	if p.meta.Live {
+		p.ShowOSD("Live stream, can't seek")
+		return
+	}
+
 	p.mu.Lock()
-	currentTime := p.state.CurrentTime
-	duration := p.meta.Duration
+	// Consecutive presses add up even before a pending skip lands
+	currentTime := max(p.state.CurrentTime, p.state.SkipTarget)
+	// Unknown length: allow forward seeks without clamping
+	end := p.segmentEnd()
 	state := p.state.State
-	frameW, frameH := p.state.FrameW, p.state.FrameH
+	frameW, frameH := p.state.CurrentFrameSize(p.meta)
 	p.mu.Unlock()
 
-	newTime := currentTime + delta
-
-	if newTime < 0 {
-		newTime = 0
-	}
-	if duration > 0 && newTime >= duration {
-		newTime = duration - time.Second
-		if newTime < 0 {
-			newTime = 0
-		}
+	newTime := max(currentTime+delta, p.segStart)
+	if end > 0 && newTime >= end {
+		newTime = max(end-time.Second, p.segStart)
 	}
 
 	p.mu.Lock()
 	p.state.CurrentTime = newTime
+	p.state.SkipTarget = 0
 	p.mu.Unlock()
 
 	switch state {
 	case StatePaused, StateEnded:
-		go func() {
-			if frame, err := p.decoder.ExtractFrame(newTime, frameW, frameH); err == nil {
-				p.buffer.StoreForce(frame)
-				p.mu.Lock()
-				p.state.LastFrame = frame
-				p.mu.Unlock()
-			}
-		}()
+		// A fresh token per seek so a slow extract can't land after a newer one
+		p.extractFrameAsync(newTime, frameW, frameH, p.buffer.Reset())
 
-	case StatePlaying, StateLoading:
+	case StatePlaying:
+		if allowSkip && delta > 0 && p.decoder.SkipForward(newTime, p.seekKeepAlive) {
+			p.mu.Lock()
+			p.state.SkipTarget = newTime
+			p.mu.Unlock()
+			return
+		}
+		p.StartPlayback(newTime)
+
+	case StateLoading:
 		p.StartPlayback(newTime)
 
 	default:
@@ -63,22 +93,175 @@ func (p *Player) Seek(delta time.Duration) {
 	}
 }
 
+// Shows the frame at pos without starting a stream. The result is dropped if
+// the buffer epoch or position changed meanwhile; cleanup waits for these.
+func (p *Player) extractFrameAsync(pos time.Duration, frameW, frameH int, epoch uint64) {
+	// Paused, the frame shown is worth the slower exact seek
+	p.decoder.SetExactSeek(p.exactSeek)
+	p.extractions.Add(1)
+	go func() {
+		defer p.extractions.Done()
+		defer p.recoverPanic()
+
+		frame, err := p.decoder.ExtractFrame(p.ctx, pos, frameW, frameH)
+		if err != nil || p.ctx.Err() != nil {
+			return
+		}
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.state.CurrentTime != pos || !p.buffer.Store(frame, epoch) {
+			return
+		}
+		p.state.LastFrame = frame
+	}()
+}
+
+// Stops decoding while the terminal is too small; playback restarts from
+// the same position once a resize makes it usable again
+func (p *Player) suspendPlayback() {
+	p.decoder.Stop()
+	p.mu.Lock()
+	p.state.State = StatePaused
+	p.state.ResumeOnGrow = true
+	p.mu.Unlock()
+}
+
 func (p *Player) StartPlayback(pos time.Duration) {
 	p.render.RequestClear()
 
 	p.mu.Lock()
+	if p.state.TooSmall() {
+		p.state.CurrentTime = pos
+		p.state.State = StatePaused
+		p.state.ResumeOnGrow = true
+		p.mu.Unlock()
+		return
+	}
+	// Resuming continues from the exact frame a paused seek showed;
+	// seeks while playing take the fast path
+	resuming := p.state.State == StatePaused || p.state.State == StateEnded
 	p.state.CurrentTime = pos
+	p.state.SkipTarget = 0
 	p.state.State = StateLoading
 	p.state.LoadingStart = time.Now()
-	frameW, frameH := p.state.FrameW, p.state.FrameH
+	p.state.Following = false
+	frameW, frameH := p.state.CurrentFrameSize(p.meta)
+	capped, maxArea := p.state.Capped, p.state.MaxArea
 	p.mu.Unlock()
 
 	p.render.InvalidateCache()
+	if p.meta.Still {
+		p.loadStill()
+		return
+	}
+	if capped && !p.capNoted {
+		// Once per file; resizes that stay over the cap don't repeat it
+		p.capNoted = true
+		p.logger.Info("frame size capped", "width", frameW, "height", frameH, "max_area", maxArea)
+		p.ShowOSD(fmt.Sprintf("Frame capped to %dx%d to save memory (-max-frame-area)", frameW, frameH))
+	}
+
+	if p.started {
+		Stats.Restarts.Add(1)
+	}
+	p.started = true
 
-	targetFPS := calculateTargetFPS(frameW, frameH)
-	if err := p.decoder.StartStream(p.ctx, frameW, frameH, pos, p.buffer, targetFPS); err != nil {
+	loop, loopDefault := p.loopCount()
+	p.decoder.SetLoop(loop)
+	p.decoder.SetExactSeek(p.exactSeek && resuming)
+	p.mu.Lock()
+	p.looping, p.loopDefault = loop != 0, loopDefault
+	p.mu.Unlock()
+
+	targetFPS := calculateTargetFPS(frameW, frameH, p.maxCPU, p.maxFPS)
+	if p.meta.Animated {
+		// Native rate: resampling an animated image lower drops its short frames
+		targetFPS = p.meta.FPS
+		if p.maxFPS > 0 {
+			targetFPS = min(targetFPS, p.maxFPS)
+		}
+	}
+	grantedW, grantedH, err := p.decoder.StartStream(p.ctx, frameW, frameH, pos, p.buffer, targetFPS)
+	if err != nil {
 		p.SetError("Start failed: " + err.Error())
+		return
+	}
+	if grantedW != frameW || grantedH != frameH {
+		// Centering must use what ffmpeg produces, not what was asked for
+		p.logger.Warn("decoder changed frame size", "requested", fmt.Sprintf("%dx%d", frameW, frameH),
+			"granted", fmt.Sprintf("%dx%d", grantedW, grantedH))
+		p.mu.Lock()
+		p.state.FrameW, p.state.FrameH = grantedW, grantedH
+		p.mu.Unlock()
+	}
+}
+
+// Returns the extra passes the next stream makes over the file, -1 for
+// forever, and whether that is only the default for animated images.
+// Live, piped and sliced inputs play once.
+func (p *Player) loopCount() (int, bool) {
+	if p.meta.Live || p.meta.Path == video.StdinPath || p.segStart > 0 || p.segEnd > 0 {
+		return 0, false
+	}
+	if p.loop != 0 {
+		return p.loop, false
+	}
+	if p.loopAnimated && p.meta.Animated && !p.exitOnEnd.Load() {
+		return -1, true
 	}
+	return 0, false
+}
+
+// Switches between decoding every column and decoding half of them, each
+// drawn twice, then restarts or re-extracts the frame at the new size
+func (p *Player) toggleHalfWidth() {
+	p.mu.Lock()
+	p.state.HalfWidth = !p.state.HalfWidth
+	half := p.state.HalfWidth
+	p.state.UpdateDimensions(p.state.ScreenW, p.state.ScreenH, p.meta)
+	state := p.state.State
+	currentTime := p.state.CurrentTime
+	frameW, frameH := p.state.FrameW, p.state.FrameH
+	p.mu.Unlock()
+
+	p.render.SetHalfWidth(half)
+	switch state {
+	case StatePlaying, StateLoading:
+		p.StartPlayback(currentTime)
+	case StatePaused, StateEnded:
+		p.extractFrameAsync(currentTime, frameW, frameH, p.buffer.Reset())
+	}
+	if half {
+		p.ShowOSD("Half width: every column drawn twice")
+	} else {
+		p.ShowOSD("Full width")
+	}
+}
+
+// Switches between the picture and the motion heatmap debug view
+func (p *Player) toggleHeatmap() {
+	if !p.debugViews {
+		return
+	}
+	p.mu.Lock()
+	p.heatmap = !p.heatmap
+	on := p.heatmap
+	p.mu.Unlock()
+	p.render.SetHeatmap(on)
+	if on {
+		p.ShowOSD("Motion heatmap: red changed now, yellow recently, black static")
+	} else {
+		p.ShowOSD("Motion heatmap off")
+	}
+}
+
+// Shows a transient message in the status bar
+func (p *Player) ShowOSD(msg string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state.OSD = msg
+	p.state.OSDUntil = time.Now().Add(osdDuration)
 }
 
 func (p *Player) SetError(msg string) {
@@ -87,9 +270,10 @@ func (p *Player) SetError(msg string) {
 	p.state.State = StateError
 	p.state.ErrorMsg = msg
 	p.mu.Unlock()
+	Stats.Errors.Add(1)
 }
 
-func calculateTargetFPS(width, height int) float64 {
+func calculateTargetFPS(width, height, maxCPU int, maxFPS float64) float64 {
 	targetFPS := 24.0
 	pixels := width * height
 
@@ -101,5 +285,12 @@ func calculateTargetFPS(width, height int) float64 {
 		targetFPS = 20.0
 	}
 
+	if maxCPU > 0 && maxCPU < 100 {
+		targetFPS = max(5.0, targetFPS*float64(maxCPU)/100)
+	}
+	if maxFPS > 0 {
+		targetFPS = min(targetFPS, maxFPS)
+	}
+
 	return targetFPS
 }
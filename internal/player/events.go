@@ -9,6 +9,8 @@ import (
 const (
 	SeekSmall = 5 * time.Second
 	SeekLarge = 30 * time.Second
+
+	DefaultSeekKeepAlive = 10 * time.Second
 )
 
 type EventResult int
@@ -39,9 +41,20 @@ func (p *Player) handleResize(ev *tcell.EventResize) EventResult {
 	dimensionsChanged := p.state.UpdateDimensions(w, h, p.meta)
 	state := p.state.State
 	currentTime := p.state.CurrentTime
+	tooSmall := p.state.TooSmall()
+	resume := p.state.ResumeOnGrow
 	p.mu.Unlock()
 
-	if dimensionsChanged && (state == StatePlaying || state == StateLoading) {
+	active := state == StatePlaying || state == StateLoading
+	switch {
+	case tooSmall && active:
+		p.suspendPlayback()
+	case !tooSmall && resume:
+		p.mu.Lock()
+		p.state.ResumeOnGrow = false
+		p.mu.Unlock()
+		p.StartPlayback(currentTime)
+	case !tooSmall && dimensionsChanged && active:
 		p.StartPlayback(currentTime)
 	}
 
@@ -49,6 +62,23 @@ func (p *Player) handleResize(ev *tcell.EventResize) EventResult {
 }
 
 func (p *Player) handleKey(ev *tcell.EventKey) EventResult {
+	if p.prompt != nil {
+		return p.handlePromptKey(ev)
+	}
+	if isLogViewToggle(ev) {
+		p.toggleLogView()
+		return EventContinue
+	}
+	if p.logView {
+		return p.handleLogViewKey(ev)
+	}
+	if p.markerView {
+		return p.handleMarkerViewKey(ev)
+	}
+	if p.playlistView {
+		return p.handlePlaylistViewKey(ev)
+	}
+
 	if ev.Key() == tcell.KeyEscape || ev.Key() == tcell.KeyCtrlC {
 		return EventQuit
 	}
@@ -75,6 +105,12 @@ func (p *Player) handleKey(ev *tcell.EventKey) EventResult {
 		p.Seek(-SeekLarge)
 	case tcell.KeyUp:
 		p.Seek(SeekLarge)
+	case tcell.KeyPgUp:
+		p.jumpChapter(-1)
+	case tcell.KeyPgDn:
+		p.jumpChapter(1)
+	case tcell.KeyF3:
+		p.togglePlaylistView()
 	case tcell.KeyHome:
 		p.mu.RLock()
 		ct := p.state.CurrentTime
@@ -83,10 +119,12 @@ func (p *Player) handleKey(ev *tcell.EventKey) EventResult {
 	case tcell.KeyEnd:
 		p.mu.RLock()
 		ct := p.state.CurrentTime
-		dur := p.meta.Duration
+		end := p.segmentEnd()
 		p.mu.RUnlock()
-		if dur > time.Second {
-			p.Seek(dur - ct - time.Second)
+		if end == 0 {
+			p.ShowOSD("End unavailable: duration unknown")
+		} else if end > time.Second {
+			p.Seek(end - ct - time.Second)
 		}
 	}
 	return EventContinue
@@ -98,7 +136,40 @@ func (p *Player) handleRune(r rune) EventResult {
 		p.TogglePause()
 	case 'r', 'R':
 		p.render.Clear()
-		p.StartPlayback(0)
+		p.StartPlayback(p.segStart)
+	case 'y':
+		p.copyPosition(false)
+	case 'Y':
+		p.copyPosition(true)
+	case 'l', 'L':
+		p.ToggleAutoLevels()
+	case 'M':
+		p.promptMarker()
+	case 'm':
+		p.toggleMarkerView()
+	case 'i', 'I':
+		p.toggleStatsView()
+	case 'P':
+		p.togglePlaylistView()
+	case 'H':
+		p.toggleHeatmap()
+	case 'g', 'G':
+		p.promptGoto()
+	case 'w', 'W':
+		p.toggleHalfWidth()
+	case '+', '=':
+		p.changeVolume(volumeStep)
+	case '-':
+		p.changeVolume(-volumeStep)
+	case 'c', 'C':
+		p.toggleSubtitles()
+	case '[':
+		p.jumpChapter(-1)
+	case ']':
+		p.jumpChapter(1)
+	case 'u', 'U':
+		// m is taken by the marker list
+		p.toggleMute()
 	}
 	return EventContinue
 }
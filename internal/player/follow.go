@@ -0,0 +1,80 @@
+package player
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/video"
+)
+
+const (
+	// How often a followed file is checked for new data
+	followPoll = time.Second
+
+	// How long a followed file may stop growing before playback ends
+	DefaultFollowTimeout = 30 * time.Second
+)
+
+// Switches to waiting for the file to grow instead of ending. Caller holds
+// p.mu.
+func (p *Player) beginFollow() {
+	p.state.State = StatePlaying
+	p.state.Following = true
+	p.followSince = time.Now()
+	p.followPolled = time.Time{}
+	p.fileGrew()
+}
+
+// Polls a followed file while waiting at its end: resumes decoding from the
+// last shown position once it grew, or ends playback after followTimeout
+// without growth. Called from the main loop after Update.
+func (p *Player) checkFollow() {
+	p.mu.RLock()
+	waiting := p.state.Following && p.state.State == StatePlaying
+	p.mu.RUnlock()
+	if !waiting || time.Since(p.followPolled) < followPoll {
+		return
+	}
+	p.followPolled = time.Now()
+
+	if p.fileGrew() {
+		ctx, cancel := context.WithTimeout(p.ctx, 5*time.Second)
+		duration := video.ProbeDuration(ctx, p.decoder.Path())
+		cancel()
+
+		p.mu.Lock()
+		if duration > p.meta.Duration {
+			p.meta.Duration = duration
+			p.durationKnown = true
+		}
+		pos := p.state.CurrentTime
+		p.mu.Unlock()
+
+		p.logger.Debugf("Followed file grew, duration now %v, continuing at %v", duration, pos)
+		p.StartPlayback(pos)
+		return
+	}
+
+	if time.Since(p.followSince) > p.followTimeout {
+		p.logger.Infof("File stopped growing for %v, ending", p.followTimeout)
+		p.mu.Lock()
+		p.state.Following = false
+		p.state.State = StateEnded
+		p.mu.Unlock()
+		return
+	}
+	p.ShowOSD("Waiting for data…")
+}
+
+// Reports whether the file's size or modification time changed since the
+// last call; beginFollow's call sets the baseline
+func (p *Player) fileGrew() bool {
+	info, err := os.Stat(p.decoder.Path())
+	if err != nil {
+		return false
+	}
+	grew := info.Size() != p.followSize || !info.ModTime().Equal(p.followMtime)
+	p.followSize, p.followMtime = info.Size(), info.ModTime()
+	return grew
+}
@@ -0,0 +1,101 @@
+package player
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/timecode"
+)
+
+// Returns the number of frames in the video: nb_frames when the container
+// has it, else estimated from duration and FPS, or 0 if unknown
+func (p *Player) frameCount() int {
+	if p.meta.Frames > 0 {
+		return p.meta.Frames
+	}
+	p.mu.RLock()
+	known := p.durationKnown
+	p.mu.RUnlock()
+	if !known || p.meta.FPS <= 0 {
+		return 0
+	}
+	return int(p.meta.Duration.Seconds() * p.meta.FPS)
+}
+
+// Returns the position of frame n, counted from 0 like ffmpeg does.
+// Truncated to the millisecond -ss works in, so the seek starts at or
+// just before the frame's timestamp and the accurate seek keeps it; the
+// rounding first undoes float error on timestamps that are whole ms.
+func frameTime(n int, fps float64) time.Duration {
+	d := time.Duration(float64(n) / fps * float64(time.Second))
+	return d.Round(time.Microsecond).Truncate(time.Millisecond)
+}
+
+// Returns the number of the frame shown at pos, tolerating the truncation
+// in frameTime
+func frameAt(pos time.Duration, fps float64) int {
+	return int((pos + time.Millisecond).Seconds() * fps)
+}
+
+// Clamps frame n to the video and returns its position
+func (p *Player) framePos(n int) (pos time.Duration, frame int, clamped bool) {
+	frame = max(n, 0)
+	if count := p.frameCount(); count > 0 && frame >= count {
+		frame, clamped = count-1, true
+	}
+	return frameTime(frame, p.meta.FPS), frame, clamped
+}
+
+// Seeks exactly to frame n and confirms the frame it landed on
+func (p *Player) SeekFrame(n int) {
+	if p.meta.AttachedPic || p.meta.FPS <= 0 {
+		p.ShowOSD("No frames to seek to")
+		return
+	}
+	pos, frame, clamped := p.framePos(n)
+
+	p.mu.RLock()
+	current := p.state.CurrentTime
+	p.mu.RUnlock()
+	p.seek(pos-current, false)
+
+	p.mu.RLock()
+	landed := p.state.CurrentTime
+	p.mu.RUnlock()
+	msg := fmt.Sprintf("Frame %d at %s", frameAt(landed, p.meta.FPS), timecode.Format(landed))
+	if clamped {
+		msg = fmt.Sprintf("Frame %d is past the end (%d frames); %s", n, p.frameCount(), msg)
+	}
+	p.ShowOSD(msg)
+	p.logger.Debug("seek to frame", "requested", n, "frame", frame, "pos", pos)
+}
+
+// Opens the go-to prompt, which takes a position or ":frame N"
+func (p *Player) promptGoto() {
+	p.openPrompt("Go to (time or :frame N): ", func(text string) {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return
+		}
+		if arg, ok := strings.CutPrefix(strings.TrimPrefix(text, ":"), "frame"); ok {
+			n, err := strconv.Atoi(strings.TrimSpace(arg))
+			if err != nil || n < 0 {
+				p.ShowOSD(fmt.Sprintf("Invalid frame number %q", strings.TrimSpace(arg)))
+				return
+			}
+			p.SeekFrame(n)
+			return
+		}
+		pos, err := timecode.Parse(text)
+		if err != nil {
+			p.ShowOSD(err.Error())
+			return
+		}
+		p.mu.RLock()
+		current := p.state.CurrentTime
+		p.mu.RUnlock()
+		p.Seek(pos - current)
+	})
+}
@@ -0,0 +1,105 @@
+package player
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/fakeff"
+	"github.com/0bVdnt/PixlGo/internal/video"
+	"github.com/gdamore/tcell/v2"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestMain(m *testing.M) {
+	fakeff.Main()
+	os.Exit(m.Run())
+}
+
+// Returns a player on an 80x25 simulation screen for a file the fake
+// ffprobe describes with testdata/<fixture>, decoded by the fake ffmpeg
+// with the given fakeff settings
+func newTestPlayer(t *testing.T, fixture string, env map[string]string, cfg Config) (*Player, tcell.SimulationScreen) {
+	t.Helper()
+	cfg = testConfig(t, fixture, env, cfg)
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		// Unless the test already did
+		select {
+		case <-p.doneChan:
+		default:
+			p.cleanup()
+		}
+	})
+	return p, cfg.Screen.(tcell.SimulationScreen)
+}
+
+// Completes cfg for newTestPlayer, without creating the player
+func testConfig(t *testing.T, fixture string, env map[string]string, cfg Config) Config {
+	t.Helper()
+	probe, err := filepath.Abs(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := map[string]string{"PROBE": probe}
+	for key, value := range env {
+		settings[key] = value
+	}
+	exe := fakeff.Setenv(t, settings)
+	video.SetTools(video.Tools{FFmpeg: exe, FFprobe: exe})
+	t.Cleanup(func() { video.SetTools(video.Tools{}) })
+
+	cfg.VideoPath = filepath.Join(t.TempDir(), "clip.mp4")
+	if err := os.WriteFile(cfg.VideoPath, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Screen = tcell.NewSimulationScreen("UTF-8")
+	cfg.NoAudio = true
+	return cfg
+}
+
+// Returns the player's state
+func (p *Player) testState() State {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.state.State
+}
+
+// Runs Update as the main loop would until the state is want
+func waitState(t *testing.T, p *Player, want State) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for p.testState() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("state %s, want %s", p.testState(), want)
+		}
+		p.Update()
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Compares got with testdata/<name>.golden, or rewrites that file when the
+// tests run with -update
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from %s:\n%s\nwant:\n%s", name, path, got, want)
+	}
+}
@@ -0,0 +1,64 @@
+package player
+
+import (
+	"image"
+
+	"github.com/0bVdnt/PixlGo/internal/renderer"
+)
+
+const (
+	// Share of pixels at each end allowed to clip when stretching
+	levelsClip = 0.01
+
+	// Smallest range stretched to full scale, capping the gain at about 4x
+	// so near-black frames don't turn into amplified noise
+	levelsMinSpan = 64
+
+	// Weight of each new frame's bounds; lower values flicker less but
+	// follow scene cuts more slowly
+	levelsSmoothing = 0.15
+)
+
+// Toggles the auto-levels stretch, effective from the next frame
+func (p *Player) ToggleAutoLevels() {
+	p.autoLevels = !p.autoLevels
+	p.levelsLo, p.levelsHi = -1, -1
+	p.render.InvalidateCache()
+	if p.autoLevels {
+		p.ShowOSD("Auto levels: on")
+	} else {
+		p.ShowOSD("Auto levels: off")
+	}
+	p.settingsChanged()
+}
+
+// Returns img with its luma range stretched to the full scale, or img itself
+// when auto-levels is off. The bounds follow the content smoothly over a few
+// frames. Only called from the main loop.
+func (p *Player) applyLevels(img *image.RGBA) *image.RGBA {
+	if !p.autoLevels || img == nil {
+		return img
+	}
+
+	lo, hi := renderer.LumaRange(img, levelsClip)
+	if p.levelsLo < 0 {
+		p.levelsLo, p.levelsHi = float64(lo), float64(hi)
+	} else {
+		p.levelsLo += (float64(lo) - p.levelsLo) * levelsSmoothing
+		p.levelsHi += (float64(hi) - p.levelsHi) * levelsSmoothing
+	}
+
+	// Widen short ranges around their middle, keeping them inside 0-255
+	low, high := p.levelsLo, p.levelsHi
+	if high-low < levelsMinSpan {
+		mid := (low + high) / 2
+		low = min(max(mid-levelsMinSpan/2, 0), 255-levelsMinSpan)
+		high = low + levelsMinSpan
+	}
+
+	if p.levelsImg == nil || p.levelsImg.Bounds() != img.Bounds() {
+		p.levelsImg = image.NewRGBA(img.Bounds())
+	}
+	renderer.LevelsLUT(low, high, 1).Apply(p.levelsImg, img)
+	return p.levelsImg
+}
@@ -0,0 +1,71 @@
+package player
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/video"
+)
+
+const (
+	// How long a live stream may deliver no frames before reconnecting
+	liveStallTimeout = 10 * time.Second
+
+	// Wait before the first reconnect attempt, doubling per failed attempt
+	// up to maxReconnectDelay
+	reconnectDelay    = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// Schedules a reconnect to a live stream that ended or stalled, backing off
+// after failed attempts. Caller holds p.mu.
+func (p *Player) beginReconnect(reason string) {
+	if p.reconnectDelay == 0 {
+		p.reconnectDelay = reconnectDelay
+	} else {
+		p.reconnectDelay = min(2*p.reconnectDelay, maxReconnectDelay)
+	}
+	p.state.State = StateReconnecting
+	p.reconnecting = true
+	p.reconnectAt = time.Now().Add(p.reconnectDelay)
+	p.logger.Warn("Live stream lost", "reason", reason, "retry_in", p.reconnectDelay)
+}
+
+// Watches a live network stream: reconnects once the scheduled time comes, and
+// treats a stream that stopped delivering frames, such as a camera that
+// dropped off the network, as lost. Called from the main loop after Update.
+func (p *Player) checkReconnect() {
+	if !p.meta.Live || !video.IsURL(p.meta.Path) {
+		return
+	}
+	p.mu.RLock()
+	state, pos := p.state.State, p.state.CurrentTime
+	reconnecting, at := p.reconnecting, p.reconnectAt
+	stalled := time.Since(p.liveFrameAt)
+	p.mu.RUnlock()
+
+	switch {
+	case state == StatePlaying && reconnecting:
+		p.mu.Lock()
+		p.reconnecting = false
+		p.reconnectDelay = 0
+		p.mu.Unlock()
+		p.logger.Info("Live stream reconnected")
+		p.ShowOSD("Reconnected")
+
+	case state == StatePlaying && stalled > liveStallTimeout:
+		p.decoder.Stop()
+		p.mu.Lock()
+		p.beginReconnect(fmt.Sprintf("no frames for %v", stalled.Round(time.Second)))
+		p.mu.Unlock()
+
+	case state == StateReconnecting && !time.Now().Before(at):
+		p.logger.Info("Reconnecting to live stream")
+		p.StartPlayback(pos)
+
+	case state == StateReconnecting:
+		secs := int(math.Ceil(time.Until(at).Seconds()))
+		p.ShowOSD(fmt.Sprintf("Connection lost, reconnecting in %ds…", secs))
+	}
+}
@@ -0,0 +1,84 @@
+package player
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+const logViewLines = 500
+
+func isLogViewToggle(ev *tcell.EventKey) bool {
+	return ev.Key() == tcell.KeyF2 || (ev.Key() == tcell.KeyRune && ev.Rune() == '`')
+}
+
+func (p *Player) toggleLogView() {
+	p.logView = !p.logView
+	p.logScroll = 0
+	p.render.RequestClear()
+	p.render.InvalidateCache()
+}
+
+// Handles scrolling while the log overlay is open
+func (p *Player) handleLogViewKey(ev *tcell.EventKey) EventResult {
+	page := max(p.logViewHeight()-2, 1)
+
+	switch ev.Key() {
+	case tcell.KeyEscape:
+		p.toggleLogView()
+	case tcell.KeyUp:
+		p.logScroll++
+	case tcell.KeyDown:
+		p.logScroll--
+	case tcell.KeyPgUp:
+		p.logScroll += page
+	case tcell.KeyPgDn:
+		p.logScroll -= page
+	case tcell.KeyHome:
+		p.logScroll = logViewLines
+	case tcell.KeyEnd:
+		p.logScroll = 0
+	case tcell.KeyRune:
+		if ev.Rune() == 'q' || ev.Rune() == 'Q' {
+			return EventQuit
+		}
+	}
+	if p.logScroll < 0 {
+		p.logScroll = 0
+	}
+	return EventContinue
+}
+
+func (p *Player) logViewHeight() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.state.ScreenH - 4
+}
+
+// Draws the tail of the in-memory log over the video area
+func (p *Player) renderLogView(w, h int) {
+	boxW, boxH := w-4, h-4
+	if boxW < 10 || boxH < 3 {
+		return
+	}
+
+	lines := p.logger.Tail(logViewLines)
+	visible := boxH - 2
+
+	maxScroll := max(len(lines)-visible, 0)
+	if p.logScroll > maxScroll {
+		p.logScroll = maxScroll
+	}
+
+	end := len(lines) - p.logScroll
+	start := max(end-visible, 0)
+
+	// Keep newest lines at the bottom when the log is short
+	view := make([]string, visible-(end-start), visible)
+	view = append(view, lines[start:end]...)
+
+	style := tcell.StyleDefault.Background(tcell.ColorBlack).Foreground(tcell.ColorSilver)
+	title := "Log (F2 to close)"
+	if p.logScroll > 0 {
+		title = "Log (scrolled, End for newest)"
+	}
+	p.render.DrawBox(2, 1, boxW, boxH, title, view, style)
+}
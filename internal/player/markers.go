@@ -0,0 +1,147 @@
+package player
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/0bVdnt/PixlGo/internal/chapters"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Opens the prompt for naming a marker at the current position
+func (p *Player) promptMarker() {
+	p.mu.RLock()
+	pos := p.state.CurrentTime
+	p.mu.RUnlock()
+
+	p.openPrompt(fmt.Sprintf("Marker at %s: ", formatDuration(pos)), func(title string) {
+		p.addMarker(chapters.Marker{Pos: pos, Title: title})
+	})
+}
+
+// Adds a marker, naming it "Marker N" if the title is blank
+func (p *Player) addMarker(m chapters.Marker) {
+	m.Title = strings.TrimSpace(m.Title)
+	if m.Title == "" {
+		m.Title = fmt.Sprintf("Marker %d", len(p.markers)+1)
+	}
+	p.markers = append(p.markers, m)
+	chapters.Sort(p.markers)
+	p.markersChanged()
+	p.ShowOSD(fmt.Sprintf("Marker %q at %s", m.Title, formatDuration(m.Pos)))
+}
+
+// Reports a marker change to Config.OnMarkersChange
+func (p *Player) markersChanged() {
+	if p.onMarkersChange != nil {
+		p.onMarkersChange(append([]chapters.Marker(nil), p.markers...))
+	}
+}
+
+// Returns a copy of the markers. Only valid from the main loop or after Run
+// returned.
+func (p *Player) Markers() []chapters.Marker {
+	return append([]chapters.Marker(nil), p.markers...)
+}
+
+// Writes the markers next to the video as chapters and reports where
+func (p *Player) exportMarkers() (string, error) {
+	if len(p.markers) == 0 {
+		return "", fmt.Errorf("no markers to export")
+	}
+	p.mu.RLock()
+	duration := p.meta.Duration
+	if !p.durationKnown {
+		duration = 0
+	}
+	p.mu.RUnlock()
+
+	paths, err := chapters.Export(p.decoder.Path(), p.markers, duration, p.markerList)
+	if err != nil {
+		p.logger.Warn("marker export failed", "err", err)
+		return "", err
+	}
+	p.logger.Info("markers exported", "files", paths)
+	return strings.Join(paths, ", "), nil
+}
+
+func (p *Player) toggleMarkerView() {
+	p.markerView = !p.markerView
+	p.markerSel = 0
+	p.render.RequestClear()
+	p.render.InvalidateCache()
+}
+
+// Handles keys while the marker list is open
+func (p *Player) handleMarkerViewKey(ev *tcell.EventKey) EventResult {
+	switch ev.Key() {
+	case tcell.KeyEscape:
+		p.toggleMarkerView()
+	case tcell.KeyUp:
+		p.markerSel--
+	case tcell.KeyDown:
+		p.markerSel++
+	case tcell.KeyEnter:
+		if p.markerSel < len(p.markers) {
+			p.mu.RLock()
+			current := p.state.CurrentTime
+			p.mu.RUnlock()
+			p.Seek(p.markers[p.markerSel].Pos - current)
+		}
+	case tcell.KeyDelete, tcell.KeyBackspace, tcell.KeyBackspace2:
+		p.deleteMarker()
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case 'q', 'Q':
+			return EventQuit
+		case 'm':
+			p.toggleMarkerView()
+		case 'd':
+			p.deleteMarker()
+		case 'e', 'E':
+			if files, err := p.exportMarkers(); err != nil {
+				p.ShowOSD("Export failed: " + err.Error())
+			} else {
+				p.ShowOSD("Exported " + files)
+			}
+		}
+	}
+	p.markerSel = clamp(p.markerSel, 0, max(len(p.markers)-1, 0))
+	return EventContinue
+}
+
+func (p *Player) deleteMarker() {
+	if p.markerSel >= len(p.markers) {
+		return
+	}
+	p.markers = append(p.markers[:p.markerSel], p.markers[p.markerSel+1:]...)
+	p.markersChanged()
+	p.render.RequestClear()
+}
+
+// Draws the marker list over the video area
+func (p *Player) renderMarkerView(w, h int) {
+	boxW, boxH := min(w-4, 60), min(h-4, len(p.markers)+3)
+	if boxW < 10 || boxH < 3 {
+		return
+	}
+
+	lines := []string{" Enter: jump  d: delete  e: export"}
+	visible := boxH - 3
+	start := clamp(p.markerSel-visible+1, 0, max(len(p.markers)-visible, 0))
+	for i := start; i < len(p.markers) && i < start+visible; i++ {
+		m := p.markers[i]
+		mark := "  "
+		if i == p.markerSel {
+			mark = "> "
+		}
+		lines = append(lines, fmt.Sprintf("%s%8s  %s", mark, formatDuration(m.Pos), m.Title))
+	}
+	if len(p.markers) == 0 {
+		lines = append(lines, " No markers yet, add one with M")
+		boxH = 4
+	}
+
+	style := tcell.StyleDefault.Background(tcell.ColorBlack).Foreground(tcell.ColorSilver)
+	p.render.DrawBox((w-boxW)/2, 1, boxW, boxH, fmt.Sprintf("Markers (%d)", len(p.markers)), lines, style)
+}
@@ -0,0 +1,136 @@
+package player
+
+import (
+	"fmt"
+	"image"
+	"runtime"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/video"
+	"github.com/gdamore/tcell/v2"
+)
+
+// How often the stats overlay re-reads the Go heap size; reading it briefly
+// stops the world
+const heapPollInterval = time.Second
+
+// Bytes held by the live pipeline, by owner
+type Memory struct {
+	// Pipe buffer, scratch frame and double-buffered images of the stream
+	Stream int64
+	// Auto-levels and timestamp overlay composites
+	Scratch int64
+	// Renderer diff cache, one entry per cell
+	Cache int64
+}
+
+func (m Memory) Total() int64 {
+	return m.Stream + m.Scratch + m.Cache
+}
+
+// Estimates the pipeline's memory for the current frame size. Caller holds
+// p.mu for reading.
+func (p *Player) memory() Memory {
+	w, h := p.state.FrameW, p.state.FrameH
+	m := Memory{
+		Stream: video.StreamMemory(w, h),
+		Cache:  int64(w) * int64((h+1)/2) * 8,
+	}
+	for _, img := range []*image.RGBA{p.levelsImg, p.overlayImg} {
+		if img != nil {
+			m.Scratch += int64(len(img.Pix))
+		}
+	}
+	return m
+}
+
+func (p *Player) toggleStatsView() {
+	p.statsView = !p.statsView
+	p.render.RequestClear()
+	p.render.InvalidateCache()
+}
+
+// Draws frame, rate and memory figures over the video area
+func (p *Player) renderStatsView(w, h int) {
+	if time.Since(p.heapPolled) >= heapPollInterval {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		p.heapBytes, p.heapPolled = ms.HeapInuse, time.Now()
+	}
+
+	p.mu.RLock()
+	frameW, frameH := p.state.FrameW, p.state.FrameH
+	capped, maxArea := p.state.Capped, p.state.MaxArea
+	mem := p.memory()
+	p.mu.RUnlock()
+
+	size := fmt.Sprintf("%dx%d", frameW, frameH)
+	if capped {
+		size += fmt.Sprintf(" (capped at %s px)", formatCount(int64(maxArea)))
+	}
+	columns := fmt.Sprintf("full width, %d cells drawn", p.render.DrawnCells())
+	if p.render.HalfWidth() {
+		columns = fmt.Sprintf("half width, %d cells drawn", p.render.DrawnCells())
+	}
+	lines := []string{
+		" Frame     " + size,
+		" Columns   " + columns,
+		fmt.Sprintf(" Rate      %.1f fps (%.2f source)", FPS(), p.meta.FPS),
+		fmt.Sprintf(" Frames    %d decoded, %d dropped", p.buffer.FrameCount(), p.buffer.DroppedFrames()),
+		fmt.Sprintf(" Restarts  %d", Stats.Restarts.Load()),
+		" Buffer    " + bufferAhead(p.decoder.BufferAhead()),
+		" A/V sync  " + syncState(p.decoder.SyncStats()),
+		"",
+		" Stream    " + formatBytes(mem.Stream),
+		" Scratch   " + formatBytes(mem.Scratch),
+		" Cache     " + formatBytes(mem.Cache),
+		" Pipeline  " + formatBytes(mem.Total()),
+		" Go heap   " + formatBytes(int64(p.heapBytes)),
+	}
+
+	boxW, boxH := min(w-4, 44), min(h-4, len(lines)+2)
+	if boxW < 10 || boxH < 3 {
+		return
+	}
+	style := tcell.StyleDefault.Background(tcell.ColorBlack).Foreground(tcell.ColorSilver)
+	p.render.DrawBox(w-boxW-2, 1, boxW, boxH, "Stats (i to close)", lines, style)
+}
+
+// Formats the decoder's lead for the stats overlay
+func bufferAhead(ahead time.Duration, running bool) string {
+	if !running {
+		return "-"
+	}
+	return fmt.Sprintf("%.1fs ahead", ahead.Seconds())
+}
+
+// Formats the audio/video drift for the stats overlay
+func syncState(st video.SyncStats, running bool) string {
+	if !running || !st.Synced {
+		return "-"
+	}
+	return fmt.Sprintf("%+dms drift, %d resyncs", st.Drift.Milliseconds(), st.Resyncs)
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// Formats a pixel count like 2073600 as "2.1M"
+func formatCount(n int64) string {
+	if n >= 1_000_000 {
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	}
+	if n >= 1000 {
+		return fmt.Sprintf("%.0fk", float64(n)/1e3)
+	}
+	return fmt.Sprint(n)
+}
@@ -0,0 +1,35 @@
+package player
+
+import (
+	"path/filepath"
+
+	"github.com/0bVdnt/PixlGo/internal/notify"
+	"github.com/0bVdnt/PixlGo/internal/timecode"
+)
+
+// Sends a desktop notification when playback enters the ended or error
+// state. Called from the main loop after each Update.
+func (p *Player) checkNotify() {
+	if !p.notify {
+		return
+	}
+	st := p.Status()
+	if st.State == p.notifiedState {
+		return
+	}
+	p.notifiedState = st.State
+
+	name := filepath.Base(st.File)
+	var title, body string
+	switch st.State {
+	case StateEnded:
+		title, body = "pixlgo: finished", name+" ("+timecode.Format(st.Duration)+")"
+	case StateError:
+		title, body = "pixlgo: playback failed", name+": "+st.Error
+	default:
+		return
+	}
+	if err := notify.Send(title, body); err != nil {
+		p.logger.Debugf("Notification not sent: %v", err)
+	}
+}
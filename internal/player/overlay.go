@@ -0,0 +1,106 @@
+package player
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/bitfont"
+	"github.com/0bVdnt/PixlGo/internal/timecode"
+)
+
+// Frame corner the timestamp overlay is drawn in
+type Corner int
+
+const (
+	CornerNone Corner = iota
+	CornerTopLeft
+	CornerTopRight
+	CornerBottomLeft
+	CornerBottomRight
+)
+
+var cornerNames = map[string]Corner{
+	"top-left":     CornerTopLeft,
+	"top-right":    CornerTopRight,
+	"bottom-left":  CornerBottomLeft,
+	"bottom-right": CornerBottomRight,
+}
+
+// Parses a corner name such as "bottom-right"; "" and "off" disable the
+// overlay
+func ParseCorner(s string) (Corner, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "off" || s == "none" {
+		return CornerNone, nil
+	}
+	if c, ok := cornerNames[s]; ok {
+		return c, nil
+	}
+	return CornerNone, fmt.Errorf("unknown corner %q (want top-left, top-right, bottom-left or bottom-right)", s)
+}
+
+var (
+	overlayText = color.RGBA{255, 255, 255, 255}
+	overlayBack = color.RGBA{0, 0, 0, 255}
+)
+
+// With an automatic scale the boxed text takes at most 1/overlayMaxShare of
+// the frame width
+const overlayMaxShare = 3
+
+// Returns img with the media timestamp ts burned in, or img itself when the
+// overlay is off. The copy is reused between frames since only the main loop
+// renders.
+func (p *Player) overlayTimestamp(img *image.RGBA, ts time.Duration) *image.RGBA {
+	if p.tsCorner == CornerNone || img == nil {
+		return img
+	}
+	b := img.Bounds()
+	if p.overlayImg == nil || p.overlayImg.Bounds() != b {
+		p.overlayImg = image.NewRGBA(b)
+	}
+	copy(p.overlayImg.Pix, img.Pix)
+
+	text := timecode.FormatLayout(ts, p.tsFormat)
+	scale := p.tsScale
+	if scale <= 0 {
+		scale = autoScale(text, b.Dx())
+	}
+	w, h := bitfont.TextSize(text, scale)
+	if w == 0 {
+		return p.overlayImg
+	}
+
+	// One glyph pixel of padding inside the box, and the box away from the edge
+	pad := scale
+	boxW, boxH := w+2*pad, h+2*pad
+	x, y := b.Min.X+pad, b.Min.Y+pad
+	if p.tsCorner == CornerTopRight || p.tsCorner == CornerBottomRight {
+		x = b.Max.X - pad - boxW
+	}
+	if p.tsCorner == CornerBottomLeft || p.tsCorner == CornerBottomRight {
+		y = b.Max.Y - pad - boxH
+	}
+	bitfont.Fill(p.overlayImg, image.Rect(x, y, x+boxW, y+boxH), overlayBack)
+	bitfont.Draw(p.overlayImg, x+pad, y+pad, text, scale, overlayText)
+	return p.overlayImg
+}
+
+// Largest scale at which the boxed text fits in a third of the frame width,
+// at least 1
+func autoScale(text string, frameW int) int {
+	scale := 1
+	if text == "" {
+		return scale
+	}
+	for {
+		w, _ := bitfont.TextSize(text, scale+1)
+		if w+2*(scale+1) > frameW/overlayMaxShare {
+			return scale
+		}
+		scale++
+	}
+}
@@ -0,0 +1,40 @@
+package player
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+
+	"github.com/0bVdnt/PixlGo/internal/logger"
+)
+
+// Exit status after a crash
+const CrashExitCode = 1
+
+// Deferred at the top of Run and every goroutine the player owns
+func (p *Player) recoverPanic() {
+	if r := recover(); r != nil {
+		p.crash(r, debug.Stack())
+	}
+}
+
+// Restores the terminal, kills ffmpeg, reports the panic and exits.
+// Renderer.Close is idempotent, so this is safe after cleanup already ran.
+func (p *Player) crash(r any, stack []byte) {
+	p.crashOnce.Do(func() {
+		p.render.Close()
+		p.decoder.Stop()
+		ReportCrash(p.logger, r, stack)
+		os.Exit(CrashExitCode)
+	})
+}
+
+// Prints the panic and stack to stderr and dumps the log ring to a crash
+// file. Call only once the terminal has been restored.
+func ReportCrash(log *logger.Logger, r any, stack []byte) {
+	fmt.Fprintf(os.Stderr, "pixlgo crashed: %v\n\n%s\n", r, stack)
+	log.Errorf("panic: %v", r)
+	if path, err := log.DumpCrash(os.TempDir(), r, stack); err == nil {
+		fmt.Fprintf(os.Stderr, "Crash log written to %s\n", path)
+	}
+}
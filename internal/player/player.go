@@ -2,11 +2,21 @@ package player
 
 import (
 	"context"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/0bVdnt/PixlGo/internal/chapters"
 	"github.com/0bVdnt/PixlGo/internal/logger"
+	"github.com/0bVdnt/PixlGo/internal/metrics"
 	"github.com/0bVdnt/PixlGo/internal/renderer"
+	"github.com/0bVdnt/PixlGo/internal/subtitle"
+	"github.com/0bVdnt/PixlGo/internal/timecode"
 	"github.com/0bVdnt/PixlGo/internal/video"
 	"github.com/gdamore/tcell/v2"
 )
@@ -18,6 +28,44 @@ type Player struct {
 	meta    video.Metadata
 	logger  *logger.Logger
 
+	// Audio levels for the status bar VU meter; nil when it is off
+	levels *video.LevelMeter
+	// Whether the decoder plays the file's audio
+	audio bool
+
+	// Burned-in subtitles: the track shown (-1 for none; written under mu),
+	// the one c turns on, and whether ffmpeg has the filter, checked on
+	// first use
+	subtitle      int
+	subtitleTrack int
+	subProbed     bool
+	subFilter     bool
+
+	// Audio stream played, counted among the file's audio streams
+	audioTrack int
+
+	// External subtitles drawn as text, the cues on screen, and whether c
+	// hid them
+	cues       *subtitle.Track
+	cuesShown  []*subtitle.Cue
+	cuesHidden bool
+
+	loopAnimated bool
+	loop         int
+	exactSeek    bool
+	// Whether the running stream repeats, and whether only because it is
+	// an animated image, which stops at the end of a pass in a playlist
+	looping     bool
+	loopDefault bool
+
+	// Terminal title, set as the state changes
+	setTitle bool
+	title    string
+
+	// False when probing found no duration; meta.Duration then tracks the
+	// furthest timestamp seen so far
+	durationKnown bool
+
 	mu    sync.RWMutex
 	state *PlayerState
 
@@ -25,12 +73,266 @@ type Player struct {
 	cancel   context.CancelFunc
 	doneChan chan struct{}
 
-	prevState State
+	prevState     State
+	seekKeepAlive time.Duration
+	threads       int
+	maxCPU        int
+	maxFPS        float64
+
+	logView   bool
+	logScroll int
+
+	metrics       *metrics.Recorder
+	metricsStored time.Time
+	metricsDrops  uint64
+
+	// Rendered frames, display rate and restarts for Stats
+	statsStored time.Time
+	fpsFrames   int
+	fpsSince    time.Time
+	started     bool
+
+	crashOnce   sync.Once
+	extractions sync.WaitGroup
+
+	exitOnEnd atomic.Bool
+	finished  bool
+	startPos  time.Duration
+
+	// The -start/-end slice; segEnd 0 means the end of the file
+	segStart time.Duration
+	segEnd   time.Duration
+
+	noClipboard bool
+
+	notify        bool
+	notifiedState State
+
+	// Follow mode: wait at EOF for a file that is still being written
+	follow        bool
+	followTimeout time.Duration
+	followSince   time.Time
+	followPolled  time.Time
+	followSize    int64
+	followMtime   time.Time
+
+	// Still images: the decoded original, which resizes scale from
+	stillImg     *image.RGBA
+	stillLoading bool
+
+	// Live streams: when the last frame arrived, and the pending reconnect
+	liveFrameAt    time.Time
+	reconnecting   bool
+	reconnectAt    time.Time
+	reconnectDelay time.Duration
+
+	// Control commands from Exec, handled by the main loop
+	commands chan commandRequest
+
+	// Burned-in timestamp overlay; overlayImg is the reused composite
+	tsCorner   Corner
+	tsFormat   string
+	tsScale    int
+	overlayImg *image.RGBA
+
+	// Auto-levels stretch with bounds smoothed across frames; negative
+	// bounds mean none measured yet
+	autoLevels bool
+	levelsLo   float64
+	levelsHi   float64
+	levelsImg  *image.RGBA
+
+	restore          *Settings
+	onSettingsChange func(Settings)
+
+	// Stats overlay, with the Go heap size read at most once a second
+	statsView  bool
+	heapBytes  uint64
+	heapPolled time.Time
+
+	// Set once the frame-area cap notice was shown
+	capNoted bool
+
+	// When New was called, for logging the time to first frame
+	created    time.Time
+	firstFrame bool
+
+	// Text input in the status bar, nil when closed
+	prompt *textPrompt
+
+	// User markers, sorted by position, and the list overlay
+	markers         []chapters.Marker
+	markerView      bool
+	markerSel       int
+	markerList      bool
+	onMarkersChange func([]chapters.Marker)
+
+	// Debug views, only reachable with Config.DebugViews; heatmap is
+	// guarded by mu for Status
+	debugViews bool
+	heatmap    bool
+
+	// The playlist overlay; pendingDelete is set after the first d of dd
+	playlist      Playlist
+	playlistView  bool
+	playlistSel   int
+	pendingDelete bool
 }
 
 type Config struct {
 	VideoPath string
 	Logger    *logger.Logger
+
+	// Screen to draw on instead of the terminal, e.g. a
+	// tcell.SimulationScreen for headless use
+	Screen tcell.Screen
+
+	// Forward seeks up to this distance skip frames in the running ffmpeg
+	// process instead of restarting it. Zero disables.
+	SeekKeepAlive time.Duration
+
+	// Caps ffmpeg decode threads. Zero means one per CPU.
+	Threads int
+
+	// Rough CPU budget in percent of all cores. Below 100 it also lowers
+	// the target FPS and enables interlaced rendering. Zero disables.
+	MaxCPU int
+
+	// Caps the decode frame rate for slow terminals. Zero means automatic.
+	MaxFPS float64
+
+	// Records per-frame timings when set
+	Metrics *metrics.Recorder
+
+	// Return from Run once playback ends, so the caller can move on to the
+	// next file
+	ExitOnEnd bool
+
+	// Position to start playing from, e.g. a saved resume point
+	StartPos time.Duration
+	// Frame to start playing from, counted from 0; overrides StartPos
+	// when positive. Past the end it clamps to the last frame.
+	StartFrame int
+
+	// Plays only this slice of the file: seeks stay within it and reaching
+	// SegmentEnd ends playback. SegmentEnd 0 means the end of the file.
+	SegmentStart time.Duration
+	SegmentEnd   time.Duration
+
+	// Waits at the end for a file that is still being written, ending only
+	// after it stopped growing for FollowTimeout (DefaultFollowTimeout if 0)
+	Follow        bool
+	FollowTimeout time.Duration
+
+	// Sends a desktop notification when playback ends or fails
+	Notify bool
+
+	// Disables the y/Y copy keys; some terminals treat OSC 52 clipboard
+	// writes as a security concern
+	DisableClipboard bool
+
+	// Burns the media timestamp into a corner of the frame, laid out with
+	// timecode.FormatLayout (timecode.DefaultLayout if empty) in a bitmap
+	// font. Zero TimestampScale picks a size from the frame width.
+	TimestampOverlay Corner
+	TimestampFormat  string
+	TimestampScale   int
+
+	// Stretches each frame's brightness range to full scale, for dark or
+	// washed-out footage. Toggled with the l key.
+	AutoLevels bool
+
+	// Settings remembered from an earlier run, applied over the options
+	// above with an OSD summary
+	Restore *Settings
+
+	// Called on the main loop whenever the user changes a setting
+	OnSettingsChange func(Settings)
+
+	// Markers saved earlier; OnMarkersChange is called on the main loop
+	// with the full list whenever one is added or deleted
+	Markers         []chapters.Marker
+	OnMarkersChange func([]chapters.Marker)
+
+	// Marker export also writes a "00:12:34 Title" chapter list
+	MarkerList bool
+
+	// Cap on the decoded frame area in pixels, scaling large terminals'
+	// frames down to save memory. Zero means video.DefaultMaxFrameArea,
+	// negative means no cap.
+	MaxFrameArea int
+
+	// Cancels opening and probing the file, e.g. on a signal during
+	// startup; nil means never
+	Context context.Context
+
+	// How long probing may take. Zero means video.DefaultProbeTimeout,
+	// negative means no limit.
+	ProbeTimeout time.Duration
+
+	// Repeat animated GIF, APNG and WebP images until the user quits or a
+	// playlist moves on
+	LoopAnimated bool
+	// Extra passes over the file in the same ffmpeg run, -1 for forever;
+	// overrides LoopAnimated when nonzero
+	Loop int
+
+	// Seek frame-accurately while paused, and resume from exactly the
+	// frame shown; seeks while playing always take the fast input seek
+	ExactSeek bool
+
+	// Set the terminal window title to the file name; the previous title
+	// comes back on exit where the terminal supports it
+	WindowTitle bool
+
+	// Enable debug views such as the motion heatmap (H)
+	DebugViews bool
+
+	// The playlist this file is part of, for the P overlay; nil when
+	// playing a single file
+	Playlist Playlist
+
+	// Show a VU meter in the status bar, fed by an audio tap alongside the
+	// video stream. Ignored for files without audio.
+	VUMeter bool
+
+	// Hardware decoding method passed to ffmpeg's -hwaccel, such as auto,
+	// vaapi, videotoolbox or cuda, and an optional device. Empty or "none"
+	// decodes in software, as does a method that fails to initialize.
+	HWAccel       string
+	HWAccelDevice string
+
+	// Start with text subtitle track SubtitleTrack, counted from 0 among
+	// the subtitle streams, burned into the video. Files without it, or an
+	// ffmpeg without libass, play without subtitles; a positive track the
+	// file lacks fails New.
+	Subtitles     bool
+	SubtitleTrack int
+
+	// Subtitles from an external file, drawn as text above the progress
+	// bar; C hides them. Replaces Subtitles when set.
+	ExternalSubtitles *subtitle.Track
+
+	// Video stream to play, counted from 0 among the video streams; 0 means
+	// the first one that isn't cover art. A stream the file lacks fails New.
+	VideoStream int
+
+	// Input read for a VideoPath of video.StdinPath; nil means os.Stdin.
+	// StdinHint's Width, Height and FPS override what its probe finds.
+	Stdin     io.Reader
+	StdinHint video.Metadata
+
+	// Audio stream to play, counted from 0 among the audio streams. A
+	// positive track the file lacks fails New.
+	AudioTrack int
+
+	// Skip audio playback; by default files with an audio stream play it
+	// through ffplay alongside the video
+	NoAudio bool
+
+	// Start with half-width decoding, each pixel column drawn as two cells;
+	// halves the pixels to decode and compare on very wide terminals
+	HalfWidth bool
 }
 
 func New(cfg Config) (*Player, error) {
@@ -39,24 +341,128 @@ func New(cfg Config) (*Player, error) {
 		log = logger.Noop()
 	}
 
-	log.Log("Creating decoder for: %s", cfg.VideoPath)
+	if cfg.SegmentStart < 0 || cfg.SegmentEnd != 0 && cfg.SegmentEnd <= cfg.SegmentStart {
+		return nil, fmt.Errorf("segment end %v must be after its start %v", cfg.SegmentEnd, cfg.SegmentStart)
+	}
+
+	log.Debugf("Creating decoder for: %s", cfg.VideoPath)
+	created := time.Now()
+
+	// The terminal initializes while ffprobe runs; both take tens of
+	// milliseconds. Files that don't exist fail before the screen flashes,
+	// and a slow probe shows a spinner that Esc cancels.
+	probeCtx, cancelProbe := probeContext(cfg.Context, cfg.ProbeTimeout)
+	defer cancelProbe()
+	probeDone := make(chan probeResult, 1)
+	go func() {
+		logs := video.Logs{
+			Debug: log.Func(logger.LevelDebug),
+			Info:  log.Func(logger.LevelInfo),
+			Error: log.Func(logger.LevelError),
+		}
+		var decoder *video.Decoder
+		var err error
+		if cfg.VideoPath == video.StdinPath {
+			stdin := cfg.Stdin
+			if stdin == nil {
+				stdin = os.Stdin
+			}
+			decoder, err = video.NewDecoderFromReaderContext(probeCtx, stdin, cfg.StdinHint, logs)
+		} else {
+			decoder, err = video.NewDecoderContext(probeCtx, cfg.VideoPath, logs)
+		}
+		probeDone <- probeResult{decoder, err, time.Since(created)}
+	}()
+
+	local := !video.IsURL(cfg.VideoPath) && cfg.VideoPath != video.StdinPath
+	if _, statErr := video.StatContext(probeCtx, cfg.VideoPath); statErr != nil && local {
+		res := <-probeDone
+		if res.err != nil {
+			return nil, res.err
+		}
+		probeDone <- res
+	}
 
-	decoder, err := video.NewDecoderWithLogger(cfg.VideoPath, log.Log)
+	newRenderer := renderer.New
+	if cfg.Screen != nil {
+		newRenderer = func() (*renderer.Renderer, error) { return renderer.NewWithScreen(cfg.Screen) }
+	}
+	render, err := newRenderer()
+	screenTook := time.Since(created)
 	if err != nil {
+		cancelProbe()
+		if res := <-probeDone; res.decoder != nil {
+			res.decoder.Close()
+		}
 		return nil, err
 	}
+	probe := waitProbe(render, cfg.VideoPath, probeDone, cancelProbe)
+	if probe.err != nil {
+		render.Close()
+		return nil, probe.err
+	}
+	decoder := probe.decoder
+	log.Debug("startup", "probe", probe.took, "screen", screenTook)
+	// No audio-only mode yet: music without cover art has nothing to show
+	if meta := decoder.Metadata(); !meta.IsValid() {
+		decoder.Close()
+		render.Close()
+		return nil, meta.RequireVideo()
+	}
 
-	render, err := renderer.New()
-	if err != nil {
+	maxCPU := clamp(cfg.MaxCPU, 0, 100)
+	threads := cfg.Threads
+	if threads <= 0 && maxCPU > 0 {
+		threads = max(1, runtime.NumCPU()*maxCPU/100)
+	}
+	decoder.SetThreads(threads)
+	decoder.SetHWAccel(cfg.HWAccel, cfg.HWAccelDevice)
+	render.SetInterlace(maxCPU > 0 && maxCPU < 100)
+	render.SetHalfWidth(cfg.HalfWidth)
+
+	if threads > 0 || maxCPU > 0 {
+		log.Infof("CPU limits: threads=%d max-cpu=%d%%", threads, maxCPU)
+	}
+
+	if cfg.VideoStream > 0 {
+		if err := decoder.SelectVideoStream(cfg.VideoStream); err != nil {
+			decoder.Close()
+			render.Close()
+			return nil, err
+		}
+		log.Info("Video stream selected", "stream", cfg.VideoStream, "index", decoder.Metadata().StreamIndex)
+	}
+	meta := decoder.Metadata()
+	if err := checkTracks(meta, cfg); err != nil {
 		decoder.Close()
+		render.Close()
 		return nil, err
 	}
+	decoder.SetAudioTrack(cfg.AudioTrack)
 
 	ctx, cancel := context.WithCancel(context.Background())
-	meta := decoder.Metadata()
+	var levels *video.LevelMeter
+	if cfg.VUMeter {
+		if levels = decoder.EnableLevelMeter(); levels == nil {
+			log.Info("No audio stream, VU meter disabled")
+		}
+	}
+	audio := false
+	if !cfg.NoAudio {
+		var err error
+		switch audio, err = decoder.EnableAudio(); {
+		case err != nil:
+			log.Warn("Playing without sound", "err", err)
+		case !audio:
+			log.Info("No audio stream")
+		}
+	}
+	if meta.AttachedPic {
+		log.Infof("No video stream, showing cover art (stream %d)", meta.StreamIndex)
+	}
 	screenW, screenH := render.Size()
 
-	return &Player{
+	p := &Player{
 		decoder:  decoder,
 		render:   render,
 		buffer:   video.NewFrameBuffer(),
@@ -66,24 +472,105 @@ func New(cfg Config) (*Player, error) {
 		ctx:      ctx,
 		cancel:   cancel,
 		doneChan: make(chan struct{}),
-	}, nil
+		commands: make(chan commandRequest),
+
+		levels:        levels,
+		audio:         audio,
+		audioTrack:    max(cfg.AudioTrack, 0),
+		subtitle:      -1,
+		subtitleTrack: max(cfg.SubtitleTrack, 0),
+		cues:          cfg.ExternalSubtitles,
+		loopAnimated:  cfg.LoopAnimated,
+		loop:          cfg.Loop,
+		exactSeek:     cfg.ExactSeek,
+		setTitle:      cfg.WindowTitle,
+		playlist:      cfg.Playlist,
+		debugViews:    cfg.DebugViews,
+		durationKnown: meta.Duration > 0,
+		seekKeepAlive: cfg.SeekKeepAlive,
+		threads:       threads,
+		maxCPU:        maxCPU,
+		maxFPS:        cfg.MaxFPS,
+		metrics:       cfg.Metrics,
+		startPos:      max(cfg.StartPos, 0),
+		segStart:      cfg.SegmentStart,
+		segEnd:        cfg.SegmentEnd,
+		noClipboard:   cfg.DisableClipboard,
+		notify:        cfg.Notify,
+		follow:        cfg.Follow,
+		followTimeout: cfg.FollowTimeout,
+		tsCorner:      cfg.TimestampOverlay,
+		tsFormat:      cfg.TimestampFormat,
+		tsScale:       cfg.TimestampScale,
+		autoLevels:    cfg.AutoLevels,
+		levelsLo:      -1,
+		levelsHi:      -1,
+
+		created:          created,
+		restore:          cfg.Restore,
+		onSettingsChange: cfg.OnSettingsChange,
+		markers:          append([]chapters.Marker(nil), cfg.Markers...),
+		markerList:       cfg.MarkerList,
+		onMarkersChange:  cfg.OnMarkersChange,
+	}
+	chapters.Sort(p.markers)
+	switch {
+	case cfg.MaxFrameArea == 0:
+		p.state.MaxArea = video.DefaultMaxFrameArea
+	case cfg.MaxFrameArea > 0:
+		p.state.MaxArea = cfg.MaxFrameArea
+	}
+	p.state.HalfWidth = cfg.HalfWidth
+	p.state.Volume = 100
+	p.state.UpdateDimensions(screenW, screenH, meta)
+	if p.tsFormat == "" {
+		p.tsFormat = timecode.DefaultLayout
+	}
+	p.exitOnEnd.Store(cfg.ExitOnEnd)
+	if p.followTimeout <= 0 {
+		p.followTimeout = DefaultFollowTimeout
+	}
+	if cfg.StartFrame > 0 && meta.FPS > 0 && !meta.AttachedPic {
+		pos, frame, clamped := p.framePos(cfg.StartFrame)
+		p.startPos = pos
+		if clamped {
+			p.ShowOSD(fmt.Sprintf("Frame %d is past the end (%d frames); starting at frame %d",
+				cfg.StartFrame, p.frameCount(), frame))
+		}
+	}
+	p.checkSegment()
+	if cfg.Subtitles && cfg.ExternalSubtitles == nil {
+		if msg := p.checkSubtitle(p.subtitleTrack); msg != "" {
+			log.Warn("Playing without subtitles", "reason", msg)
+			p.ShowOSD(msg)
+		} else {
+			p.subtitle = p.subtitleTrack
+			decoder.SetSubtitles(p.subtitle)
+		}
+	}
+	decoder.SetPanicHandler(p.crash)
+	return p, nil
 }
 
 func (p *Player) Run() {
+	// Deferred first so it runs after cleanup has restored the terminal
+	defer p.recoverPanic()
 	defer p.cleanup()
 
 	eventChan := make(chan tcell.Event, 50)
 	go p.pollEvents(eventChan)
 
-	time.Sleep(50 * time.Millisecond)
-	p.drainInitialEvents(eventChan)
-
+	// The screen knows its size after Init; tcell's initial EventResize is
+	// handled by the main loop and only restarts if the size differs.
 	p.mu.Lock()
 	w, h := p.render.Size()
 	p.state.UpdateDimensions(w, h, p.meta)
 	p.mu.Unlock()
 
-	p.StartPlayback(0)
+	if p.restore != nil {
+		p.restoreSettings(*p.restore)
+	}
+	p.StartPlayback(p.startPos)
 	p.mainLoop(eventChan)
 }
 
@@ -104,9 +591,23 @@ func (p *Player) mainLoop(eventchan <-chan tcell.Event) {
 				return
 			}
 
+		case req := <-p.commands:
+			res := p.execCommand(req.line)
+			req.reply <- res
+			if res.quit {
+				return
+			}
+
 		case <-ticker.C:
 			p.Update()
+			p.checkFollow()
+			p.checkReconnect()
+			p.checkNotify()
 			p.Render()
+			if p.exitOnEnd.Load() && p.ended() {
+				p.finished = true
+				return
+			}
 		}
 	}
 }
@@ -117,7 +618,12 @@ func (p *Player) Update() {
 		state := p.state.State
 		p.mu.RUnlock()
 
-		if state == StateLoading {
+		switch {
+		case state == StateLoading && p.reconnecting:
+			p.mu.Lock()
+			p.beginReconnect(err.Error())
+			p.mu.Unlock()
+		case state == StateLoading:
 			p.SetError(err.Error())
 		}
 		return
@@ -128,29 +634,125 @@ func (p *Player) Update() {
 
 	switch p.state.State {
 	case StateLoading:
+		// Read the end reason first: streams store frames before reporting
+		// their end, so a short clip that already finished is still seen as
+		// Loading -> Playing here and moves on to Ended next tick
+		reason := p.buffer.EndReason()
 		frame := p.buffer.Load()
 		if frame != nil {
 			p.state.LastFrame = frame
-			p.state.CurrentTime = frame.Timestamp
+			p.state.setPosition(frame.Timestamp)
 			p.state.State = StatePlaying
+			p.liveFrameAt = time.Now()
+			if !p.firstFrame {
+				p.firstFrame = true
+				p.logger.Info("first frame", "after", time.Since(p.created).Round(time.Millisecond),
+					"decoded", frame.Stored.Sub(p.created).Round(time.Millisecond))
+			}
+		} else if p.reconnecting && (reason == video.EndEOF || time.Since(p.state.LoadingStart) > 10*time.Second) {
+			p.beginReconnect("no frames")
+		} else if reason == video.EndEmpty {
+			p.handleEmptyStream()
+		} else if reason == video.EndEOF {
+			// Finished without storing anything; don't wait for the timeout
+			p.state.State = StateError
+			p.state.ErrorMsg = "No frames decoded"
+			Stats.Errors.Add(1)
 		} else if time.Since(p.state.LoadingStart) > 10*time.Second {
 			p.state.State = StateError
 			p.state.ErrorMsg = "Timeout loading video"
+			Stats.Errors.Add(1)
 		}
 	case StatePlaying:
 		frame := p.buffer.Load()
 		if frame != nil {
+			// A looping stream's timestamps start over after each pass
+			wrapped := p.looping && frame.Timestamp < p.state.CurrentTime
 			p.state.LastFrame = frame
-			p.state.CurrentTime = frame.Timestamp
+			p.state.setPosition(frame.Timestamp)
+			p.liveFrameAt = time.Now()
+			if wrapped && p.loopDefault && p.exitOnEnd.Load() {
+				// A playlist queued meanwhile moves on after this pass
+				p.decoder.Stop()
+				p.endPlayback()
+				break
+			}
+			if !p.looping && frame.Timestamp > p.meta.Duration {
+				p.meta.Duration = frame.Timestamp
+			}
+		}
+		if p.pastSegment() {
+			break
 		}
 
-		if !p.decoder.IsRunning() && p.buffer.FrameCount() > 0 {
-			p.state.State = StateEnded
+		// Only the current epoch's stream running out ends playback; stops
+		// and restarts during seeks report other reasons
+		if p.state.Following {
+			break
+		}
+		switch p.buffer.EndReason() {
+		case video.EndEOF:
+			p.endPlayback()
+		case video.EndError:
+			if p.buffer.FrameCount() > 0 {
+				p.endPlayback()
+			}
 		}
 	}
 }
 
+// Ends playback, waits for more data in follow mode, or reconnects to a
+// live network stream. Caller holds p.mu.
+func (p *Player) endPlayback() {
+	if p.meta.Live && video.IsURL(p.meta.Path) {
+		p.beginReconnect("stream ended")
+		return
+	}
+	if p.follow {
+		p.beginFollow()
+		return
+	}
+	p.state.State = StateEnded
+}
+
+func (p *Player) ended() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.state.State == StateEnded
+}
+
+// A stream that exits cleanly without frames started past the last decodable
+// frame, typically because the probed duration is overstated. Step back and
+// show the final frame as ended instead of reporting a decode error.
+// Caller holds p.mu.
+func (p *Player) handleEmptyStream() {
+	pos := p.state.CurrentTime
+	if p.follow && pos > 0 {
+		// Nothing written past pos yet
+		p.beginFollow()
+		return
+	}
+	if pos <= 0 {
+		p.state.State = StateError
+		p.state.ErrorMsg = video.ErrDecodeFailed.Error()
+		Stats.Errors.Add(1)
+		return
+	}
+
+	final := max(pos-time.Second, 0)
+	p.logger.Infof("No frames at %v, treating as end of video (showing %v)", pos, final)
+	p.state.CurrentTime = final
+	p.state.State = StateEnded
+	if p.durationKnown && pos < p.meta.Duration {
+		p.meta.Duration = pos
+	}
+
+	frameW, frameH := p.state.CurrentFrameSize(p.meta)
+	p.extractFrameAsync(final, frameW, frameH, p.buffer.Epoch())
+}
+
 func (p *Player) pollEvents(eventChan chan<- tcell.Event) {
+	defer p.recoverPanic()
 	screen := p.render.Screen()
 	if screen == nil {
 		return
@@ -164,39 +766,68 @@ func (p *Player) pollEvents(eventChan chan<- tcell.Event) {
 		select {
 		case eventChan <- ev:
 		case <-p.doneChan:
-			return
+			// Keep swallowing input until Fini makes PollEvent return nil,
+			// so half-read escape sequences don't reach the shell
 		case <-p.ctx.Done():
-			return
 		}
 	}
 }
 
-func (p *Player) drainInitialEvents(eventchan <-chan tcell.Event) {
-	for {
-		select {
-		case ev := <-eventchan:
-			if ev == nil {
-				return
-			}
-			if resize, ok := ev.(*tcell.EventResize); ok {
-				w, h := resize.Size()
-				p.mu.Lock()
-				p.state.ScreenW = w
-				p.state.ScreenH = h
-				p.mu.Unlock()
-			}
-		case <-time.After(20 * time.Millisecond):
-			return
-		}
-	}
-}
+// How long cleanup waits for in-flight single-frame extractions
+const (
+	extractionGrace = 500 * time.Millisecond
+	inputDrainGrace = 50 * time.Millisecond
+)
 
 func (p *Player) cleanup() {
 	close(p.doneChan)
+	p.cancel()
 	p.decoder.Close()
+
+	// Cancelling ctx kills their ffmpeg processes; give them a moment to exit
+	waited := make(chan struct{})
+	go func() {
+		p.extractions.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(extractionGrace):
+	}
+
+	// Let pollEvents drain keys still arriving before the terminal resets
+	time.Sleep(inputDrainGrace)
 	p.render.Close()
 }
 
+// Reports whether Run returned because playback ended with ExitOnEnd set or
+// a next command skipped ahead, rather than because the user quit or Stop
+// was called
+func (p *Player) Finished() bool {
+	return p.finished
+}
+
+// Returns the error playback ended with, or nil if it didn't end in the
+// error state. Decode failures wrap video.ErrDecodeFailed.
+func (p *Player) Err() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.state.State != StateError {
+		return nil
+	}
+	msg := p.state.ErrorMsg
+	if msg == video.ErrDecodeFailed.Error() {
+		return video.ErrDecodeFailed
+	}
+	return fmt.Errorf("%w: %s", video.ErrDecodeFailed, msg)
+}
+
+// Changes Config.ExitOnEnd while playing, e.g. once another file was queued
+// behind this one. Safe to call from any goroutine.
+func (p *Player) SetExitOnEnd(exit bool) {
+	p.exitOnEnd.Store(exit)
+}
+
 func (p *Player) Stop() {
 	p.cancel()
 }
@@ -0,0 +1,345 @@
+package player
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/fakeff"
+	"github.com/0bVdnt/PixlGo/internal/renderer"
+	"github.com/0bVdnt/PixlGo/internal/video"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Restarts during seeks and pause/resume stop streams mid-file; none of
+// that may show as the end of playback, not even for a tick
+func TestRapidSeekPauseNeverEnds(t *testing.T) {
+	p, _ := newTestPlayer(t, "clip.json", map[string]string{"FRAMES": "100000", "INTERVAL": "2ms"}, Config{})
+	p.StartPlayback(0)
+	waitState(t, p, StatePlaying)
+
+	actions := []func(){
+		func() { p.Seek(5 * time.Second) },
+		func() { p.Seek(-3 * time.Second) },
+		p.TogglePause,
+		func() { p.Seek(time.Second) },
+		p.TogglePause,
+		func() { p.seek(2*time.Second, false) },
+	}
+	for i := range 60 {
+		actions[i%len(actions)]()
+		for range 3 {
+			p.Update()
+			if state := p.testState(); state == StateEnded || state == StateError {
+				t.Fatalf("step %d: state %s", i, state)
+			}
+			time.Sleep(time.Duration(i%3) * time.Millisecond)
+		}
+	}
+}
+
+// Shrinking below MinScreenW x MinScreenH stops the stream and shows the
+// size hint clipped to whatever fits; growing again resumes
+func TestTinyTerminal(t *testing.T) {
+	p, screen := newTestPlayer(t, "clip.json", map[string]string{"FRAMES": "100000", "INTERVAL": "2ms"}, Config{})
+	p.StartPlayback(0)
+	waitState(t, p, StatePlaying)
+	msg := fmt.Sprintf("Terminal too small (%dx%d required)", MinScreenW, MinScreenH)
+
+	resize := func(w, h int) {
+		screen.SetSize(w, h)
+		p.HandleEvent(tcell.NewEventResize(w, h))
+		p.Update()
+		p.Render()
+	}
+	for _, size := range [][2]int{{1, 1}, {5, 2}, {19, 5}} {
+		w, h := size[0], size[1]
+		resize(w, h)
+		if state := p.testState(); state != StatePaused {
+			t.Fatalf("%dx%d: state %s, want paused", w, h, state)
+		}
+		if p.decoder.IsRunning() {
+			t.Errorf("%dx%d: still decoding", w, h)
+		}
+		rows := strings.Split(p.render.Snapshot().String(), "\n")
+		if got, want := strings.TrimSpace(rows[h/2]), renderer.Truncate(msg, w); got != want {
+			t.Errorf("%dx%d: row %d = %q, want %q", w, h, h/2, got, want)
+		}
+	}
+
+	resize(80, 25)
+	waitState(t, p, StatePlaying)
+	frame := p.buffer.Load()
+	if w, h := frame.Image.Bounds().Dx(), frame.Image.Bounds().Dy(); w > 80 || h > 2*(25-3) {
+		t.Errorf("frame %dx%d after growing back", w, h)
+	}
+}
+
+// Each restart decodes at the size of the latest screen, however resizes
+// and seeks interleave with loading
+func TestResizeSeekInterleaved(t *testing.T) {
+	argsFile := filepath.Join(t.TempDir(), "args")
+	p, screen := newTestPlayer(t, "clip.json",
+		map[string]string{"FRAMES": "100000", "INTERVAL": "5ms", "ARGS": argsFile}, Config{})
+	p.StartPlayback(0)
+	waitState(t, p, StatePlaying)
+
+	sizes := [][2]int{{100, 30}, {60, 20}, {120, 40}, {70, 25}, {90, 30}}
+	for i, size := range sizes {
+		w, h := size[0], size[1]
+		screen.SetSize(w, h)
+		p.HandleEvent(tcell.NewEventResize(w, h))
+		// Still loading from the resize
+		p.Seek(time.Second)
+		if i%2 == 1 {
+			// A second resize lands before the seek's stream shows anything
+			w, h = w+10, h+4
+			screen.SetSize(w, h)
+			p.HandleEvent(tcell.NewEventResize(w, h))
+			p.Seek(-time.Second)
+		}
+		waitState(t, p, StatePlaying)
+
+		wantW, wantH := CalculateFrameDimensions(w, h, p.meta)
+		runs := fakeff.Args(t, argsFile)
+		args := runs[len(runs)-1]
+		vf := args[slices.Index(args, "-vf")+1]
+		if !strings.Contains(vf, fmt.Sprintf("scale=%d:%d", wantW, wantH)) {
+			t.Errorf("%dx%d: ffmpeg filter %q, want scale=%d:%d", w, h, vf, wantW, wantH)
+		}
+		if b := p.buffer.Load().Image.Bounds(); b.Dx() != wantW || b.Dy() != wantH {
+			t.Errorf("%dx%d: frame %dx%d, want %dx%d", w, h, b.Dx(), b.Dy(), wantW, wantH)
+		}
+	}
+}
+
+// Run takes the startup size from the screen; a resize event arriving after
+// playback started restarts it once at the new size, and tcell's initial
+// event for the size already in use doesn't restart it at all
+func TestRunLateResize(t *testing.T) {
+	tests := []struct {
+		name     string
+		w, h     int
+		restarts int
+	}{
+		{"same size", 80, 25, 0},
+		{"new size", 100, 30, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			argsFile := filepath.Join(t.TempDir(), "args")
+			p, screen := newTestPlayer(t, "clip.json",
+				map[string]string{"FRAMES": "100000", "INTERVAL": "2ms", "ARGS": argsFile}, Config{})
+			done := make(chan struct{})
+			go func() {
+				p.Run()
+				close(done)
+			}()
+			streams := func() [][]string {
+				var runs [][]string
+				for _, args := range fakeff.Args(t, argsFile) {
+					if slices.Contains(args, "-vf") {
+						runs = append(runs, args)
+					}
+				}
+				return runs
+			}
+			waitFor := func(what string, cond func() bool) {
+				t.Helper()
+				deadline := time.Now().Add(5 * time.Second)
+				for !cond() {
+					if time.Now().After(deadline) {
+						t.Fatalf("timed out waiting for %s", what)
+					}
+					time.Sleep(time.Millisecond)
+				}
+			}
+			waitFor("playback", func() bool { return p.testState() == StatePlaying })
+
+			screen.SetSize(tt.w, tt.h)
+			screen.PostEvent(tcell.NewEventResize(tt.w, tt.h))
+			// Handled after the resize; without audio it only shows an OSD
+			screen.PostEvent(tcell.NewEventKey(tcell.KeyRune, '+', tcell.ModNone))
+			waitFor("the key after the resize", func() bool {
+				p.mu.RLock()
+				defer p.mu.RUnlock()
+				return p.state.OSD == "No audio"
+			})
+			waitFor("the restart", func() bool { return len(streams()) >= 1+tt.restarts })
+			// Give a spurious restart time to show
+			time.Sleep(100 * time.Millisecond)
+			p.Stop()
+			<-done
+
+			runs := streams()
+			if len(runs) != 1+tt.restarts {
+				t.Fatalf("%d streams started, want %d", len(runs), 1+tt.restarts)
+			}
+			wantW, wantH := CalculateFrameDimensions(tt.w, tt.h, p.meta)
+			args := runs[len(runs)-1]
+			vf := args[slices.Index(args, "-vf")+1]
+			if !strings.Contains(vf, fmt.Sprintf("scale=%d:%d", wantW, wantH)) {
+				t.Errorf("ffmpeg filter %q, want scale=%d:%d", vf, wantW, wantH)
+			}
+		})
+	}
+}
+
+// Returns the distinct states Update passes through until want, which the
+// test fails to reach within a few seconds
+func stateTrail(t *testing.T, p *Player, want State) []State {
+	t.Helper()
+	trail := []State{p.testState()}
+	deadline := time.Now().Add(5 * time.Second)
+	for trail[len(trail)-1] != want && time.Now().Before(deadline) {
+		p.Update()
+		if state := p.testState(); state != trail[len(trail)-1] {
+			trail = append(trail, state)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return trail
+}
+
+// A clip that decodes completely before the first tick still shows its
+// frames and ends, instead of timing out in Loading
+func TestThreeFrameClip(t *testing.T) {
+	p, _ := newTestPlayer(t, "short.json", map[string]string{"FRAMES": "3"}, Config{})
+	p.StartPlayback(0)
+	deadline := time.Now().Add(5 * time.Second)
+	for p.buffer.EndReason() == video.EndNone && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	trail := stateTrail(t, p, StateEnded)
+	if want := []State{StateLoading, StatePlaying, StateEnded}; !slices.Equal(trail, want) {
+		t.Errorf("states %v, want %v", trail, want)
+	}
+	if p.state.LastFrame == nil || p.buffer.FrameCount() != 3 {
+		t.Errorf("last frame %v after %d frames", p.state.LastFrame, p.buffer.FrameCount())
+	}
+}
+
+// The same with a clip ffmpeg generates, where it is installed
+func TestThreeFrameClipFFmpeg(t *testing.T) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+	if _, err := exec.LookPath("ffprobe"); err != nil {
+		t.Skip("ffprobe not installed")
+	}
+	path := filepath.Join(t.TempDir(), "three.mp4")
+	gen := exec.Command(ffmpeg, "-v", "error", "-f", "lavfi", "-i", "testsrc=size=320x240:rate=25",
+		"-frames:v", "3", "-pix_fmt", "yuv420p", path)
+	if out, err := gen.CombinedOutput(); err != nil {
+		t.Skipf("generating the clip failed: %v: %s", err, out)
+	}
+
+	p, err := New(Config{VideoPath: path, Screen: tcell.NewSimulationScreen("UTF-8"), NoAudio: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.cleanup)
+	p.StartPlayback(0)
+	trail := stateTrail(t, p, StateEnded)
+	if want := []State{StateLoading, StatePlaying, StateEnded}; !slices.Equal(trail, want) {
+		t.Errorf("states %v, want %v", trail, want)
+	}
+}
+
+// Quitting while a paused seek is extracting its frame kills that ffmpeg
+// and leaves nothing running
+func TestQuitDuringExtraction(t *testing.T) {
+	// Both the stream and the extraction hang after their frame
+	p, _ := newTestPlayer(t, "clip.json", map[string]string{"FRAMES": "1", "HANG": "1"}, Config{})
+	p.StartPlayback(0)
+	waitState(t, p, StatePlaying)
+	p.TogglePause()
+	if len(fakeff.ChildProcesses(t)) != 0 {
+		t.Fatal("stream still running after pause")
+	}
+	goroutines := runtime.NumGoroutine()
+
+	p.Seek(2 * time.Second)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(fakeff.ChildProcesses(t)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("extraction never started")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	start := time.Now()
+	p.Stop()
+	p.cleanup()
+	if took := time.Since(start); took > extractionGrace {
+		t.Errorf("cleanup took %v", took)
+	}
+	if pids := fakeff.ChildProcesses(t); len(pids) != 0 {
+		t.Errorf("ffmpeg still running: %v", pids)
+	}
+	fakeff.WaitGoroutines(t, goroutines)
+}
+
+// Probing describes a music file; the player is what turns it down
+func TestAudioOnlyRefused(t *testing.T) {
+	_, err := New(testConfig(t, "audio.json", nil, Config{}))
+	if !errors.Is(err, video.ErrNoVideoStream) || !strings.Contains(err.Error(), "only audio") {
+		t.Errorf("error %v, want an audio-only ErrNoVideoStream", err)
+	}
+}
+
+// Without a probed duration forward seeks go anywhere ahead, End has
+// nowhere to seek to, and the duration grows with the frames played
+func TestUnknownDuration(t *testing.T) {
+	p, _ := newTestPlayer(t, "unknown.json", map[string]string{"FRAMES": "100000", "INTERVAL": "2ms"}, Config{})
+	if st := p.Status(); st.DurationKnown || st.Duration != 0 {
+		t.Fatalf("duration %s, known %v, want unknown", st.Duration, st.DurationKnown)
+	}
+	p.StartPlayback(0)
+	waitState(t, p, StatePlaying)
+
+	var seen time.Duration
+	deadline := time.Now().Add(5 * time.Second)
+	for grew := 0; grew < 3; {
+		if time.Now().After(deadline) {
+			t.Fatalf("duration stuck at %s", seen)
+		}
+		p.Update()
+		st := p.Status()
+		if st.Duration < st.Position {
+			t.Fatalf("duration %s behind the frame at %s", st.Duration, st.Position)
+		}
+		if st.Duration > seen {
+			seen = st.Duration
+			grew++
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if p.Status().DurationKnown {
+		t.Error("a duration from frame timestamps counts as known")
+	}
+
+	p.TogglePause()
+	pos := p.Status().Position
+	p.Seek(time.Minute)
+	if got, want := p.Status().Position, pos+time.Minute; got != want {
+		t.Errorf("seeked to %s, want %s", got, want)
+	}
+
+	p.HandleEvent(tcell.NewEventKey(tcell.KeyEnd, 0, tcell.ModNone))
+	st := p.Status()
+	if st.OSD != "End unavailable: duration unknown" {
+		t.Errorf("OSD %q after End", st.OSD)
+	}
+	if st.Position != pos+time.Minute {
+		t.Errorf("End moved to %s", st.Position)
+	}
+}
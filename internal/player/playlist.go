@@ -0,0 +1,160 @@
+package player
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/renderer"
+	"github.com/gdamore/tcell/v2"
+)
+
+// One file of a playlist as the overlay shows it
+type PlaylistEntry struct {
+	Path     string
+	Duration time.Duration // zero until the file was probed
+	Current  bool
+}
+
+// The playlist a player is part of. Methods are called from the main loop
+// and must be safe against other goroutines changing the list.
+type Playlist interface {
+	Entries() []PlaylistEntry
+	// Returns the index of the current file and the number of files
+	Position() (index, length int)
+	// Makes entry i the next file to play
+	Jump(i int)
+	// Removes entry i; the current file can't be removed
+	Remove(i int) error
+}
+
+func (p *Player) togglePlaylistView() {
+	if p.playlist == nil {
+		p.ShowOSD("No playlist")
+		return
+	}
+	p.playlistView = !p.playlistView
+	p.playlistSel = -1
+	p.pendingDelete = false
+	p.render.RequestClear()
+	p.render.InvalidateCache()
+}
+
+// Handles keys while the playlist is open
+func (p *Player) handlePlaylistViewKey(ev *tcell.EventKey) EventResult {
+	entries := p.playlist.Entries()
+	if p.playlistSel < 0 {
+		// Start on the playing file
+		p.playlistSel = 0
+		for i, e := range entries {
+			if e.Current {
+				p.playlistSel = i
+			}
+		}
+	}
+	pendingDelete := p.pendingDelete
+	p.pendingDelete = false
+
+	switch ev.Key() {
+	case tcell.KeyEscape, tcell.KeyF3:
+		p.togglePlaylistView()
+		return EventContinue
+	case tcell.KeyUp:
+		p.playlistSel--
+	case tcell.KeyDown:
+		p.playlistSel++
+	case tcell.KeyPgUp:
+		p.playlistSel -= 10
+	case tcell.KeyPgDn:
+		p.playlistSel += 10
+	case tcell.KeyHome:
+		p.playlistSel = 0
+	case tcell.KeyEnd:
+		p.playlistSel = len(entries) - 1
+	case tcell.KeyEnter:
+		if p.playlistSel < len(entries) && !entries[p.playlistSel].Current {
+			// Run returns as if playback ended, so the caller opens the
+			// entry the same way it advances to the next file
+			p.playlist.Jump(p.playlistSel)
+			p.finished = true
+			return EventQuit
+		}
+	case tcell.KeyDelete:
+		p.removePlaylistEntry()
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case 'q', 'Q':
+			return EventQuit
+		case 'P':
+			p.togglePlaylistView()
+			return EventContinue
+		case 'd':
+			// dd removes, like in vim
+			if pendingDelete {
+				p.removePlaylistEntry()
+			} else {
+				p.pendingDelete = true
+			}
+		}
+	}
+	p.playlistSel = clamp(p.playlistSel, 0, max(len(p.playlist.Entries())-1, 0))
+	return EventContinue
+}
+
+func (p *Player) removePlaylistEntry() {
+	if err := p.playlist.Remove(p.playlistSel); err != nil {
+		p.ShowOSD(err.Error())
+		return
+	}
+	p.render.RequestClear()
+
+	// With nothing left after this file, stay on its end screen
+	entries := p.playlist.Entries()
+	if len(entries) > 0 && entries[len(entries)-1].Current {
+		p.SetExitOnEnd(false)
+	}
+}
+
+// Draws the playlist over the video area
+func (p *Player) renderPlaylistView(w, h int) {
+	entries := p.playlist.Entries()
+	boxW, boxH := min(w-4, 80), min(h-4, len(entries)+3)
+	if boxW < 20 || boxH < 4 {
+		return
+	}
+
+	lines := []string{" Enter: play  dd/Del: remove  Esc: close"}
+	visible := boxH - 3
+	sel := clamp(p.playlistSel, 0, max(len(entries)-1, 0))
+	start := clamp(sel-visible/2, 0, max(len(entries)-visible, 0))
+	for i := start; i < len(entries) && i < start+visible; i++ {
+		e := entries[i]
+		mark := "  "
+		switch {
+		case i == sel:
+			mark = "> "
+		case e.Current:
+			mark = "▶ "
+		}
+		dur := ""
+		if e.Duration > 0 {
+			dur = formatDuration(e.Duration)
+		}
+		// Box borders and the mark take 4 cells, the duration column 9
+		nameW := boxW - 4 - 9
+		name := renderer.TruncateLeft(e.Path, nameW)
+		pad := max(nameW-renderer.TextWidth(name), 0)
+		lines = append(lines, fmt.Sprintf("%s%s%*s %8s", mark, name, pad, "", dur))
+	}
+
+	style := tcell.StyleDefault.Background(tcell.ColorBlack).Foreground(tcell.ColorSilver)
+	x, y := (w-boxW)/2, 1
+	p.render.DrawBox(x, y, boxW, boxH, fmt.Sprintf("Playlist (%d)", len(entries)), lines, style)
+
+	// Highlight the playing entry
+	for row, i := 0, start; i < len(entries) && i < start+visible; row, i = row+1, i+1 {
+		if entries[i].Current {
+			hl := style.Foreground(tcell.ColorYellow).Bold(true)
+			p.render.DrawText(x+1, y+2+row, lines[row+1], hl)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package player
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/renderer"
+	"github.com/0bVdnt/PixlGo/internal/video"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Returned by New when the user cancelled probing
+var ErrCancelled = errors.New("cancelled")
+
+// Probes quicker than this never show the probing screen, so local files
+// open without a flash
+const probeScreenDelay = 250 * time.Millisecond
+
+var spinnerFrames = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+
+type probeResult struct {
+	decoder *video.Decoder
+	err     error
+	took    time.Duration
+}
+
+// Returns the context probing runs under, derived from parent (nil for
+// none): timeout 0 means video.DefaultProbeTimeout, negative means no
+// timeout
+func probeContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if parent == nil {
+		parent = context.Background()
+	}
+	if timeout == 0 {
+		timeout = video.DefaultProbeTimeout
+	}
+	if timeout < 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
+// Waits for probing to finish. If it takes a while, shows a spinner and
+// lets Esc, q or Ctrl-C cancel it, in which case the result is ErrCancelled.
+func waitProbe(render *renderer.Renderer, path string, done <-chan probeResult, cancel context.CancelFunc) probeResult {
+	select {
+	case res := <-done:
+		return res
+	case <-time.After(probeScreenDelay):
+	}
+
+	screen := render.Screen()
+	var cancelled atomic.Bool
+	polled := make(chan struct{})
+	go func() {
+		defer close(polled)
+		for {
+			switch ev := screen.PollEvent().(type) {
+			case nil, *tcell.EventInterrupt:
+				return
+			case *tcell.EventKey:
+				if ev.Key() == tcell.KeyEscape || ev.Key() == tcell.KeyCtrlC ||
+					ev.Key() == tcell.KeyRune && (ev.Rune() == 'q' || ev.Rune() == 'Q') {
+					cancelled.Store(true)
+					cancel()
+				}
+			case *tcell.EventResize:
+				render.Sync()
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	name := filepath.Base(path)
+	var res probeResult
+	for frame := 0; ; frame++ {
+		msg := fmt.Sprintf("%c Probing %s… (Esc to cancel)", spinnerFrames[frame%len(spinnerFrames)], name)
+		if cancelled.Load() {
+			msg = "Cancelling…"
+		}
+		render.Clear()
+		render.RenderMessage(msg, tcell.ColorDarkBlue)
+		render.Show()
+
+		select {
+		case res = <-done:
+		case <-ticker.C:
+			continue
+		}
+		break
+	}
+
+	screen.PostEvent(tcell.NewEventInterrupt(nil))
+	<-polled
+	render.Clear()
+	if cancelled.Load() {
+		if res.decoder != nil {
+			res.decoder.Close()
+		}
+		return probeResult{err: ErrCancelled, took: res.took}
+	}
+	return res
+}
@@ -0,0 +1,58 @@
+package player
+
+import (
+	"unicode/utf8"
+
+	"github.com/0bVdnt/PixlGo/internal/renderer"
+	"github.com/gdamore/tcell/v2"
+)
+
+// One-line text input shown in place of the status bar. Playback keeps
+// running while it is open.
+type textPrompt struct {
+	label  string
+	text   []rune
+	submit func(string)
+}
+
+// Opens a prompt; submit runs with the entered text on Enter, and not at
+// all if the user cancels with Esc
+func (p *Player) openPrompt(label string, submit func(string)) {
+	p.prompt = &textPrompt{label: label, submit: submit}
+}
+
+// Handles typing while the prompt is open
+func (p *Player) handlePromptKey(ev *tcell.EventKey) EventResult {
+	tp := p.prompt
+	switch ev.Key() {
+	case tcell.KeyEnter:
+		p.prompt = nil
+		tp.submit(string(tp.text))
+	case tcell.KeyEscape, tcell.KeyCtrlC:
+		p.prompt = nil
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(tp.text) > 0 {
+			tp.text = tp.text[:len(tp.text)-1]
+		}
+	case tcell.KeyCtrlU:
+		tp.text = tp.text[:0]
+	case tcell.KeyRune:
+		tp.text = append(tp.text, ev.Rune())
+	}
+	return EventContinue
+}
+
+// Draws the prompt over the status bar, scrolled so the end of the text and
+// the cursor stay visible
+func (p *Player) renderPrompt(w, y int) {
+	style := tcell.StyleDefault.Background(tcell.ColorDarkGreen).Foreground(tcell.ColorWhite)
+	p.render.FillLine(y, style)
+
+	text := string(p.prompt.text) + "█"
+	room := w - 1 - renderer.TextWidth(p.prompt.label)
+	for renderer.TextWidth(text) > room && text != "" {
+		_, size := utf8.DecodeRuneInString(text)
+		text = text[size:]
+	}
+	p.render.DrawText(0, y, renderer.Truncate(" "+p.prompt.label+text, w), style)
+}
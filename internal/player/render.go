@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/0bVdnt/PixlGo/internal/metrics"
+	"github.com/0bVdnt/PixlGo/internal/renderer"
+	"github.com/0bVdnt/PixlGo/internal/video"
 	"github.com/gdamore/tcell/v2"
 )
 
@@ -12,22 +15,32 @@ func (p *Player) Render() {
 		return
 	}
 
+	st := p.Status()
+	state, errorMsg, frameW, frameH := st.State, st.Error, st.FrameW, st.FrameH
+
 	p.mu.RLock()
-	state := p.state.State
 	lastFrame := p.state.LastFrame
-	errorMsg := p.state.ErrorMsg
 	screenW, screenH := p.state.ScreenW, p.state.ScreenH
-	frameW, frameH := p.state.FrameW, p.state.FrameH
-	currentTime := p.state.CurrentTime
+	tooSmall := p.state.TooSmall()
 	p.mu.RUnlock()
+	if p.meta.Still {
+		lastFrame = p.stillFrame(frameW, frameH)
+	}
+
+	if tooSmall {
+		p.renderTooSmall()
+		return
+	}
 
 	stateChanged := state != p.prevState
 	if stateChanged {
+		p.updateTitle(state)
 		p.render.RequestClear()
 		p.render.InvalidateCache()
 		p.prevState = state
 	}
 
+	cues := p.activeCues(st.Position)
 	if p.render.NeedsClear() {
 		p.render.ClearVideoArea()
 	}
@@ -42,7 +55,11 @@ func (p *Player) Render() {
 	default:
 		if lastFrame != nil {
 			cellH := frameH / 2
-			offsetX := (screenW - frameW) / 2
+			cellW := frameW
+			if p.render.HalfWidth() {
+				cellW *= 2
+			}
+			offsetX := (screenW - cellW) / 2
 			offsetY := (screenH - cellH - 3) / 2
 			if offsetX < 0 {
 				offsetX = 0
@@ -51,35 +68,109 @@ func (p *Player) Render() {
 				offsetY = 0
 			}
 
-			p.render.RenderImage(lastFrame.Image, offsetX, offsetY)
+			img := p.applyLevels(lastFrame.Image)
+			img = p.overlayTimestamp(img, lastFrame.Timestamp)
+			p.render.RenderImage(img, offsetX, offsetY)
 		} else {
 			p.render.RenderMessage("Waiting...", tcell.ColorDarkBlue)
 		}
 	}
 
-	p.renderUI(screenW, screenH, frameW, frameH, currentTime, state)
+	if len(cues) > 0 {
+		p.renderCues(cues, screenW, screenH)
+	}
+
+	if p.logView {
+		p.renderLogView(screenW, screenH)
+	} else if p.markerView {
+		p.renderMarkerView(screenW, screenH)
+	} else if p.playlistView {
+		p.renderPlaylistView(screenW, screenH)
+	} else if p.statsView {
+		p.renderStatsView(screenW, screenH)
+	}
+
+	p.renderUI(screenW, screenH, st)
+
+	renderStart := time.Now()
 	p.render.Show()
+
+	if stall := p.render.TakeStall(); stall > 0 {
+		p.logger.Warnf("Terminal output stalled for %v", stall)
+		p.ShowOSD(fmt.Sprintf("Output was suspended %.1fs (Ctrl-S? Ctrl-Q resumes)", stall.Seconds()))
+	}
+
+	if state == StatePlaying && lastFrame != nil {
+		p.countRendered(lastFrame)
+		if p.metrics != nil {
+			p.recordMetrics(lastFrame, renderStart)
+		}
+	}
 }
 
-func (p *Player) renderUI(w, h, frameW, frameH int, currentTime time.Duration, state State) {
-	if w < 10 || h < 5 {
+// Records one metrics row per newly displayed frame
+func (p *Player) recordMetrics(frame *video.Frame, renderStart time.Time) {
+	if frame.Stored.Equal(p.metricsStored) {
 		return
 	}
+	p.metricsStored = frame.Stored
 
-	p.mu.RLock()
-	duration := p.meta.Duration
-	codec := p.meta.Codec
 	dropped := p.buffer.DroppedFrames()
-	p.mu.RUnlock()
+	delta := dropped
+	if dropped >= p.metricsDrops {
+		delta = dropped - p.metricsDrops
+	}
+	p.metricsDrops = dropped
+
+	row := metrics.Row{
+		Wall:          renderStart,
+		Timestamp:     frame.Timestamp,
+		StoreToRender: renderStart.Sub(frame.Stored),
+		Show:          time.Since(renderStart),
+		DroppedDelta:  delta,
+	}
+	if !frame.Decoded.IsZero() {
+		row.DecodeToStore = frame.Stored.Sub(frame.Decoded)
+	}
+	p.metrics.Record(row)
+}
+
+// Replaces the whole screen with a size hint; redrawn in full each tick
+func (p *Player) renderTooSmall() {
+	p.render.Clear()
+	p.render.RenderMessage(fmt.Sprintf("Terminal too small (%dx%d required)", MinScreenW, MinScreenH),
+		tcell.ColorDarkRed)
+	p.render.Show()
+	p.prevState = -1
+}
+
+// Draws the progress and status bars from one Status snapshot
+func (p *Player) renderUI(w, h int, st Status) {
+	if w < 10 || h < 5 {
+		return
+	}
+
+	state, currentTime, duration := st.State, st.Position, st.Duration
+	codec, dropped := st.Codec, st.Dropped
 
 	// Progress bar
 	barY := h - 2
 	bgStyle := tcell.StyleDefault.Background(tcell.ColorBlack)
 	p.render.FillLine(barY, bgStyle)
 
-	if duration > 0 {
-		progress := float64(currentTime) / float64(duration)
-		p.render.ProgressBar(barY, progress, tcell.ColorGreen, tcell.ColorDarkGray)
+	// With -start/-end the bar and the end time cover only the slice
+	known := st.DurationKnown
+	if st.SegmentEnd > 0 {
+		duration, known = st.SegmentEnd, true
+	}
+	if known && duration > st.SegmentStart && !st.Still {
+		span := float64(duration - st.SegmentStart)
+		progress := float64(currentTime-st.SegmentStart) / span
+		buffered := progress + float64(st.BufferAhead)/span
+		p.render.BufferedProgressBar(barY, progress, buffered, tcell.ColorGreen, tcell.ColorDarkGreen, tcell.ColorDarkGray)
+		if len(p.meta.Chapters) > 0 {
+			p.render.ProgressTicks(barY, p.chapterTicks(st.SegmentStart, duration), tcell.ColorYellow)
+		}
 	}
 
 	// Status bar
@@ -99,22 +190,118 @@ func (p *Player) renderUI(w, h, frameW, frameH int, currentTime time.Duration, s
 		droppedStr = fmt.Sprintf(" D:%d", dropped)
 	}
 
-	status := fmt.Sprintf(" %s %s/%s │ %s │ %dx%d%s | Q: quit SPC:pause <-/->: seek",
+	limitsStr := ""
+	if p.threads > 0 {
+		limitsStr += fmt.Sprintf(" T:%d", p.threads)
+	}
+	if p.maxCPU > 0 {
+		limitsStr += fmt.Sprintf(" CPU:%d%%", p.maxCPU)
+		if st.RenderMode == "interlaced" {
+			limitsStr += " IL"
+		}
+	}
+	if p.render.HalfWidth() {
+		limitsStr += " HW"
+	}
+	if st.Audio && len(p.meta.StreamsOf("audio")) > 1 {
+		limitsStr += fmt.Sprintf(" A:%d(%s)", st.AudioTrack, p.trackLabel("audio", st.AudioTrack))
+	}
+	if st.SubtitleTrack >= 0 {
+		limitsStr += fmt.Sprintf(" S:%d(%s)", st.SubtitleTrack, p.trackLabel("subtitle", st.SubtitleTrack))
+	}
+	if st.PlaylistLen > 1 {
+		limitsStr += fmt.Sprintf(" │ %d/%d", st.PlaylistIndex+1, st.PlaylistLen)
+	}
+
+	// Time fields always stay visible; the rest is ellipsized to fit.
+	// Durations from the stream rather than the container get a ~.
+	total := "/--:--"
+	switch {
+	case known:
+		total = "/" + formatDuration(duration)
+		if st.DurationEstimated && st.SegmentEnd == 0 {
+			total = "/~" + formatDuration(duration)
+		}
+	case st.Live:
+		total = " ● LIVE"
+	}
+	head := fmt.Sprintf(" %s %s%s",
 		state.Icon(),
 		formatDuration(currentTime),
-		formatDuration(duration),
+		total,
+	)
+	hint := " | Q: quit SPC:pause <-/->: seek"
+	if st.Still {
+		// No timeline; the picture's own size instead
+		head = fmt.Sprintf(" %s %dx%d", state.Icon(), st.Width, st.Height)
+		hint = " | Q: quit"
+	}
+	if st.Title != "" {
+		codec = st.Title + " │ " + codec
+	}
+	if st.Chapter != "" {
+		codec = st.Chapter + " │ " + codec
+	}
+	if st.AudioCodec != "" {
+		codec += fmt.Sprintf(" │ %s %s", st.AudioCodec, channelName(st.AudioChannels))
+	}
+	tail := fmt.Sprintf(" │ %s │ %dx%d%s%s%s",
 		codec,
-		frameW, frameH,
+		st.FrameW, st.FrameH,
 		droppedStr,
+		limitsStr,
+		hint,
 	)
+	if st.OSD != "" {
+		tail = " │ " + st.OSD
+	}
+
+	// The VU meter takes the right end while the audio tap delivers
+	levels, metering := p.levels.Current()
+	meterW := 0
+	if metering {
+		meterW = renderer.VUMeterWidth(levels.Channels, vuBarWidth) + 1
+		if renderer.TextWidth(head)+meterW > w {
+			metering, meterW = false, 0
+		}
+	}
 
-	if len(status) > w {
-		status = status[:w]
+	status := renderer.Truncate(head, w-meterW)
+	if rest := w - meterW - renderer.TextWidth(status); rest > 0 {
+		status += renderer.Truncate(tail, rest)
 	}
 
 	p.render.DrawText(0, statusY, status, statusStyle)
+	if metering {
+		n := levels.Channels
+		p.render.VUMeter(w-meterW+1, statusY, vuBarWidth, levels.Level[:n], levels.Peak[:n], tcell.ColorDarkBlue)
+	}
+
+	if p.prompt != nil {
+		p.renderPrompt(w, statusY)
+	}
 }
 
+// Names a channel count the way players show it: mono, stereo, 5.1, ...
+func channelName(n int) string {
+	switch n {
+	case 0:
+		return "?ch"
+	case 1:
+		return "mono"
+	case 2:
+		return "stereo"
+	case 6:
+		return "5.1"
+	case 8:
+		return "7.1"
+	}
+	return fmt.Sprintf("%dch", n)
+}
+
+// Cells per VU meter bar
+const vuBarWidth = 6
+
 func formatDuration(d time.Duration) string {
 	if d < 0 {
 		d = 0
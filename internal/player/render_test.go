@@ -0,0 +1,60 @@
+package player
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatusBarGolden(t *testing.T) {
+	playing := Status{
+		State: StatePlaying, Position: 65 * time.Second, Duration: 12 * time.Minute, DurationKnown: true,
+		Codec: "h264", FrameW: 78, FrameH: 44, SubtitleTrack: -1,
+	}
+	tests := []struct {
+		name   string
+		width  int
+		status func(Status) Status
+	}{
+		{"status_playing", 80, func(st Status) Status { return st }},
+		{"status_paused_drops", 80, func(st Status) Status {
+			st.State, st.Dropped, st.Title = StatePaused, 12, "Sample"
+			st.PlaylistIndex, st.PlaylistLen = 1, 3
+			return st
+		}},
+		// The time fields survive any truncation of the rest
+		{"status_narrow", 24, func(st Status) Status { return st }},
+		{"status_estimated", 80, func(st Status) Status {
+			st.DurationEstimated, st.AudioCodec, st.AudioChannels = true, "aac", 6
+			return st
+		}},
+		// No bar and no percentages, just a placeholder total
+		{"status_unknown_duration", 80, func(st Status) Status {
+			st.DurationKnown, st.Duration = false, 0
+			return st
+		}},
+		{"status_live", 80, func(st Status) Status {
+			st.DurationKnown, st.Duration, st.Live = false, 0, true
+			return st
+		}},
+		{"status_still", 80, func(st Status) Status {
+			st.Still, st.Width, st.Height = true, 640, 360
+			return st
+		}},
+		{"status_osd", 80, func(st Status) Status {
+			st.OSD = "Volume 80%"
+			return st
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, screen := newTestPlayer(t, "clip.json", nil, Config{})
+			screen.SetSize(tt.width, 25)
+			p.renderUI(tt.width, 25, tt.status(playing))
+
+			// The progress and status bars, characters only
+			rows := strings.SplitAfter(p.render.Snapshot().String(), "\n")
+			checkGolden(t, tt.name, strings.Join(rows[23:25], ""))
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package player
+
+import (
+	"fmt"
+	"time"
+)
+
+// Returns where playback ends: the segment end, else the file's duration,
+// or 0 if unknown. Caller holds p.mu.
+func (p *Player) segmentEnd() time.Duration {
+	if p.segEnd > 0 {
+		return p.segEnd
+	}
+	if p.durationKnown {
+		return p.meta.Duration
+	}
+	return 0
+}
+
+// Ends playback once the stream passed the segment end, stopping the
+// decoder so it doesn't run on beyond it. Caller holds p.mu.
+func (p *Player) pastSegment() bool {
+	if p.segEnd == 0 || p.state.CurrentTime < p.segEnd {
+		return false
+	}
+	p.decoder.Stop()
+	p.state.State = StateEnded
+	return true
+}
+
+// Moves the start position into the segment and warns when the segment
+// lies beyond the probed duration. Called once from New.
+func (p *Player) checkSegment() {
+	if p.segStart == 0 && p.segEnd == 0 {
+		return
+	}
+	if p.startPos < p.segStart || p.segEnd > 0 && p.startPos >= p.segEnd {
+		p.startPos = p.segStart
+	}
+
+	if !p.durationKnown {
+		return
+	}
+	var warning string
+	switch duration := p.meta.Duration; {
+	case p.segStart >= duration:
+		warning = fmt.Sprintf("-start %s is past the end of the video (%s)", formatDuration(p.segStart), formatDuration(duration))
+	case p.segEnd > duration:
+		warning = fmt.Sprintf("-end %s is past the end of the video (%s)", formatDuration(p.segEnd), formatDuration(duration))
+	}
+	if warning != "" {
+		p.logger.Warn("segment beyond duration", "start", p.segStart, "end", p.segEnd, "duration", p.meta.Duration)
+		p.ShowOSD(warning)
+	}
+}
@@ -0,0 +1,40 @@
+package player
+
+import "strings"
+
+// Per-file playback settings the user can change while playing, remembered
+// between runs by the caller
+type Settings struct {
+	AutoLevels bool
+}
+
+// Short description of the non-default settings, e.g. "auto levels"
+func (s Settings) Summary() string {
+	var parts []string
+	if s.AutoLevels {
+		parts = append(parts, "auto levels")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Returns the current settings. Only valid from the main loop or after Run
+// returned.
+func (p *Player) Settings() Settings {
+	return Settings{AutoLevels: p.autoLevels}
+}
+
+// Applies restored settings and tells the user what changed from the
+// defaults. Called from Run before the first frame.
+func (p *Player) restoreSettings(s Settings) {
+	p.autoLevels = s.AutoLevels
+	if summary := s.Summary(); summary != "" {
+		p.ShowOSD("Restored: " + summary)
+	}
+}
+
+// Reports a settings change to Config.OnSettingsChange
+func (p *Player) settingsChanged() {
+	if p.onSettingsChange != nil {
+		p.onSettingsChange(p.Settings())
+	}
+}
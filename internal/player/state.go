@@ -15,6 +15,8 @@ const (
 	StatePaused
 	StateError
 	StateEnded
+	// A live stream dropped; waiting to reconnect
+	StateReconnecting
 )
 
 func (s State) String() string {
@@ -31,6 +33,8 @@ func (s State) String() string {
 		return "error"
 	case StateEnded:
 		return "ended"
+	case StateReconnecting:
+		return "reconnecting"
 	default:
 		return "unknown"
 	}
@@ -48,11 +52,19 @@ func (s State) Icon() string {
 		return "ⓘ"
 	case StateEnded:
 		return "■"
+	case StateReconnecting:
+		return "↻"
 	default:
 		return "○"
 	}
 }
 
+// Smallest terminal the player draws video in
+const (
+	MinScreenW = 20
+	MinScreenH = 6
+)
+
 type PlayerState struct {
 	State        State
 	CurrentTime  time.Duration
@@ -60,10 +72,54 @@ type PlayerState struct {
 	LastFrame    *video.Frame
 	LoadingStart time.Time
 
+	// Target of a skip ahead in the running stream that no frame has
+	// reached yet; zero when none is pending
+	SkipTarget time.Duration
+
+	// Short on-screen message shown in the status bar until OSDUntil
+	OSD      string
+	OSDUntil time.Time
+
 	ScreenW int
 	ScreenH int
 	FrameW  int
 	FrameH  int
+
+	// Set when playback was stopped because the terminal got too small
+	ResumeOnGrow bool
+
+	// Set while waiting at the end of a followed file for more data
+	Following bool
+
+	// Cap on FrameW*FrameH in pixels, zero for none; Capped is set while
+	// it shrinks the frame
+	MaxArea int
+	Capped  bool
+
+	// Decode at half the width and draw each pixel column as two cells
+	HalfWidth bool
+
+	// Audio volume in percent, kept while muted so unmuting restores it
+	Volume int
+	Muted  bool
+}
+
+// Moves CurrentTime to the timestamp of a decoded frame. While a skip is
+// pending, frames from before its target don't move it back, so a further
+// seek builds on the target rather than on the stale position.
+func (ps *PlayerState) setPosition(t time.Duration) {
+	if ps.SkipTarget > 0 {
+		if t < ps.SkipTarget {
+			return
+		}
+		ps.SkipTarget = 0
+	}
+	ps.CurrentTime = t
+}
+
+// Reports whether the terminal is below the minimum usable size
+func (ps *PlayerState) TooSmall() bool {
+	return ps.ScreenW < MinScreenW || ps.ScreenH < MinScreenH
 }
 
 func NewPlayerState(screenW, screenH int, meta video.Metadata) *PlayerState {
@@ -85,8 +141,7 @@ func CalculateFrameDimensions(screenW, screenH int, meta video.Metadata) (int, i
 	frameW := screenW
 	frameH := availH * 2
 
-	if meta.Width > 0 && meta.Height > 0 {
-		aspect := float64(meta.Width) / float64(meta.Height)
+	if aspect := meta.DisplayAspect(); aspect > 0 {
 		frameAspect := float64(frameW) / float64(frameH)
 
 		if frameAspect > aspect {
@@ -102,17 +157,39 @@ func CalculateFrameDimensions(screenW, screenH int, meta video.Metadata) (int, i
 	return frameW, frameH
 }
 
+// Recomputes FrameW/FrameH from the current screen size and returns them.
+// Callers starting decodes use this instead of snapshotting FrameW/FrameH so
+// the stream always matches the latest known screen size.
+func (ps *PlayerState) CurrentFrameSize(meta video.Metadata) (int, int) {
+	ps.setFrameSize(meta)
+	return ps.FrameW, ps.FrameH
+}
+
+// Sets FrameW/FrameH for the screen size, within video.MaxDimension and
+// MaxArea. Both caps scale proportionally, so the aspect ratio survives.
+func (ps *PlayerState) setFrameSize(meta video.Metadata) {
+	w, h := CalculateFrameDimensions(ps.ScreenW, ps.ScreenH, meta)
+	if ps.HalfWidth {
+		w = max(w/4*2, 2)
+	}
+	w, h, _ = video.LimitSize(w, h, video.MaxDimension())
+	ps.FrameW, ps.FrameH, ps.Capped = video.LimitArea(w, h, ps.MaxArea)
+}
+
 func (ps *PlayerState) UpdateDimensions(screenW, screenH int, meta video.Metadata) bool {
 	oldFrameW, oldFrameH := ps.FrameW, ps.FrameH
 
 	ps.ScreenW = screenW
 	ps.ScreenH = screenH
-	ps.FrameW, ps.FrameH = CalculateFrameDimensions(screenW, screenH, meta)
+	ps.setFrameSize(meta)
 
 	return ps.FrameW != oldFrameW || ps.FrameH != oldFrameH
 }
 
 func clamp(v, min, max int) int {
+	if max < min {
+		max = min
+	}
 	if v < min {
 		return min
 	}
@@ -0,0 +1,124 @@
+package player
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/video"
+)
+
+func TestSetPositionPendingSkip(t *testing.T) {
+	ps := &PlayerState{CurrentTime: 10 * time.Second, SkipTarget: 20 * time.Second}
+
+	// Frames decoded before the skip target don't move the position back
+	ps.setPosition(10*time.Second + 40*time.Millisecond)
+	if ps.CurrentTime != 10*time.Second || ps.SkipTarget != 20*time.Second {
+		t.Fatalf("stale frame moved position: time %v, target %v", ps.CurrentTime, ps.SkipTarget)
+	}
+
+	ps.setPosition(20 * time.Second)
+	if ps.CurrentTime != 20*time.Second || ps.SkipTarget != 0 {
+		t.Fatalf("frame at target: time %v, target %v", ps.CurrentTime, ps.SkipTarget)
+	}
+
+	// Without a pending skip every frame counts
+	ps.setPosition(5 * time.Second)
+	if ps.CurrentTime != 5*time.Second {
+		t.Fatalf("time %v, want 5s", ps.CurrentTime)
+	}
+}
+
+func TestTinyScreens(t *testing.T) {
+	meta := video.Metadata{Width: 1920, Height: 1080}
+	tests := []struct {
+		w, h           int
+		tooSmall       bool
+		frameW, frameH int
+	}{
+		// Below the minimum nothing is decoded; the size only has to stay
+		// sane for a stream that is never started
+		{1, 1, true, 4, 4},
+		{5, 2, true, 4, 4},
+		{19, 5, true, 6, 4},
+		{20, 5, true, 6, 4},
+		{19, 6, true, 10, 6},
+		{20, 6, false, 10, 6},
+		{80, 25, false, 78, 44},
+	}
+	for _, tt := range tests {
+		ps := NewPlayerState(tt.w, tt.h, meta)
+		if ps.TooSmall() != tt.tooSmall {
+			t.Errorf("%dx%d: TooSmall = %v, want %v", tt.w, tt.h, ps.TooSmall(), tt.tooSmall)
+		}
+		fw, fh := CalculateFrameDimensions(tt.w, tt.h, meta)
+		if fw != tt.frameW || fh != tt.frameH {
+			t.Errorf("%dx%d: frame %dx%d, want %dx%d", tt.w, tt.h, fw, fh, tt.frameW, tt.frameH)
+		}
+		if !tt.tooSmall && (fw > tt.w || fh > 2*(tt.h-3)) {
+			t.Errorf("%dx%d: frame %dx%d doesn't fit", tt.w, tt.h, fw, fh)
+		}
+	}
+}
+
+// Anamorphic video is fitted to its display aspect, not its storage size
+func TestCalculateFrameDimensionsSAR(t *testing.T) {
+	tests := []struct {
+		name           string
+		w, h           int
+		dispW, dispH   int
+		frameW, frameH int
+	}{
+		// 16:9 on the 78x44 frame an 80x25 terminal leaves, not 5:4 (55x44)
+		{"PAL 16:9", 720, 576, 1024, 576, 78, 44},
+		{"PAL 4:3", 720, 576, 768, 576, 58, 44},
+		{"NTSC 16:9", 720, 480, 853, 480, 78, 44},
+		{"NTSC 4:3", 720, 480, 640, 480, 58, 44},
+		{"HDV", 1440, 1080, 1920, 1080, 78, 44},
+		{"square pixels", 720, 576, 0, 0, 54, 44},
+	}
+	for _, tt := range tests {
+		meta := video.Metadata{Width: tt.w, Height: tt.h, DisplayWidth: tt.dispW, DisplayHeight: tt.dispH}
+		fw, fh := CalculateFrameDimensions(80, 25, meta)
+		if fw != tt.frameW || fh != tt.frameH {
+			t.Errorf("%s: frame %dx%d, want %dx%d", tt.name, fw, fh, tt.frameW, tt.frameH)
+		}
+	}
+}
+
+// Extreme terminal shapes still get a frame of the video's shape, inside
+// the screen and the dimension cap, whichever side the cap lands on
+func TestExtremeGeometries(t *testing.T) {
+	metas := []video.Metadata{
+		{Width: 1920, Height: 1080},
+		{Width: 640, Height: 480},
+		{Width: 1080, Height: 1920},
+		{Width: 2390, Height: 1000},
+	}
+	screens := []struct{ w, h int }{{6000, 40}, {40, 400}, {6000, 3000}, {5120, 1440}}
+	t.Cleanup(func() { video.SetMaxDimension(0) })
+
+	for _, maxDim := range []int{video.DefaultMaxDimension, 1024} {
+		video.SetMaxDimension(maxDim)
+		for _, meta := range metas {
+			aspect := meta.DisplayAspect()
+			for _, sc := range screens {
+				ps := NewPlayerState(sc.w, sc.h, meta)
+				fw, fh := ps.CurrentFrameSize(meta)
+				name := fmt.Sprintf("cap %d, %dx%d video on %dx%d", maxDim, meta.Width, meta.Height, sc.w, sc.h)
+				if fw != ps.FrameW || fh != ps.FrameH {
+					t.Errorf("%s: returned %dx%d, state has %dx%d", name, fw, fh, ps.FrameW, ps.FrameH)
+				}
+				if fw > sc.w || fh > 2*(sc.h-3) || fw > maxDim || fh > maxDim {
+					t.Errorf("%s: frame %dx%d doesn't fit", name, fw, fh)
+				}
+				// Even rounding at each step may cost a few pixels on the
+				// side derived from the other, no more
+				if math.Abs(float64(fh)-float64(fw)/aspect) > 4 && math.Abs(float64(fw)-float64(fh)*aspect) > 4 {
+					t.Errorf("%s: frame %dx%d is %.3f:1, want %.3f:1", name, fw, fh, float64(fw)/float64(fh), aspect)
+				}
+			}
+		}
+	}
+}
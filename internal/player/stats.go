@@ -0,0 +1,43 @@
+package player
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/video"
+)
+
+// Process-wide playback counters for metrics endpoints, kept across files.
+// Updated with atomic adds, so they cost next to nothing when unread.
+var Stats struct {
+	FramesRendered atomic.Uint64 // distinct frames drawn
+	Restarts       atomic.Uint64 // decode restarts for seeks, resizes and resumes
+	Errors         atomic.Uint64 // playbacks that entered the error state
+
+	fps atomic.Uint64 // math.Float64bits of the last measured display rate
+}
+
+// Returns the display rate measured over the last second
+func FPS() float64 {
+	return math.Float64frombits(Stats.fps.Load())
+}
+
+// Counts frame as rendered if it wasn't drawn before. Called from Render.
+func (p *Player) countRendered(frame *video.Frame) {
+	if frame.Stored.Equal(p.statsStored) {
+		return
+	}
+	p.statsStored = frame.Stored
+	Stats.FramesRendered.Add(1)
+
+	now := time.Now()
+	p.fpsFrames++
+	if p.fpsSince.IsZero() {
+		p.fpsSince = now
+	} else if elapsed := now.Sub(p.fpsSince); elapsed >= time.Second {
+		Stats.fps.Store(math.Float64bits(float64(p.fpsFrames) / elapsed.Seconds()))
+		p.fpsFrames = 0
+		p.fpsSince = now
+	}
+}
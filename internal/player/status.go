@@ -0,0 +1,150 @@
+package player
+
+import (
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/video"
+)
+
+// Point-in-time view of the player for status endpoints and the status
+// bar, captured under one lock so the fields agree with each other
+type Status struct {
+	File          string
+	Title         string // title tag, else the file's base name
+	State         State
+	Error         string
+	Position      time.Duration
+	Duration      time.Duration
+	DurationKnown bool
+
+	// Duration was derived from the stream rather than the container
+	DurationEstimated bool
+	// A live stream: no duration and no seeking
+	Live bool
+	// A still image, shown until the user moves on
+	Still bool
+
+	Codec  string
+	Width  int
+	Height int
+	FPS    float64
+
+	// The file's first audio stream; AudioCodec is empty without one
+	AudioCodec    string
+	AudioChannels int
+
+	// Size of the rendered frame in pixels (cells x half-cells)
+	FrameW int
+	FrameH int
+
+	Frames  uint64
+	Dropped uint64
+
+	// Decoded frames waiting to be shown; the buffer holds at most one
+	Buffered int
+	// Media time the decoder had ready beyond the shown frame; 0 while no
+	// stream runs
+	BufferAhead time.Duration
+
+	// How frames are drawn: half-block, interlaced or heatmap
+	RenderMode string
+
+	// Position of this file in the playlist, from 0, and the playlist's
+	// length; both 0 without a playlist
+	PlaylistIndex int
+	PlaylistLen   int
+
+	// Audio volume in percent; Audio is false when nothing plays sound
+	Audio  bool
+	Volume int
+	Muted  bool
+
+	// Audio and burned-in subtitle tracks, counted among the streams of
+	// their type; SubtitleTrack is -1 while none shows
+	AudioTrack    int
+	SubtitleTrack int
+
+	// Title of the chapter playing; empty for files without chapters
+	Chapter string
+
+	// The slice being played; SegmentEnd 0 means the end of the file
+	SegmentStart time.Duration
+	SegmentEnd   time.Duration
+
+	// Transient status bar message, empty when none is showing
+	OSD string
+}
+
+// Returns the current status. Safe to call from any goroutine.
+func (p *Player) Status() Status {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	buffered := 0
+	if p.state.State == StatePlaying && p.buffer.Load() != nil &&
+		p.buffer.Timestamp() != p.state.CurrentTime {
+		buffered = 1
+	}
+	osd := p.state.OSD
+	if time.Now().After(p.state.OSDUntil) {
+		osd = ""
+	}
+	ahead, _ := p.decoder.BufferAhead()
+	var index, length int
+	if p.playlist != nil {
+		index, length = p.playlist.Position()
+	}
+	return Status{
+		File:          p.decoder.Path(),
+		Title:         p.meta.Title(),
+		State:         p.state.State,
+		Error:         p.state.ErrorMsg,
+		Position:      p.state.CurrentTime,
+		Duration:      p.meta.Duration,
+		DurationKnown: p.durationKnown,
+		Live:          p.meta.Live,
+		Still:         p.meta.Still,
+		Codec:         p.meta.Codec,
+		Width:         p.meta.Width,
+		Height:        p.meta.Height,
+		FPS:           p.meta.FPS,
+		AudioCodec:    p.meta.AudioCodec,
+		AudioChannels: p.meta.Channels,
+		FrameW:        p.state.FrameW,
+		FrameH:        p.state.FrameH,
+		Frames:        p.buffer.FrameCount(),
+		Dropped:       p.buffer.DroppedFrames(),
+		Buffered:      buffered,
+		BufferAhead:   ahead,
+		RenderMode:    p.renderMode(),
+		PlaylistIndex: index,
+		PlaylistLen:   length,
+		Audio:         p.audio,
+		Volume:        p.state.Volume,
+		Muted:         p.state.Muted,
+		AudioTrack:    p.audioTrack,
+		SubtitleTrack: p.subtitle,
+		Chapter:       p.currentChapter(),
+		SegmentStart:  p.segStart,
+		SegmentEnd:    p.segEnd,
+		OSD:           osd,
+
+		DurationEstimated: p.meta.DurationEstimated,
+	}
+}
+
+// Returns the name of the active render path
+func (p *Player) renderMode() string {
+	switch {
+	case p.heatmap:
+		return "heatmap"
+	case p.render.Interlaced():
+		return "interlaced"
+	}
+	return "half-block"
+}
+
+// Returns a copy of the frame on screen, or nil before the first one. Safe
+// to call from any goroutine; the decoder keeps running meanwhile.
+func (p *Player) Snapshot() *video.Frame {
+	return p.buffer.Snapshot()
+}
@@ -0,0 +1,95 @@
+package player
+
+import (
+	"image"
+
+	"github.com/0bVdnt/PixlGo/internal/video"
+)
+
+// Decodes a still image once, at its full display size; resizes rescale
+// that copy instead of running ffmpeg again. The image then stays up,
+// paused, until the user moves on.
+func (p *Player) loadStill() {
+	p.mu.Lock()
+	if p.stillImg != nil {
+		p.state.State = StatePaused
+		p.mu.Unlock()
+		return
+	}
+	if p.stillLoading {
+		p.mu.Unlock()
+		return
+	}
+	p.stillLoading = true
+	p.mu.Unlock()
+
+	p.extractions.Add(1)
+	go func() {
+		defer p.extractions.Done()
+		defer p.recoverPanic()
+
+		frame, err := p.decoder.ExtractFrame(p.ctx, 0, p.meta.DisplayWidth, p.meta.DisplayHeight)
+		p.mu.Lock()
+		p.stillLoading = false
+		p.mu.Unlock()
+		if p.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			p.SetError("Cannot decode image: " + err.Error())
+			return
+		}
+
+		b := frame.Image.Bounds()
+		p.logger.Info("image decoded", "width", b.Dx(), "height", b.Dy())
+		p.mu.Lock()
+		p.stillImg = frame.Image
+		p.state.State = StatePaused
+		p.mu.Unlock()
+	}()
+}
+
+// Returns the still image scaled to w x h, or nil before it is decoded. The
+// result becomes the last frame and is reused until the size changes.
+func (p *Player) stillFrame(w, h int) *video.Frame {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stillImg == nil || w <= 0 || h <= 0 {
+		return nil
+	}
+	if last := p.state.LastFrame; last != nil && last.Image.Rect.Dx() == w && last.Image.Rect.Dy() == h {
+		return last
+	}
+	p.state.LastFrame = &video.Frame{Image: scaleImage(p.stillImg, w, h)}
+	return p.state.LastFrame
+}
+
+// Scales src to w x h, averaging the source pixels each destination pixel
+// covers so detail shrinks smoothly instead of aliasing
+func scaleImage(src *image.RGBA, w, h int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		y0 := y * sh / h
+		y1 := max((y+1)*sh/h, y0+1)
+		for x := range w {
+			x0 := x * sw / w
+			x1 := max((x+1)*sw/w, x0+1)
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for c := range sum {
+						sum[c] += int(row[sx*4+c])
+					}
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			i := y*dst.Stride + x*4
+			for c := range sum {
+				dst.Pix[i+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return dst
+}
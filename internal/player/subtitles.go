@@ -0,0 +1,202 @@
+package player
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/renderer"
+	"github.com/0bVdnt/PixlGo/internal/subtitle"
+	"github.com/0bVdnt/PixlGo/internal/video"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Bounds the ffmpeg -filters run behind the first toggle
+const subtitleProbeTimeout = 5 * time.Second
+
+// Reports whether ffmpeg has the libass subtitles filter, checking on
+// first use
+func (p *Player) hasSubtitleFilter() bool {
+	if !p.subProbed {
+		ctx, cancel := context.WithTimeout(p.ctx, subtitleProbeTimeout)
+		defer cancel()
+		filters, err := video.FFmpegFilters(ctx)
+		p.subFilter = err == nil && filters["subtitles"]
+		p.subProbed = true
+	}
+	return p.subFilter
+}
+
+// Checks that track want can be burned in, returning why not otherwise
+func (p *Player) checkSubtitle(want int) string {
+	tracks := p.meta.StreamsOf("subtitle")
+	switch {
+	case len(tracks) == 0:
+		return "No subtitles"
+	case want >= len(tracks):
+		return fmt.Sprintf("No subtitle track %d (%d available)", want, len(tracks))
+	case !tracks[want].TextSubtitle():
+		return fmt.Sprintf("Subtitle track %d is %s; only text subtitles can be shown", want, tracks[want].Codec)
+	case !p.hasSubtitleFilter():
+		return "Subtitles need an ffmpeg built with libass"
+	}
+	return ""
+}
+
+// Shows or hides the -sub file's cues. Without one, turns burned-in
+// subtitles on or off and restarts the stream at the current position so
+// the change shows.
+func (p *Player) toggleSubtitles() {
+	if p.cues != nil {
+		p.cuesHidden = !p.cuesHidden
+		if p.cuesHidden {
+			p.ShowOSD("Subtitles off")
+			return
+		}
+		p.ShowOSD("Subtitles on")
+		return
+	}
+	if p.subtitle >= 0 {
+		p.setSubtitle(-1)
+		p.ShowOSD("Subtitles off")
+		return
+	}
+	if msg := p.checkSubtitle(p.subtitleTrack); msg != "" {
+		p.ShowOSD(msg)
+		return
+	}
+	p.setSubtitle(p.subtitleTrack)
+	p.ShowOSD(fmt.Sprintf("Subtitles: track %d (%s)", p.subtitleTrack, p.trackLabel("subtitle", p.subtitleTrack)))
+}
+
+func (p *Player) setSubtitle(si int) {
+	p.decoder.SetSubtitles(si)
+
+	p.mu.Lock()
+	p.subtitle = si
+	state := p.state.State
+	current := p.state.CurrentTime
+	p.mu.Unlock()
+	if state == StatePlaying || state == StateLoading {
+		p.StartPlayback(current)
+	}
+}
+
+// Cue rows stay above the progress bar with one row between them, and take
+// at most a third of the screen
+const (
+	cueGap     = 1
+	cueMaxRows = 3
+)
+
+// Picks the external subtitle cues for pos. A change of cues clears the
+// screen so the previous text doesn't linger where the diff cache skips
+// unchanged video cells.
+func (p *Player) activeCues(pos time.Duration) []*subtitle.Cue {
+	var cues []*subtitle.Cue
+	if !p.cuesHidden {
+		cues = p.cues.At(pos)
+	}
+	if !slices.Equal(cues, p.cuesShown) {
+		p.cuesShown = cues
+		p.render.RequestClear()
+		p.render.InvalidateCache()
+	}
+	return cues
+}
+
+// Draws cues centered above the progress bar, word-wrapped to the screen
+// width. Overlapping cues stack in start order.
+func (p *Player) renderCues(cues []*subtitle.Cue, w, h int) {
+	var rows [][]cueWord
+	for _, cue := range cues {
+		for _, line := range cue.Lines {
+			rows = append(rows, wrapCue(line, w-2)...)
+		}
+	}
+	maxRows := max(h/cueMaxRows, 1)
+	if len(rows) > maxRows {
+		rows = rows[len(rows)-maxRows:]
+	}
+
+	base := tcell.StyleDefault.Background(tcell.ColorBlack).Foreground(tcell.ColorWhite)
+	y := h - 2 - cueGap - len(rows)
+	for i, row := range rows {
+		x := (w - rowWidth(row)) / 2
+		for j, word := range row {
+			text := word.text
+			if j > 0 && word.space {
+				text = " " + text
+			}
+			style := base.Italic(word.italic).Bold(word.bold)
+			p.render.DrawText(x, y+i, text, style)
+			x += renderer.TextWidth(text)
+		}
+	}
+}
+
+// A word of cue text in one style; space is set when a blank precedes it
+type cueWord struct {
+	text         string
+	italic, bold bool
+	space        bool
+}
+
+// Splits a cue line into rows of at most width columns, breaking at blanks.
+// Words wider than a row are cut.
+func wrapCue(line []subtitle.Span, width int) [][]cueWord {
+	var words []cueWord
+	space := false
+	for _, span := range line {
+		text := span.Text
+		for text != "" {
+			if text[0] == ' ' {
+				space = true
+				text = text[1:]
+				continue
+			}
+			end := strings.IndexByte(text, ' ')
+			if end < 0 {
+				end = len(text)
+			}
+			words = append(words, cueWord{text: text[:end], italic: span.Italic, bold: span.Bold, space: space})
+			space = false
+			text = text[end:]
+		}
+	}
+
+	var rows [][]cueWord
+	var row []cueWord
+	rowW := 0
+	for _, word := range words {
+		word.text = renderer.Truncate(word.text, width)
+		ww := renderer.TextWidth(word.text)
+		if word.space && len(row) > 0 {
+			ww++
+		}
+		if len(row) > 0 && word.space && rowW+ww > width {
+			rows = append(rows, row)
+			row, rowW = nil, 0
+			ww = renderer.TextWidth(word.text)
+		}
+		row = append(row, word)
+		rowW += ww
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func rowWidth(row []cueWord) int {
+	w := 0
+	for i, word := range row {
+		if i > 0 && word.space {
+			w++
+		}
+		w += renderer.TextWidth(word.text)
+	}
+	return w
+}
@@ -0,0 +1,24 @@
+package player
+
+import "path/filepath"
+
+// Returns the window title for the current state: the file name, marked
+// while paused
+func (p *Player) windowTitle(state State) string {
+	name := filepath.Base(p.decoder.Path())
+	if state == StatePaused {
+		name += " (paused)"
+	}
+	return name + " — pixlgo"
+}
+
+// Sets the terminal title when it changed. Only called from the main loop.
+func (p *Player) updateTitle(state State) {
+	if !p.setTitle {
+		return
+	}
+	if title := p.windowTitle(state); title != p.title {
+		p.title = title
+		p.render.SetTitle(title)
+	}
+}
@@ -0,0 +1,42 @@
+package player
+
+import (
+	"fmt"
+
+	"github.com/0bVdnt/PixlGo/internal/video"
+)
+
+// Fails for audio or subtitle tracks the file doesn't have. Track 0 is the
+// default and may be missing; the file then plays without it.
+func checkTracks(meta video.Metadata, cfg Config) error {
+	if n := len(meta.StreamsOf("audio")); cfg.AudioTrack > 0 && cfg.AudioTrack >= n {
+		return fmt.Errorf("audio track %d does not exist: the file has %s", cfg.AudioTrack, trackCount(n, "audio track"))
+	}
+	if n := len(meta.StreamsOf("subtitle")); cfg.SubtitleTrack > 0 && cfg.SubtitleTrack >= n {
+		return fmt.Errorf("subtitle track %d does not exist: the file has %s", cfg.SubtitleTrack, trackCount(n, "subtitle track"))
+	}
+	return nil
+}
+
+func trackCount(n int, noun string) string {
+	switch n {
+	case 0:
+		return "no " + noun + "s"
+	case 1:
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// Describes track n of a kind for the status bar and OSD by its language,
+// or its codec when untagged
+func (p *Player) trackLabel(kind string, n int) string {
+	tracks := p.meta.StreamsOf(kind)
+	if n < 0 || n >= len(tracks) {
+		return "?"
+	}
+	if lang := tracks[n].Language; lang != "" && lang != "und" {
+		return lang
+	}
+	return tracks[n].Codec
+}
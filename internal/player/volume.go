@@ -0,0 +1,45 @@
+package player
+
+import (
+	"fmt"
+
+	"github.com/0bVdnt/PixlGo/internal/video"
+)
+
+// Volume change per key press, in percent
+const volumeStep = 5
+
+// Raises or lowers the volume by delta percent, unmuting
+func (p *Player) changeVolume(delta int) {
+	if !p.audio {
+		p.ShowOSD("No audio")
+		return
+	}
+	p.mu.Lock()
+	p.state.Volume = clamp(p.state.Volume+delta, 0, video.MaxVolume)
+	p.state.Muted = false
+	volume := p.state.Volume
+	p.mu.Unlock()
+
+	p.decoder.SetVolume(volume)
+	p.ShowOSD(fmt.Sprintf("Volume %d%%", volume))
+}
+
+func (p *Player) toggleMute() {
+	if !p.audio {
+		p.ShowOSD("No audio")
+		return
+	}
+	p.mu.Lock()
+	p.state.Muted = !p.state.Muted
+	muted, volume := p.state.Muted, p.state.Volume
+	p.mu.Unlock()
+
+	if muted {
+		p.decoder.SetVolume(0)
+		p.ShowOSD("Muted")
+		return
+	}
+	p.decoder.SetVolume(volume)
+	p.ShowOSD(fmt.Sprintf("Volume %d%%", volume))
+}
@@ -0,0 +1,180 @@
+// Package progress writes machine-readable playback progress as one JSON
+// record per line, for programs that wrap pixlgo in their own UI.
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/logger"
+	"github.com/0bVdnt/PixlGo/internal/player"
+)
+
+// Format version in every record. Fields may be added without a bump;
+// renaming or removing one bumps it.
+const Version = 1
+
+// Time between progress records
+const Interval = 500 * time.Millisecond
+
+// How long Finish waits for the final record to be written
+const finishTimeout = time.Second
+
+// A progress or exit record. Times are in seconds; Duration is left out
+// while unknown.
+type Record struct {
+	Version int    `json:"version"`
+	Event   string `json:"event"`
+	Time    string `json:"time"`
+
+	File     string   `json:"file,omitempty"`
+	State    string   `json:"state,omitempty"`
+	Position *float64 `json:"position,omitempty"`
+	Duration *float64 `json:"duration,omitempty"`
+	Speed    *float64 `json:"speed,omitempty"`
+	Dropped  *uint64  `json:"dropped,omitempty"`
+
+	// Exit records only: ended, quit, error or signal, and the exit status
+	Reason string `json:"reason,omitempty"`
+	Code   *int   `json:"code,omitempty"`
+}
+
+// Writes records to a file or descriptor from its own goroutine, so a
+// consumer that stops reading never stalls playback; records that find the
+// writer still busy are dropped
+type Reporter struct {
+	queue   chan []byte
+	done    chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+	log     *logger.Logger
+
+	finishOnce sync.Once
+}
+
+// Starts reporting the status of whichever player current returns (nil
+// between files) every Interval. open runs on the writer goroutine, so
+// opening a FIFO without a reader doesn't block the caller.
+func Start(open func() (io.WriteCloser, error), current func() *player.Player, log *logger.Logger) *Reporter {
+	if log == nil {
+		log = logger.Noop()
+	}
+	r := &Reporter{
+		queue:   make(chan []byte, 1),
+		done:    make(chan struct{}),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		log:     log,
+	}
+	go r.write(open)
+	go r.tick(current)
+	return r
+}
+
+func (r *Reporter) tick(current func() *player.Player) {
+	defer close(r.stopped)
+	ticker := time.NewTicker(Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if p := current(); p != nil {
+				r.send(statusRecord(p.Status()), false)
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+func statusRecord(st player.Status) Record {
+	pos := st.Position.Seconds()
+	speed := 0.0
+	if st.State == player.StatePlaying {
+		speed = 1
+	}
+	rec := Record{
+		Event:    "progress",
+		File:     st.File,
+		State:    st.State.String(),
+		Position: &pos,
+		Speed:    &speed,
+		Dropped:  &st.Dropped,
+	}
+	if st.DurationKnown {
+		d := st.Duration.Seconds()
+		rec.Duration = &d
+	}
+	return rec
+}
+
+// Queues rec; wait blocks until there is room instead of dropping it
+func (r *Reporter) send(rec Record, wait bool) bool {
+	rec.Version = Version
+	rec.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return false
+	}
+	line = append(line, '\n')
+	if !wait {
+		select {
+		case r.queue <- line:
+			return true
+		default:
+			return false
+		}
+	}
+	select {
+	case r.queue <- line:
+		return true
+	case <-time.After(finishTimeout):
+		return false
+	}
+}
+
+func (r *Reporter) write(open func() (io.WriteCloser, error)) {
+	defer close(r.done)
+	w, err := open()
+	if err != nil {
+		r.log.Warn("progress output unavailable", "err", err)
+		// Keep draining so senders never wait on a dead writer
+		for range r.queue {
+		}
+		return
+	}
+	defer w.Close()
+
+	failed := false
+	for line := range r.queue {
+		if failed {
+			continue
+		}
+		// One write per line; a reader sees whole records
+		if _, err := w.Write(line); err != nil {
+			r.log.Warn("progress output failed", "err", err)
+			failed = true
+		}
+	}
+}
+
+// Writes the final record with the exit reason and status and closes the
+// output, waiting up to a second for a slow reader. Does nothing on nil.
+func (r *Reporter) Finish(reason string, code int) {
+	if r == nil {
+		return
+	}
+	r.finishOnce.Do(func() {
+		close(r.stop)
+		<-r.stopped
+		r.send(Record{Event: "exit", Reason: reason, Code: &code}, true)
+		close(r.queue)
+		select {
+		case <-r.done:
+		case <-time.After(finishTimeout):
+			r.log.Warn("progress output stalled, final record may be lost")
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"io"
+	"time"
+)
+
+// Writes an animated GIF one frame at a time. image/gif only encodes whole
+// animations, so each frame is encoded on its own and its image block is
+// spliced into a single stream. Only the previous frame is held, because a
+// frame's delay is known once the next one arrives.
+type GIFWriter struct {
+	w       *bufio.Writer
+	started bool
+
+	pending     []byte // image block of the previous frame
+	pendingTime time.Duration
+	frameDur    time.Duration
+	written     int // centiseconds already assigned to frames
+}
+
+// Creates a GIF writer; frameDur is the delay given to the last frame
+func NewGIFWriter(w io.Writer, frameDur time.Duration) *GIFWriter {
+	return &GIFWriter{w: bufio.NewWriter(w), frameDur: frameDur}
+}
+
+// Adds a frame shown from t (relative to the first frame). All frames must
+// share the size and palette of the first.
+func (g *GIFWriter) WriteFrame(img *image.Paletted, t time.Duration) error {
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, img, &gif.Options{NumColors: len(img.Palette)}); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	blockStart, err := imageBlockOffset(data)
+	if err != nil {
+		return err
+	}
+
+	if !g.started {
+		// Header, screen descriptor and global palette of the first frame,
+		// followed by a NETSCAPE2.0 block to loop forever
+		g.w.Write(data[:blockStart])
+		g.w.Write([]byte{0x21, 0xff, 0x0b})
+		g.w.WriteString("NETSCAPE2.0")
+		g.w.Write([]byte{0x03, 0x01, 0x00, 0x00, 0x00})
+		g.started = true
+	} else if err := g.flushPending(t); err != nil {
+		return err
+	}
+
+	// Everything up to the trailer byte
+	g.pending = append(g.pending[:0], data[blockStart:len(data)-1]...)
+	g.pendingTime = t
+	return nil
+}
+
+// Writes the held frame with the delay up to next
+func (g *GIFWriter) flushPending(next time.Duration) error {
+	if g.pending == nil {
+		return nil
+	}
+	// Delays are in centiseconds; rounding against the running total keeps
+	// the animation from drifting at rates like 15 fps
+	end := int((next + 5*time.Millisecond) / (10 * time.Millisecond))
+	delay := max(end-g.written, 1)
+	g.written += delay
+
+	g.w.Write([]byte{0x21, 0xf9, 0x04, 0x00, byte(delay), byte(delay >> 8), 0x00, 0x00})
+	_, err := g.w.Write(g.pending)
+	g.pending = nil
+	return err
+}
+
+// Writes the last frame and the trailer. The underlying writer stays open.
+func (g *GIFWriter) Close() error {
+	if !g.started {
+		return errors.New("gif: no frames")
+	}
+	if err := g.flushPending(g.pendingTime + g.frameDur); err != nil {
+		return err
+	}
+	g.w.WriteByte(0x3b)
+	return g.w.Flush()
+}
+
+// Returns where the first extension or image descriptor starts, after the
+// header, logical screen descriptor and global color table
+func imageBlockOffset(data []byte) (int, error) {
+	const headerLen = 6 + 7
+	if len(data) < headerLen+1 {
+		return 0, fmt.Errorf("gif: short encoding")
+	}
+	offset := headerLen
+	if packed := data[10]; packed&0x80 != 0 {
+		offset += 3 << (int(packed&0x07) + 1)
+	}
+	if offset >= len(data) || (data[offset] != 0x2c && data[offset] != 0x21) {
+		return 0, fmt.Errorf("gif: unexpected encoding")
+	}
+	return offset, nil
+}
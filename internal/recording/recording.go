@@ -0,0 +1,163 @@
+package recording
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Receives rendered text frames in display order
+type Writer interface {
+	WriteFrame(t time.Duration, frame string) error
+	// Flushes buffered output. The underlying file is left open.
+	Close() error
+}
+
+// Magic first line of an .ans recording
+const ansMagic = "PIXLGO-ANS 1"
+
+// Writes the .ans format: a header line, then per frame "@<ms> <bytes>\n"
+// followed by the frame text. Every record is complete on its own, so a
+// file cut short between frames still replays.
+type ansWriter struct {
+	w *bufio.Writer
+}
+
+// Creates an .ans writer for frames of cols x rows cells
+func NewANSWriter(w io.Writer, cols, rows int) (Writer, error) {
+	bw := bufio.NewWriter(w)
+	if _, err := fmt.Fprintf(bw, "%s %d %d\n", ansMagic, cols, rows); err != nil {
+		return nil, err
+	}
+	return &ansWriter{w: bw}, nil
+}
+
+func (a *ansWriter) WriteFrame(t time.Duration, frame string) error {
+	_, err := fmt.Fprintf(a.w, "@%d %d\n%s", t.Milliseconds(), len(frame), frame)
+	return err
+}
+
+func (a *ansWriter) Close() error {
+	return a.w.Flush()
+}
+
+// Writes an asciinema v2 recording: a JSON header line, then one
+// [seconds, "o", data] event line per frame
+type castWriter struct {
+	w *bufio.Writer
+}
+
+// Creates an asciinema v2 writer for a cols x rows terminal
+func NewCastWriter(w io.Writer, cols, rows int, title string) (Writer, error) {
+	bw := bufio.NewWriter(w)
+	header := struct {
+		Version   int    `json:"version"`
+		Width     int    `json:"width"`
+		Height    int    `json:"height"`
+		Timestamp int64  `json:"timestamp"`
+		Title     string `json:"title,omitempty"`
+	}{2, cols, rows, time.Now().Unix(), title}
+	data, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	bw.Write(data)
+	bw.WriteByte('\n')
+
+	c := &castWriter{w: bw}
+	// Clear and hide the cursor once up front
+	if err := c.event(0, "\x1b[2J\x1b[?25l"); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *castWriter) WriteFrame(t time.Duration, frame string) error {
+	// Players write events to a raw terminal, so newlines need a CR. The
+	// final one is dropped so a full-height frame doesn't scroll.
+	frame = strings.TrimSuffix(frame, "\n")
+	return c.event(t, "\x1b[H"+strings.ReplaceAll(frame, "\n", "\r\n"))
+}
+
+func (c *castWriter) event(t time.Duration, data string) error {
+	text, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.w, "[%.6f, \"o\", %s]\n", t.Seconds(), text)
+	return err
+}
+
+func (c *castWriter) Close() error {
+	return c.w.Flush()
+}
+
+var ErrNotANS = errors.New("not a pixlgo .ans recording")
+
+// Reports whether r starts with the .ans header
+func IsANS(r io.Reader) bool {
+	buf := make([]byte, len(ansMagic))
+	_, err := io.ReadFull(r, buf)
+	return err == nil && string(buf) == ansMagic
+}
+
+// Plays an .ans recording to out with its original timing until it ends or
+// ctx is cancelled. A truncated final frame is skipped.
+func Replay(ctx context.Context, r io.Reader, out io.Writer) error {
+	br := bufio.NewReader(r)
+	header, err := br.ReadString('\n')
+	if err != nil || !strings.HasPrefix(header, ansMagic+" ") {
+		return ErrNotANS
+	}
+
+	io.WriteString(out, "\x1b[2J\x1b[?25l")
+	defer io.WriteString(out, "\x1b[0m\x1b[?25h\n")
+
+	start := time.Now()
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			// End of file, possibly mid-header of a cut-off frame
+			return nil
+		}
+		t, size, err := parseRecord(line)
+		if err != nil {
+			return err
+		}
+		frame := make([]byte, size)
+		if _, err := io.ReadFull(br, frame); err != nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Until(start.Add(t))):
+		}
+
+		io.WriteString(out, "\x1b[H")
+		if _, err := out.Write(frame); err != nil {
+			return err
+		}
+	}
+}
+
+// Parses a "@<ms> <bytes>" record header
+func parseRecord(line string) (time.Duration, int, error) {
+	ms, size, ok := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(line, "@"), "\n"), " ")
+	if !ok || !strings.HasPrefix(line, "@") {
+		return 0, 0, fmt.Errorf("bad frame header %q", strings.TrimSpace(line))
+	}
+	t, err1 := strconv.ParseInt(ms, 10, 64)
+	n, err2 := strconv.Atoi(size)
+	if err1 != nil || err2 != nil || t < 0 || n < 0 {
+		return 0, 0, fmt.Errorf("bad frame header %q", strings.TrimSpace(line))
+	}
+	return time.Duration(t) * time.Millisecond, n, nil
+}
@@ -0,0 +1,37 @@
+package renderer
+
+import (
+	"encoding/base64"
+	"errors"
+)
+
+var (
+	ErrClipboardTooLarge = errors.New("clipboard data exceeds the OSC 52 limit")
+	ErrClipboardNoTTY    = errors.New("no terminal to send the clipboard sequence to")
+)
+
+// Most terminals ignore OSC 52 sequences longer than this
+const osc52Max = 100000
+
+// Copies data to the system clipboard with the OSC 52 escape sequence,
+// which the terminal handles, so it also works over SSH. Inside tmux or
+// screen the sequence is wrapped for passthrough.
+func (r *Renderer) SendOSC52(data []byte) error {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString(data) + "\x1b\\"
+	if len(seq) > osc52Max {
+		return ErrClipboardTooLarge
+	}
+	seq = r.mux.Wrap(seq)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.screen == nil || r.closed {
+		return ErrClipboardNoTTY
+	}
+	tty, ok := r.screen.Tty()
+	if !ok {
+		return ErrClipboardNoTTY
+	}
+	_, err := tty.Write([]byte(seq))
+	return err
+}
@@ -0,0 +1,6 @@
+//go:build !(aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || zos)
+
+package renderer
+
+// No termios here; Show stall detection is the only safeguard
+func disableFlowControl() {}
@@ -0,0 +1,27 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || zos
+
+package renderer
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Turns off XON/XOFF so an accidental Ctrl-S can't freeze the display.
+// Called after screen.Init; screen.Fini restores the original termios.
+func disableFlowControl() {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return
+	}
+	defer tty.Close()
+
+	fd := int(tty.Fd())
+	termios, err := unix.IoctlGetTermios(fd, ioctlReadTermios)
+	if err != nil || termios.Iflag&unix.IXON == 0 {
+		return
+	}
+	termios.Iflag &^= unix.IXON
+	unix.IoctlSetTermios(fd, ioctlWriteTermios, termios)
+}
@@ -40,10 +40,23 @@ func (r *Renderer) RenderImage(img *image.RGBA, offsetX, offsetY int) {
 			r.prevCells[i] = 0xFFFFFFFFFFFFFFFF
 		}
 	}
+	if r.heatmap && len(r.cellAges) != bufsize {
+		r.cellAges = make([]uint8, bufsize)
+		for i := range r.cellAges {
+			r.cellAges[i] = heatFade
+		}
+	}
+
+	// Cells per pixel column; the cache still holds one entry per pixel
+	colW := 1
+	if r.halfWidth {
+		colW = 2
+	}
 
 	pix := img.Pix
 	stride := img.Stride
 	idx := 0
+	r.drawn = 0
 
 	for py := 0; py < imgH; py += 2 {
 		cellY := offsetY + py/2
@@ -51,13 +64,17 @@ func (r *Renderer) RenderImage(img *image.RGBA, offsetX, offsetY int) {
 			idx += cellW
 			continue
 		}
+		if r.interlace && (py/2)%2 != r.field {
+			idx += cellW
+			continue
+		}
 
 		topRowOff := py * stride
 		botRowOff := topRowOff + stride
 		hasBot := py+1 < imgH
 
 		for px := range imgW {
-			cellX := offsetX + px
+			cellX := offsetX + px*colW
 			if cellX < 0 || cellX >= screenW {
 				idx++
 				continue
@@ -76,6 +93,13 @@ func (r *Renderer) RenderImage(img *image.RGBA, offsetX, offsetY int) {
 
 			packed := packColors(tr, tg, tb, br, bg, bb)
 
+			if r.heatmap {
+				r.drawHeat(cellX, cellY, min(colW, screenW-cellX), idx, r.prevCells[idx] != packed)
+				r.prevCells[idx] = packed
+				idx++
+				continue
+			}
+
 			if idx < len(r.prevCells) && r.prevCells[idx] == packed {
 				idx++
 				continue
@@ -90,8 +114,60 @@ func (r *Renderer) RenderImage(img *image.RGBA, offsetX, offsetY int) {
 				Background(tcell.NewRGBColor(int32(br), int32(bg), int32(bb)))
 
 			r.screen.SetContent(cellX, cellY, '▀', nil, style)
+			r.drawn++
+			if colW == 2 && cellX+1 < screenW {
+				r.screen.SetContent(cellX+1, cellY, '▀', nil, style)
+				r.drawn++
+			}
 		}
 	}
+
+	if r.interlace {
+		r.field = 1 - r.field
+	}
+}
+
+// Frames after which an unchanged cell counts as static in the heatmap
+const heatFade = 24
+
+// Draws the w heatmap cells for idx: red when it changed this frame,
+// fading through yellow to dark as it stays the same, black once static
+func (r *Renderer) drawHeat(x, y, w, idx int, changed bool) {
+	age := r.cellAges[idx]
+	if changed {
+		age = 0
+	} else if age < heatFade {
+		age++
+	}
+	r.cellAges[idx] = age
+
+	var red, green, blue int32
+	switch {
+	case age >= heatFade:
+	case age < heatFade/4:
+		// Red to yellow
+		red, green = 255, int32(age)*255/(heatFade/4)
+	default:
+		// Yellow to dark
+		left := int32(heatFade - age)
+		red = 40 + left*215/(heatFade*3/4)
+		green, blue = red, 0
+	}
+	style := tcell.StyleDefault.Background(tcell.NewRGBColor(red, green, blue))
+	for i := range w {
+		r.screen.SetContent(x+i, y, ' ', nil, style)
+	}
+}
+
+// Switches RenderImage between the picture and a map of how recently each
+// cell changed, which shows what the diff cache has to redraw
+func (r *Renderer) SetHeatmap(enabled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.heatmap = enabled
+	r.cellAges = nil
+	r.prevCells = nil
+	r.needsClear = true
 }
 
 func packColors(tr, tg, tb, br, bg, bb byte) uint64 {
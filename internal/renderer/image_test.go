@@ -0,0 +1,41 @@
+package renderer
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// Returns a w x h image whose pixels each get a distinct color
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.SetRGBA(x, y, color.RGBA{R: uint8(x * 60), G: uint8(y * 60), B: uint8(255 - x*y*20), A: 255})
+		}
+	}
+	return img
+}
+
+func TestRenderImageGolden(t *testing.T) {
+	tests := []struct {
+		name      string
+		w, h      int
+		x, y      int
+		halfWidth bool
+	}{
+		// Two pixel rows per cell, the odd last row doubled into both halves
+		{"render_image", 4, 3, 1, 0, false},
+		{"render_image_halfwidth", 4, 3, 0, 1, true},
+		// Cells off any edge of the screen are left out
+		{"render_image_clipped", 4, 4, -1, 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := newTestRenderer(t, 10, 4)
+			r.SetHalfWidth(tt.halfWidth)
+			r.RenderImage(testImage(tt.w, tt.h), tt.x, tt.y)
+			checkGolden(t, tt.name, formatGrid(r.Snapshot()))
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package renderer
+
+import (
+	"image"
+	"math"
+)
+
+// Maps each 8-bit channel value to an adjusted one
+type LUT [256]uint8
+
+// Returns a LUT that stretches [lo, hi] to the full 0-255 range and clips
+// outside it. Gamma above 1 brightens midtones, below 1 darkens them; 1
+// leaves them linear.
+func LevelsLUT(lo, hi, gamma float64) *LUT {
+	var lut LUT
+	span := max(hi-lo, 1)
+	if gamma <= 0 {
+		gamma = 1
+	}
+	for i := range lut {
+		v := (float64(i) - lo) / span
+		v = min(max(v, 0), 1)
+		if gamma != 1 {
+			v = math.Pow(v, 1/gamma)
+		}
+		lut[i] = uint8(v*255 + 0.5)
+	}
+	return &lut
+}
+
+// Writes src with every color channel mapped through the LUT into dst,
+// which must have the same bounds. Alpha is copied unchanged.
+func (l *LUT) Apply(dst, src *image.RGBA) {
+	s, d := src.Pix, dst.Pix
+	for i := 0; i+3 < len(s) && i+3 < len(d); i += 4 {
+		d[i] = l[s[i]]
+		d[i+1] = l[s[i+1]]
+		d[i+2] = l[s[i+2]]
+		d[i+3] = s[i+3]
+	}
+}
+
+// Pixels skipped between luma samples in LumaRange; the bounds don't need
+// every pixel and this runs per frame
+const lumaSampleStep = 3
+
+// Returns the luma values below which the fraction clip of pixels falls and
+// above which the same fraction lies, ignoring a few outliers such as
+// subtitles or a single bright lamp
+func LumaRange(img *image.RGBA, clip float64) (lo, hi uint8) {
+	var hist [256]int
+	total := 0
+	pix := img.Pix
+	for i := 0; i+2 < len(pix); i += 4 * lumaSampleStep {
+		// Rec. 601 weights in integer math
+		y := (299*int(pix[i]) + 587*int(pix[i+1]) + 114*int(pix[i+2])) / 1000
+		hist[y]++
+		total++
+	}
+	if total == 0 {
+		return 0, 255
+	}
+
+	limit := int(float64(total) * clip)
+	lo, hi = 0, 255
+	for n := 0; lo < 255; lo++ {
+		if n += hist[lo]; n > limit {
+			break
+		}
+	}
+	for n := 0; hi > 0; hi-- {
+		if n += hist[hi]; n > limit {
+			break
+		}
+	}
+	if hi < lo {
+		lo, hi = hi, lo
+	}
+	return lo, hi
+}
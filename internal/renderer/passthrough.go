@@ -0,0 +1,105 @@
+package renderer
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Terminal multiplexer pixlgo runs inside, if any
+type Multiplexer int
+
+const (
+	MuxNone Multiplexer = iota
+	MuxTmux
+	MuxScreen
+)
+
+func (m Multiplexer) String() string {
+	switch m {
+	case MuxTmux:
+		return "tmux"
+	case MuxScreen:
+		return "GNU screen"
+	default:
+		return "none"
+	}
+}
+
+// Detects tmux via $TMUX and screen via $STY or a screen* $TERM. tmux is
+// checked first since it also sets TERM=screen* by default.
+func DetectMultiplexer() Multiplexer {
+	switch {
+	case os.Getenv("TMUX") != "":
+		return MuxTmux
+	case os.Getenv("STY") != "" || strings.HasPrefix(os.Getenv("TERM"), "screen"):
+		return MuxScreen
+	}
+	return MuxNone
+}
+
+// Largest payload per passthrough envelope. screen drops DCS strings over
+// 768 bytes; tmux has no fixed limit but buffers each one whole, so large
+// images go through in pieces.
+const (
+	tmuxChunk   = 4096
+	screenChunk = 768
+)
+
+// Wraps an escape sequence in DCS passthrough envelopes so the multiplexer
+// hands it to the outer terminal untouched instead of interpreting or
+// dropping it. Long sequences are split over several envelopes, which the
+// outer terminal sees as one contiguous sequence. Returns seq unchanged
+// outside a multiplexer.
+func (m Multiplexer) Wrap(seq string) string {
+	var prefix string
+	var chunk int
+	switch m {
+	case MuxTmux:
+		prefix, chunk = "\x1bPtmux;", tmuxChunk
+	case MuxScreen:
+		prefix, chunk = "\x1bP", screenChunk
+	default:
+		return seq
+	}
+
+	var sb strings.Builder
+	for len(seq) > 0 {
+		n := min(chunk, len(seq))
+		part := seq[:n]
+		seq = seq[n:]
+
+		sb.WriteString(prefix)
+		if m == MuxTmux {
+			// tmux wants every ESC inside the envelope doubled
+			part = strings.ReplaceAll(part, "\x1b", "\x1b\x1b")
+		}
+		sb.WriteString(part)
+		sb.WriteString("\x1b\\")
+	}
+	return sb.String()
+}
+
+// Reports whether the multiplexer forwards passthrough envelopes. tmux 3.3
+// and later drop them unless allow-passthrough is on; older versions and
+// screen always forward them.
+func (m Multiplexer) PassthroughEnabled() bool {
+	if m != MuxTmux {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "tmux", "show-options", "-Apv", "allow-passthrough").CombinedOutput()
+	value := strings.TrimSpace(string(out))
+	if err != nil {
+		// Versions before 3.3 don't know the option and always pass through
+		return strings.Contains(value, "invalid option") || strings.Contains(value, "unknown option")
+	}
+	// The inherited form prints "allow-passthrough on"; take the last word
+	if fields := strings.Fields(value); len(fields) > 0 {
+		value = fields[len(fields)-1]
+	}
+	return value == "on" || value == "all"
+}
@@ -0,0 +1,78 @@
+package renderer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoTerminal = errors.New("no controlling terminal")
+	ErrNoReply    = errors.New("terminal did not reply")
+)
+
+const queryTimeout = 500 * time.Millisecond
+
+// Prints s at the start of the line and asks the terminal where the cursor
+// ended up, giving the width it really used. The line is cleared after.
+func MeasureWidth(s string) (int, error) {
+	reply, err := QueryTerminal("\r"+s+"\x1b[6n\r\x1b[K", 'R', queryTimeout)
+	if err != nil {
+		return 0, err
+	}
+	// Reply is ESC [ row ; col R
+	start := strings.LastIndex(reply, "\x1b[")
+	if start < 0 {
+		return 0, fmt.Errorf("unexpected reply %q", reply)
+	}
+	_, col, ok := strings.Cut(strings.TrimSuffix(reply[start+2:], "R"), ";")
+	n, err := strconv.Atoi(col)
+	if !ok || err != nil {
+		return 0, fmt.Errorf("unexpected reply %q", reply)
+	}
+	return n - 1, nil
+}
+
+// Graphics protocols a terminal reported
+type Graphics struct {
+	Sixel bool
+	Kitty bool
+
+	// Multiplexer the queries went through, and whether it refuses to pass
+	// graphics sequences on; callers then fall back to half blocks
+	Mux                Multiplexer
+	PassthroughBlocked bool
+}
+
+// Asks the terminal for kitty graphics support and its primary device
+// attributes, where parameter 4 means sixel. Terminals that ignore the
+// kitty query still answer the attributes request, which ends the wait.
+// Inside tmux or screen the kitty query is sent in a passthrough envelope;
+// the attributes come from the multiplexer, which draws sixel itself.
+func DetectGraphics() (Graphics, error) {
+	const kittyQuery = "\x1b_Gi=31,s=1,v=1,a=q,t=d,f=24;AAAA\x1b\\"
+	g := Graphics{Mux: DetectMultiplexer()}
+	query := "\x1b[c"
+	if g.Mux == MuxNone || g.Mux.PassthroughEnabled() {
+		query = g.Mux.Wrap(kittyQuery) + query
+	} else {
+		g.PassthroughBlocked = true
+	}
+
+	reply, err := QueryTerminal(query, 'c', queryTimeout)
+	if err != nil {
+		return g, err
+	}
+
+	g.Kitty = strings.Contains(reply, "_Gi=31;OK")
+	if idx := strings.LastIndex(reply, "\x1b[?"); idx >= 0 {
+		for _, p := range strings.Split(strings.TrimSuffix(reply[idx+3:], "c"), ";") {
+			if p == "4" {
+				g.Sixel = true
+			}
+		}
+	}
+	return g, nil
+}
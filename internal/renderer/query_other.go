@@ -0,0 +1,10 @@
+//go:build !(aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || zos)
+
+package renderer
+
+import "time"
+
+// Terminal queries need termios; elsewhere they are reported as unsupported
+func QueryTerminal(query string, terminator byte, timeout time.Duration) (string, error) {
+	return "", ErrNoTerminal
+}
@@ -0,0 +1,56 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || zos
+
+package renderer
+
+import (
+	"bytes"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Writes query to the controlling terminal and returns its reply up to and
+// including terminator. Echo and line buffering are off meanwhile. Must not
+// be used while the tcell screen is active.
+func QueryTerminal(query string, terminator byte, timeout time.Duration) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", ErrNoTerminal
+	}
+	defer tty.Close()
+
+	fd := int(tty.Fd())
+	saved, err := unix.IoctlGetTermios(fd, ioctlReadTermios)
+	if err != nil {
+		return "", ErrNoTerminal
+	}
+	raw := *saved
+	raw.Lflag &^= unix.ICANON | unix.ECHO
+	// Return after at most 100ms without input so the deadline is checked
+	raw.Cc[unix.VMIN] = 0
+	raw.Cc[unix.VTIME] = 1
+	if err := unix.IoctlSetTermios(fd, ioctlWriteTermios, &raw); err != nil {
+		return "", err
+	}
+	defer unix.IoctlSetTermios(fd, ioctlWriteTermios, saved)
+
+	if _, err := tty.WriteString(query); err != nil {
+		return "", err
+	}
+
+	var reply []byte
+	buf := make([]byte, 256)
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		n, err := tty.Read(buf)
+		if err != nil {
+			return "", err
+		}
+		reply = append(reply, buf[:n]...)
+		if bytes.IndexByte(reply, terminator) >= 0 {
+			return string(reply), nil
+		}
+	}
+	return string(reply), ErrNoReply
+}
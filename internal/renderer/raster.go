@@ -0,0 +1,46 @@
+package renderer
+
+import (
+	"image"
+	"image/color"
+)
+
+// Draws the half-block cell grid that RenderColor would print for img as
+// pixels. Each cell becomes scale x 2*scale pixels: the upper half in the
+// top pixel's color, the lower half in the bottom pixel's.
+func Rasterize(img *image.RGBA, scale int) *image.RGBA {
+	if img == nil {
+		return nil
+	}
+	scale = max(scale, 1)
+	bounds := img.Bounds()
+	cols := bounds.Dx()
+	rows := (bounds.Dy() + 1) / 2
+
+	out := image.NewRGBA(image.Rect(0, 0, cols*scale, rows*2*scale))
+	for row := range rows {
+		for col := range cols {
+			top := img.RGBAAt(bounds.Min.X+col, bounds.Min.Y+row*2)
+			bottom := top
+			if row*2+1 < bounds.Dy() {
+				bottom = img.RGBAAt(bounds.Min.X+col, bounds.Min.Y+row*2+1)
+			}
+			fillRect(out, col*scale, row*2*scale, scale, scale, top)
+			fillRect(out, col*scale, (row*2+1)*scale, scale, scale, bottom)
+		}
+	}
+	return out
+}
+
+func fillRect(img *image.RGBA, x, y, w, h int, c color.RGBA) {
+	for yy := y; yy < y+h; yy++ {
+		off := img.PixOffset(x, yy)
+		for range w {
+			img.Pix[off+0] = c.R
+			img.Pix[off+1] = c.G
+			img.Pix[off+2] = c.B
+			img.Pix[off+3] = 255
+			off += 4
+		}
+	}
+}
@@ -1,7 +1,12 @@
 package renderer
 
 import (
+	"os"
+	"runtime"
+	"strings"
 	"sync"
+	"time"
+	"unicode"
 
 	"github.com/gdamore/tcell/v2"
 )
@@ -14,15 +19,46 @@ type Renderer struct {
 	prevH      int
 	closed     bool
 	needsClear bool
+	interlace  bool
+	field      int
+	stall      time.Duration
+	mux        Multiplexer
+
+	// Debug view: per-cell frames since the last change instead of the image
+	heatmap  bool
+	cellAges []uint8
+
+	// Draws each pixel column as two cells, for frames decoded at half width
+	halfWidth bool
+	// Cells written by the last RenderImage, for the stats overlay
+	drawn int
 }
 
+// Show calls slower than this are reported as output stalls, usually a
+// terminal suspended with Ctrl-S or a very slow connection
+const stallThreshold = time.Second
+
 // Creates a new terminal renderer
 func New() (*Renderer, error) {
+	enableTrueColor()
+
 	screen, err := tcell.NewScreen()
 	if err != nil {
 		return nil, err
 	}
 
+	r, err := NewWithScreen(screen)
+	if err != nil {
+		return nil, err
+	}
+	disableFlowControl()
+	return r, nil
+}
+
+// Creates a renderer drawing to screen, which it initializes and owns from
+// then on. Tests and embedders pass tcell.NewSimulationScreen to render
+// without a terminal; see Snapshot.
+func NewWithScreen(screen tcell.Screen) (*Renderer, error) {
 	if err := screen.Init(); err != nil {
 		return nil, err
 	}
@@ -33,9 +69,21 @@ func New() (*Renderer, error) {
 	return &Renderer{
 		screen:     screen,
 		needsClear: true,
+		mux:        DetectMultiplexer(),
 	}, nil
 }
 
+// Windows Terminal supports 24-bit color but doesn't advertise it through
+// COLORTERM, so tcell would otherwise fall back to the 16-color palette
+func enableTrueColor() {
+	if runtime.GOOS != "windows" || os.Getenv("COLORTERM") != "" {
+		return
+	}
+	if os.Getenv("WT_SESSION") != "" {
+		os.Setenv("COLORTERM", "truecolor")
+	}
+}
+
 // Returns undelying tcell screen
 func (r *Renderer) Screen() tcell.Screen {
 	r.mu.Lock()
@@ -87,6 +135,65 @@ func (r *Renderer) InvalidateCache() {
 	r.prevCells = nil
 }
 
+// Enables drawing alternate cell rows on each frame to halve render cost
+func (r *Renderer) SetInterlace(enabled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interlace = enabled
+	r.field = 0
+}
+
+// Returns whether the interlaced render path is enabled
+func (r *Renderer) Interlaced() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.interlace
+}
+
+// Makes RenderImage draw every pixel column twice, so a frame decoded at
+// half the terminal width still fills it with the aspect ratio intact
+func (r *Renderer) SetHalfWidth(enabled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.halfWidth = enabled
+	r.prevCells = nil
+	r.needsClear = true
+}
+
+// Returns whether pixel columns are drawn twice
+func (r *Renderer) HalfWidth() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.halfWidth
+}
+
+// Returns how many cells the last RenderImage wrote; unchanged cells the
+// diff cache skipped don't count
+func (r *Renderer) DrawnCells() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.drawn
+}
+
+// Sets the terminal window title with OSC 2. The previous title is saved
+// when the screen starts and restored by Close on terminals that support
+// it; others ignore the sequences. Control characters are dropped so a
+// file name can't inject escape sequences.
+func (r *Renderer) SetTitle(title string) {
+	title = strings.Map(func(c rune) rune {
+		if unicode.IsControl(c) {
+			return -1
+		}
+		return c
+	}, title)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.screen != nil && !r.closed {
+		r.screen.SetTitle(title)
+	}
+}
+
 // Returns whether the renderer is closed
 func (r *Renderer) IsClosed() bool {
 	r.mu.Lock()
@@ -105,6 +212,10 @@ func (r *Renderer) Close() {
 	r.closed = true
 
 	if r.screen != nil {
+		// Turn off input modes so no reports leak into the shell after exit
+		r.screen.DisableMouse()
+		r.screen.DisablePaste()
+		r.screen.DisableFocus()
 		r.screen.Fini()
 		r.screen = nil
 	}
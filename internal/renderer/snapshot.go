@@ -0,0 +1,64 @@
+package renderer
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// One screen cell as drawn: the character and its colors
+type Cell struct {
+	Rune rune
+	FG   tcell.Color
+	BG   tcell.Color
+}
+
+// A copy of the drawn screen, row by row, for comparing renders
+type Grid struct {
+	W, H  int
+	Cells []Cell
+}
+
+// Returns the cell at x, y
+func (g Grid) At(x, y int) Cell {
+	return g.Cells[y*g.W+x]
+}
+
+// Returns the characters only, one line per row with trailing spaces
+// trimmed, for golden comparisons of layout
+func (g Grid) String() string {
+	var sb strings.Builder
+	for y := range g.H {
+		var line strings.Builder
+		for x := range g.W {
+			line.WriteRune(g.At(x, y).Rune)
+		}
+		sb.WriteString(strings.TrimRight(line.String(), " "))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// Copies what has been drawn so far, including content not yet sent by
+// Show. Empty cells read as spaces.
+func (r *Renderer) Snapshot() Grid {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.screen == nil || r.closed {
+		return Grid{}
+	}
+	w, h := r.screen.Size()
+	g := Grid{W: w, H: h, Cells: make([]Cell, w*h)}
+	for y := range h {
+		for x := range w {
+			ch, _, style, _ := r.screen.GetContent(x, y)
+			if ch == 0 {
+				ch = ' '
+			}
+			fg, bg, _ := style.Decompose()
+			g.Cells[y*w+x] = Cell{Rune: ch, FG: fg, BG: bg}
+		}
+	}
+	return g
+}
@@ -0,0 +1,62 @@
+package renderer
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// Formats a grid for golden files: the characters, then per row each
+// cell's fg/bg as hex, - for the default color
+func formatGrid(g Grid) string {
+	color := func(c tcell.Color) string {
+		if hex := c.Hex(); hex >= 0 {
+			return fmt.Sprintf("%06x", hex)
+		}
+		return "-"
+	}
+	var sb strings.Builder
+	sb.WriteString(g.String())
+	for y := range g.H {
+		sb.WriteByte('\n')
+		for x := range g.W {
+			if x > 0 {
+				sb.WriteByte(' ')
+			}
+			c := g.At(x, y)
+			sb.WriteString(color(c.FG) + "/" + color(c.BG))
+		}
+	}
+	sb.WriteByte('\n')
+	return sb.String()
+}
+
+// Compares got with testdata/<name>.golden, or rewrites that file when the
+// tests run with -update
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from %s:\n%s\nwant:\n%s", name, path, got, want)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"image"
 	"image/color"
 	"strings"
+	"time"
 )
 
 // Updates the screen
@@ -12,12 +13,30 @@ func (r *Renderer) Show() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	if r.screen != nil && !r.closed {
+		start := time.Now()
 		r.screen.Show()
+		if d := time.Since(start); d > stallThreshold {
+			r.stall = d
+		}
 	}
 }
 
+// Returns and clears the duration of the last stalled Show, or zero
+func (r *Renderer) TakeStall() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d := r.stall
+	r.stall = 0
+	return d
+}
+
 // Render image as ascii art
 func (r *Renderer) RenderASCII(img *image.RGBA) string {
+	return RenderASCII(img)
+}
+
+// Renders an image as brightness-mapped ASCII, one character per pixel
+func RenderASCII(img *image.RGBA) string {
 	if img == nil {
 		return ""
 	}
@@ -31,7 +50,7 @@ func (r *Renderer) RenderASCII(img *image.RGBA) string {
 	var sb strings.Builder
 	for y := range height {
 		for x := range width {
-			c := img.RGBAAt(x, y)
+			c := img.RGBAAt(bounds.Min.X+x, bounds.Min.Y+y)
 
 			// Calculate brightness (0-255)
 			brightness := (int(c.R) + int(c.G) + int(c.B)) / 3
@@ -45,8 +64,14 @@ func (r *Renderer) RenderASCII(img *image.RGBA) string {
 	return sb.String()
 }
 
-// RenderColor renders an image with ANSI colors using half blocks
+// Renders an image with ANSI colors using half blocks
 func (r *Renderer) RenderColor(img *image.RGBA) string {
+	return RenderColor(img)
+}
+
+// Renders an image as 24-bit ANSI half blocks, one text row per two pixel
+// rows. Needs no screen, so it also serves output to pipes and files.
+func RenderColor(img *image.RGBA) string {
 	if img == nil {
 		return ""
 	}
@@ -57,16 +82,16 @@ func (r *Renderer) RenderColor(img *image.RGBA) string {
 
 	var sb strings.Builder
 
-	// Use half blocks - each character is represents 2 vertical pixels
-	for y := range height {
+	// Each character is 2 vertical pixels
+	for y := 0; y < height; y += 2 {
 		for x := range width {
 			// Top pixel - Foreground
-			top := img.RGBAAt(x, y)
+			top := img.RGBAAt(bounds.Min.X+x, bounds.Min.Y+y)
 
 			// Bottom pixel - Background
 			var bottom color.RGBA
 			if y+1 < height {
-				bottom = img.RGBAAt(x, y+1)
+				bottom = img.RGBAAt(bounds.Min.X+x, bounds.Min.Y+y+1)
 			} else {
 				bottom = top
 			}
@@ -95,3 +120,10 @@ func (r *Renderer) HideCursor() {
 func (r *Renderer) ShowCursor() {
 	fmt.Print("\x1b[?25h")
 }
+
+// Disables mouse, focus and bracketed paste reporting, resets attributes
+// and shows the cursor; for the raw ANSI path that bypasses tcell
+func (r *Renderer) ResetTerminal() {
+	fmt.Print("\x1b[?1000l\x1b[?1002l\x1b[?1003l\x1b[?1006l" +
+		"\x1b[?1004l\x1b[?2004l\x1b[0m\x1b[?25h")
+}
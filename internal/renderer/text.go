@@ -0,0 +1,88 @@
+package renderer
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/uniseg"
+)
+
+const ellipsis = "…"
+
+// Returns the display width of s in terminal columns
+func TextWidth(s string) int {
+	return uniseg.StringWidth(s)
+}
+
+// Shortens s to at most w columns, ending with an ellipsis when cut
+func Truncate(s string, w int) string {
+	if w <= 0 {
+		return ""
+	}
+	if uniseg.StringWidth(s) <= w {
+		return s
+	}
+	if w == 1 {
+		return ellipsis
+	}
+
+	var sb strings.Builder
+	width := 0
+	g := uniseg.NewGraphemes(s)
+	for g.Next() {
+		cw := g.Width()
+		if width+cw > w-1 {
+			break
+		}
+		sb.WriteString(g.Str())
+		width += cw
+	}
+	sb.WriteString(ellipsis)
+	return sb.String()
+}
+
+// Like Truncate but keeps the end of s, for paths whose file name matters
+// more than their directories
+func TruncateLeft(s string, w int) string {
+	if w <= 0 {
+		return ""
+	}
+	if uniseg.StringWidth(s) <= w {
+		return s
+	}
+	if w == 1 {
+		return ellipsis
+	}
+
+	var clusters []string
+	var widths []int
+	g := uniseg.NewGraphemes(s)
+	for g.Next() {
+		clusters = append(clusters, g.Str())
+		widths = append(widths, g.Width())
+	}
+	width, start := 0, len(clusters)
+	for start > 0 && width+widths[start-1] <= w-1 {
+		start--
+		width += widths[start]
+	}
+	return ellipsis + strings.Join(clusters[start:], "")
+}
+
+// Draws text cell by cell, clipped to [x, maxX). Caller holds mu.
+// Returns the column after the last drawn grapheme.
+func (r *Renderer) drawString(x, y, maxX int, text string, style tcell.Style) int {
+	g := uniseg.NewGraphemes(text)
+	for g.Next() {
+		cw := g.Width()
+		if x+cw > maxX {
+			break
+		}
+		if x >= 0 && cw > 0 {
+			runes := g.Runes()
+			r.screen.SetContent(x, y, runes[0], runes[1:], style)
+		}
+		x += cw
+	}
+	return x
+}
@@ -0,0 +1,137 @@
+package renderer
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Returns a renderer drawing on a w x h simulation screen
+func newTestRenderer(t *testing.T, w, h int) (*Renderer, tcell.SimulationScreen) {
+	t.Helper()
+	screen := tcell.NewSimulationScreen("UTF-8")
+	r, err := NewWithScreen(screen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(w, h)
+	t.Cleanup(r.Close)
+	return r, screen
+}
+
+const (
+	thumbsUp = "\U0001F44D\U0001F3FD"                       // emoji with skin tone modifier, 2 columns
+	family   = "\U0001F468\u200D\U0001F469\u200D\U0001F467" // ZWJ sequence, 2 columns
+	eAcute   = "e\u0301"                                    // e and a combining acute, 1 column
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		w    int
+		want string
+	}{
+		{"hello", 0, ""},
+		{"hello", -3, ""},
+		{"hello", 1, "…"},
+		{"hello", 5, "hello"},
+		{"hello", 4, "hel…"},
+		{"日本語テキスト", 14, "日本語テキスト"},
+		{"日本語テキスト", 5, "日本…"},
+		// A wide character that doesn't fit whole is left out
+		{"日本語", 4, "日…"},
+		{"日本語", 2, "…"},
+		{thumbsUp + "ok", 4, thumbsUp + "ok"},
+		{thumbsUp + "ok", 3, thumbsUp + "…"},
+		{thumbsUp + "ok", 2, "…"},
+		{family + family, 3, family + "…"},
+		{"caf" + eAcute + " au lait", 5, "caf" + eAcute + "…"},
+		{eAcute + eAcute + eAcute, 3, eAcute + eAcute + eAcute},
+		{eAcute + eAcute + eAcute, 2, eAcute + "…"},
+	}
+	for _, tt := range tests {
+		got := Truncate(tt.s, tt.w)
+		if got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.w, got, tt.want)
+		}
+		if w := TextWidth(got); w > max(tt.w, 0) {
+			t.Errorf("Truncate(%q, %d) is %d columns", tt.s, tt.w, w)
+		}
+	}
+}
+
+func TestTruncateLeft(t *testing.T) {
+	tests := []struct {
+		s    string
+		w    int
+		want string
+	}{
+		{"/videos/clip.mp4", 0, ""},
+		{"/videos/clip.mp4", 1, "…"},
+		{"/videos/clip.mp4", 16, "/videos/clip.mp4"},
+		{"/videos/clip.mp4", 9, "…clip.mp4"},
+		{"dir/日本.mp4", 6, "….mp4"},
+		{"dir/日本.mp4", 8, "…本.mp4"},
+		{"ab" + thumbsUp, 3, "…" + thumbsUp},
+		{"ab" + thumbsUp, 2, "…"},
+		{"x" + family, 3, "x" + family},
+		{"ab" + family, 3, "…" + family},
+		{"r" + eAcute + "sum" + eAcute, 4, "…um" + eAcute},
+		{eAcute + eAcute + eAcute, 2, "…" + eAcute},
+	}
+	for _, tt := range tests {
+		got := TruncateLeft(tt.s, tt.w)
+		if got != tt.want {
+			t.Errorf("TruncateLeft(%q, %d) = %q, want %q", tt.s, tt.w, got, tt.want)
+		}
+		if w := TextWidth(got); w > max(tt.w, 0) {
+			t.Errorf("TruncateLeft(%q, %d) is %d columns", tt.s, tt.w, w)
+		}
+	}
+}
+
+func TestDrawString(t *testing.T) {
+	type cell struct {
+		x    int
+		main rune
+		comb []rune
+	}
+	tests := []struct {
+		name  string
+		x     int
+		maxX  int
+		text  string
+		next  int
+		cells []cell
+	}{
+		{"ascii clipped", 0, 3, "hello", 3, []cell{{0, 'h', nil}, {2, 'l', nil}}},
+		{"CJK fits", 0, 6, "日本語", 6, []cell{{0, '日', nil}, {2, '本', nil}, {4, '語', nil}}},
+		// Half a wide character is not drawn
+		{"CJK clipped", 0, 5, "日本語", 4, []cell{{0, '日', nil}, {2, '本', nil}, {4, ' ', nil}}},
+		{"combining", 1, 6, "caf" + eAcute, 5, []cell{{1, 'c', nil}, {4, 'e', []rune{'\u0301'}}}},
+		{"emoji modifier", 0, 6, thumbsUp + "!", 3, []cell{{0, '\U0001F44D', []rune{'\U0001F3FD'}}, {2, '!', nil}}},
+		{"emoji clipped", 0, 1, thumbsUp, 0, []cell{{0, ' ', nil}}},
+		{"ZWJ sequence", 0, 6, family, 2, []cell{{0, '\U0001F468', []rune{'\u200D', '\U0001F469', '\u200D', '\U0001F467'}}}},
+		// Columns left of the screen are skipped, the rest still lands
+		{"starts offscreen", -1, 6, "日ab", 3, []cell{{1, 'a', nil}, {2, 'b', nil}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, screen := newTestRenderer(t, 8, 1)
+			screen.Clear()
+
+			r.mu.Lock()
+			next := r.drawString(tt.x, 0, tt.maxX, tt.text, tcell.StyleDefault)
+			r.mu.Unlock()
+			if next != tt.next {
+				t.Errorf("returned column %d, want %d", next, tt.next)
+			}
+			for _, c := range tt.cells {
+				main, comb, _, _ := screen.GetContent(c.x, 0)
+				if main != c.main || string(comb) != string(c.comb) {
+					t.Errorf("cell %d = %q%q, want %q%q", c.x, main, comb, c.main, c.comb)
+				}
+			}
+		})
+	}
+}
@@ -16,11 +16,7 @@ func (r *Renderer) DrawText(x, y int, text string, style tcell.Style) {
 		return
 	}
 
-	for i, ch := range text {
-		if x+i >= 0 && x+i < w {
-			r.screen.SetContent(x+i, y, ch, nil, style)
-		}
-	}
+	r.drawString(x, y, w, text, style)
 }
 
 // Fills a horizontal line with a style
@@ -63,19 +59,22 @@ func (r *Renderer) RenderMessage(msg string, bgColor tcell.Color) {
 		r.screen.SetContent(x, y, ' ', nil, style)
 	}
 
-	x := (w - len(msg)) / 2
+	msg = Truncate(msg, w)
+	x := (w - TextWidth(msg)) / 2
 	if x < 0 {
 		x = 0
 	}
-	for i, ch := range msg {
-		if x+i < w {
-			r.screen.SetContent(x+i, y, ch, nil, style)
-		}
-	}
+	r.drawString(x, y, w, msg, style)
 }
 
 // Draws a horizontal progress bar
 func (r *Renderer) ProgressBar(y int, progress float64, filledColor, emptyColor tcell.Color) {
+	r.BufferedProgressBar(y, progress, progress, filledColor, emptyColor, emptyColor)
+}
+
+// Draws a progress bar with a segment from progress to buffered in
+// bufferedColor, showing what is decoded but not yet played
+func (r *Renderer) BufferedProgressBar(y int, progress, buffered float64, filledColor, bufferedColor, emptyColor tcell.Color) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -94,17 +93,23 @@ func (r *Renderer) ProgressBar(y int, progress float64, filledColor, emptyColor
 	if progress > 1 {
 		progress = 1
 	}
+	buffered = min(max(buffered, progress), 1)
 
 	barW := w - 2
 	filled := int(float64(barW) * progress)
+	bufEnd := int(float64(barW) * buffered)
 
 	filledStyle := tcell.StyleDefault.Background(filledColor)
+	bufferedStyle := tcell.StyleDefault.Background(bufferedColor)
 	emptyStyle := tcell.StyleDefault.Background(emptyColor)
 
 	for x := 1; x < 1+filled && x < w-1; x++ {
 		r.screen.SetContent(x, y, '━', nil, filledStyle)
 	}
-	for x := 1 + filled; x < 1+barW && x < w-1; x++ {
+	for x := 1 + filled; x < 1+bufEnd && x < w-1; x++ {
+		r.screen.SetContent(x, y, '─', nil, bufferedStyle)
+	}
+	for x := 1 + max(filled, bufEnd); x < 1+barW && x < w-1; x++ {
 		r.screen.SetContent(x, y, '─', nil, emptyStyle)
 	}
 
@@ -115,3 +120,140 @@ func (r *Renderer) ProgressBar(y int, progress float64, filledColor, emptyColor
 	}
 	r.screen.SetContent(mx, y, '●', nil, tcell.StyleDefault.Foreground(tcell.ColorWhite))
 }
+
+// Marks positions (0..1) on a progress bar drawn at row y, such as chapter
+// starts, keeping each cell's background. The position marker stays on top.
+func (r *Renderer) ProgressTicks(y int, ticks []float64, color tcell.Color) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.screen == nil || r.closed {
+		return
+	}
+
+	w, h := r.screen.Size()
+	if y < 0 || y >= h || w < 4 {
+		return
+	}
+
+	barW := w - 2
+	for _, t := range ticks {
+		if t <= 0 || t >= 1 {
+			continue
+		}
+		x := 1 + int(float64(barW)*t)
+		if x >= w-1 {
+			continue
+		}
+		cell, _, style, _ := r.screen.GetContent(x, y)
+		if cell == '●' {
+			continue
+		}
+		r.screen.SetContent(x, y, '┃', nil, style.Foreground(color))
+	}
+}
+
+// Draws a bordered box with a title and one line of text per row inside
+func (r *Renderer) DrawBox(x, y, w, h int, title string, lines []string, style tcell.Style) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.screen == nil || r.closed || w < 3 || h < 3 {
+		return
+	}
+
+	sw, sh := r.screen.Size()
+	set := func(cx, cy int, ch rune) {
+		if cx >= 0 && cx < sw && cy >= 0 && cy < sh {
+			r.screen.SetContent(cx, cy, ch, nil, style)
+		}
+	}
+
+	for cy := y; cy < y+h; cy++ {
+		for cx := x; cx < x+w; cx++ {
+			set(cx, cy, ' ')
+		}
+	}
+	for cx := x + 1; cx < x+w-1; cx++ {
+		set(cx, y, '─')
+		set(cx, y+h-1, '─')
+	}
+	for cy := y + 1; cy < y+h-1; cy++ {
+		set(x, cy, '│')
+		set(x+w-1, cy, '│')
+	}
+	set(x, y, '┌')
+	set(x+w-1, y, '┐')
+	set(x, y+h-1, '└')
+	set(x+w-1, y+h-1, '┘')
+
+	maxX := min(x+w-1, sw)
+	if title != "" {
+		r.drawString(x+2, y, maxX-1, " "+Truncate(title, w-6)+" ", style)
+	}
+
+	for row, line := range lines {
+		cy := y + 1 + row
+		if row >= h-2 || cy >= sh {
+			break
+		}
+		if cy >= 0 {
+			r.drawString(x+1, cy, maxX, line, style)
+		}
+	}
+}
+
+// Left-aligned eighth blocks, from empty to full
+var eighths = []rune(" ▏▎▍▌▋▊▉█")
+
+// Returns the cells VUMeter needs for n bars of barW cells each
+func VUMeterWidth(n, barW int) int {
+	if n <= 0 {
+		return 0
+	}
+	return n*barW + n - 1
+}
+
+// Draws one horizontal bar per channel, side by side and one cell apart,
+// filled to levels (0..1) in eighth-cell steps with a marker at each peak.
+// Cells toward full scale turn yellow, then red.
+func (r *Renderer) VUMeter(x, y, barW int, levels, peaks []float64, bg tcell.Color) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.screen == nil || r.closed || barW <= 0 {
+		return
+	}
+	w, h := r.screen.Size()
+	if y < 0 || y >= h {
+		return
+	}
+
+	for c, level := range levels {
+		left := x + c*(barW+1)
+		filled := int(min(max(level, 0), 1) * float64(barW*8))
+		peak := -1
+		if c < len(peaks) && peaks[c] > 0 {
+			peak = min(int(peaks[c]*float64(barW)), barW-1)
+		}
+		for i := range barW {
+			cx := left + i
+			if cx < 0 || cx >= w {
+				continue
+			}
+			color := tcell.ColorGreen
+			switch {
+			case i >= barW*9/10:
+				color = tcell.ColorRed
+			case i >= barW*3/4:
+				color = tcell.ColorYellow
+			}
+			style := tcell.StyleDefault.Background(bg).Foreground(color)
+			ch := eighths[min(max(filled-i*8, 0), 8)]
+			if i == peak && ch == ' ' {
+				ch = '▏'
+			}
+			r.screen.SetContent(cx, y, ch, nil, style)
+		}
+	}
+}
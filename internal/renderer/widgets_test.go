@@ -0,0 +1,28 @@
+package renderer
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestProgressBarGolden(t *testing.T) {
+	tests := []struct {
+		name     string
+		progress float64
+		golden   string
+	}{
+		{"empty", 0, "progress_0"},
+		{"full", 1, "progress_100"},
+		// Out of range values clamp to the ends
+		{"below 0", -0.5, "progress_0"},
+		{"above 1", 1.5, "progress_100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := newTestRenderer(t, 12, 1)
+			r.ProgressBar(0, tt.progress, tcell.ColorGreen, tcell.ColorDarkGray)
+			checkGolden(t, tt.golden, formatGrid(r.Snapshot()))
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package resume
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mpv's watch_later directory: one file per video, named by the upper-case
+// hex MD5 of its path, holding "key=value" lines such as start=123.456000
+type MPV struct {
+	dir string
+}
+
+// Returns mpv's watch_later directory. mpv 0.36 moved it from
+// ~/.config/mpv to ~/.local/state/mpv; the newer one wins if it exists.
+func MPVDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	state := os.Getenv("XDG_STATE_HOME")
+	if state == "" {
+		state = filepath.Join(home, ".local", "state")
+	}
+	if dir := filepath.Join(state, "mpv", "watch_later"); isDir(dir) {
+		return dir
+	}
+	config := os.Getenv("XDG_CONFIG_HOME")
+	if config == "" {
+		config = filepath.Join(home, ".config")
+	}
+	return filepath.Join(config, "mpv", "watch_later")
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func OpenMPV(dir string) *MPV {
+	return &MPV{dir: dir}
+}
+
+// Returns the file mpv keeps video's state in
+func (m *MPV) file(video string) string {
+	sum := md5.Sum([]byte(Key(video)))
+	return filepath.Join(m.dir, strings.ToUpper(hex.EncodeToString(sum[:])))
+}
+
+// Returns the start position mpv saved for video
+func (m *MPV) Get(video string) (time.Duration, bool) {
+	data, err := os.ReadFile(m.file(video))
+	if err != nil {
+		return 0, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		value, ok := strings.CutPrefix(strings.TrimSpace(line), "start=")
+		if !ok {
+			continue
+		}
+		secs, err := strconv.ParseFloat(value, 64)
+		if err != nil || secs <= 0 {
+			return 0, false
+		}
+		return time.Duration(secs * float64(time.Second)), true
+	}
+	return 0, false
+}
+
+// Saves pos as video's start, keeping every other property mpv stored
+func (m *MPV) Set(video string, pos time.Duration) error {
+	return m.rewrite(video, fmt.Sprintf("start=%f", pos.Seconds()))
+}
+
+// Removes video's start, keeping every other property mpv stored
+func (m *MPV) Delete(video string) error {
+	return m.rewrite(video, "")
+}
+
+// Replaces the start line with start (dropping it if empty). A new file
+// gets the "# path" comment mpv writes, so it stays recognizable.
+func (m *MPV) rewrite(video, start string) error {
+	path := m.file(video)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if start == "" {
+			return nil
+		}
+		data = []byte("# " + Key(video) + "\n")
+	} else if err != nil {
+		return err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "start=") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if start != "" {
+		lines = append(lines, start)
+	}
+	return writeAtomic(path, []byte(strings.Join(lines, "\n")+"\n"))
+}
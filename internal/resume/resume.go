@@ -0,0 +1,255 @@
+// Package resume remembers where playback of each file stopped, the
+// settings it was played with and its markers, in pixlgo's own state file and optionally
+// (positions only) in mpv's watch_later directory.
+package resume
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/chapters"
+)
+
+// Positions closer than this to either end aren't worth resuming
+const MinRemaining = 5 * time.Second
+
+// Schema version of the state file. Files written by a newer version are
+// read for the fields this one knows but never overwritten.
+const Version = 2
+
+// ErrNewerVersion is returned when saving to a state file written by a newer
+// pixlgo, which would lose the fields it added
+var ErrNewerVersion = errors.New("state file is from a newer pixlgo version")
+
+// pixlgo's native state file: per-file entries keyed by absolute path
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+// On-disk layout. Version 1 files were the bare Files map with positions.
+type stateFile struct {
+	Version int              `json:"version"`
+	Files   map[string]entry `json:"files"`
+}
+
+type entry struct {
+	Position float64   `json:"position,omitempty"`
+	Updated  time.Time `json:"updated"`
+	Settings *Settings `json:"settings,omitempty"`
+	Markers  []marker  `json:"markers,omitempty"`
+}
+
+type marker struct {
+	Position float64 `json:"position"`
+	Title    string  `json:"title"`
+}
+
+// Playback settings remembered per file. The zero value means defaults.
+type Settings struct {
+	AutoLevels bool `json:"auto_levels,omitempty"`
+}
+
+// Returns the default state file, e.g. ~/.config/pixlgo/resume.json
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "pixlgo", "resume.json")
+}
+
+// Returns a store backed by the JSON file at path, created on first Set
+func Open(path string) *Store {
+	return &Store{path: path}
+}
+
+// Returns the saved position for video
+func (s *Store) Get(video string) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, err := s.load()
+	if err != nil {
+		return 0, false
+	}
+	e, ok := state.Files[Key(video)]
+	if !ok || e.Position <= 0 {
+		return 0, false
+	}
+	return time.Duration(e.Position * float64(time.Second)), true
+}
+
+// Saves pos for video
+func (s *Store) Set(video string, pos time.Duration) error {
+	return s.update(video, func(e *entry) {
+		e.Position = pos.Seconds()
+	})
+}
+
+// Forgets the position of video, e.g. once it was played to the end. Its
+// settings and markers are kept.
+func (s *Store) Delete(video string) error {
+	return s.update(video, func(e *entry) {
+		e.Position = 0
+	})
+}
+
+// Returns the saved settings for video
+func (s *Store) Settings(video string) (Settings, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, err := s.load()
+	if err != nil {
+		return Settings{}, false
+	}
+	e, ok := state.Files[Key(video)]
+	if !ok || e.Settings == nil {
+		return Settings{}, false
+	}
+	return *e.Settings, true
+}
+
+// Saves the settings for video; the zero value forgets them
+func (s *Store) SetSettings(video string, st Settings) error {
+	return s.update(video, func(e *entry) {
+		e.Settings = nil
+		if st != (Settings{}) {
+			e.Settings = &st
+		}
+	})
+}
+
+// Returns the markers saved for video, sorted by position
+func (s *Store) Markers(video string) []chapters.Marker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, err := s.load()
+	if err != nil {
+		return nil
+	}
+	var markers []chapters.Marker
+	for _, m := range state.Files[Key(video)].Markers {
+		markers = append(markers, chapters.Marker{
+			Pos:   time.Duration(m.Position * float64(time.Second)),
+			Title: m.Title,
+		})
+	}
+	chapters.Sort(markers)
+	return markers
+}
+
+// Replaces the markers saved for video
+func (s *Store) SetMarkers(video string, markers []chapters.Marker) error {
+	return s.update(video, func(e *entry) {
+		e.Markers = nil
+		for _, m := range markers {
+			e.Markers = append(e.Markers, marker{Position: m.Pos.Seconds(), Title: m.Title})
+		}
+	})
+}
+
+// Applies fn to the entry for video and writes the file, dropping the entry
+// once it holds nothing
+func (s *Store) update(video string, fn func(*entry)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, err := s.load()
+	if err != nil {
+		return err
+	}
+	if state.Version > Version {
+		return fmt.Errorf("%w (%s has version %d)", ErrNewerVersion, s.path, state.Version)
+	}
+
+	key := Key(video)
+	e, existed := state.Files[key]
+	fn(&e)
+	if e.Position <= 0 && e.Settings == nil && len(e.Markers) == 0 {
+		if !existed {
+			return nil
+		}
+		delete(state.Files, key)
+	} else {
+		e.Updated = time.Now().UTC()
+		state.Files[key] = e
+	}
+
+	state.Version = Version
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeAtomic(s.path, append(data, '\n'))
+}
+
+// Reads the state file, upgrading the version 1 layout
+func (s *Store) load() (*stateFile, error) {
+	state := &stateFile{Version: Version, Files: map[string]entry{}}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var probe struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	}
+	if probe.Version == 0 {
+		err = json.Unmarshal(data, &state.Files)
+		state.Version = 1
+	} else {
+		err = json.Unmarshal(data, state)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if state.Files == nil {
+		state.Files = map[string]entry{}
+	}
+	return state, nil
+}
+
+// Returns the key a video is stored under: its absolute, cleaned path, or
+// the input unchanged for URLs
+func Key(video string) string {
+	if strings.Contains(video, "://") {
+		return video
+	}
+	if abs, err := filepath.Abs(video); err == nil {
+		return abs
+	}
+	return filepath.Clean(video)
+}
+
+// Replaces path by writing a temporary file and renaming it, so a crash
+// mid-write never leaves a truncated file behind
+func writeAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
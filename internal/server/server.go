@@ -0,0 +1,254 @@
+// Package server streams a video as ANSI half blocks to telnet and raw TCP
+// clients, in the spirit of parrot.live.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/logger"
+	"github.com/0bVdnt/PixlGo/internal/renderer"
+)
+
+// Holds server limits and defaults
+type Config struct {
+	// Connections beyond this are turned away (0 = unlimited)
+	MaxClients int
+	// A client that accepts no output for this long is disconnected
+	IdleTimeout time.Duration
+	// Size in cells for clients that don't report one via NAWS
+	Cols, Rows int
+
+	Logger *logger.Logger
+}
+
+// Largest window size accepted from a client
+const (
+	maxCols = 400
+	maxRows = 200
+)
+
+type Server struct {
+	src     *Source
+	config  Config
+	log     *logger.Logger
+	clients atomic.Int32
+	wg      sync.WaitGroup
+}
+
+// Creates a server that sends frames from src
+func New(src *Source, config Config) *Server {
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = 30 * time.Second
+	}
+	log := config.Logger
+	if log == nil {
+		log = logger.Noop()
+	}
+	return &Server{src: src, config: config, log: log}
+}
+
+// Returns the number of connected clients
+func (s *Server) Clients() int {
+	return int(s.clients.Load())
+}
+
+// Accepts connections until ctx is cancelled, then disconnects every client
+// and returns once they are gone. Closes ln.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	defer s.wg.Wait()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return err
+		}
+
+		if max := s.config.MaxClients; max > 0 && int(s.clients.Load()) >= max {
+			s.log.Warn("client rejected, server full", "remote", conn.RemoteAddr().String())
+			conn.SetWriteDeadline(time.Now().Add(time.Second))
+			fmt.Fprint(conn, "Server full, try again later.\r\n")
+			conn.Close()
+			continue
+		}
+
+		s.clients.Add(1)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.clients.Add(-1)
+			s.handle(ctx, conn)
+		}()
+	}
+}
+
+// One connected viewer
+type client struct {
+	conn    net.Conn
+	size    atomic.Uint64 // cols<<32 | rows
+	resized atomic.Bool
+}
+
+func (c *client) setSize(cols, rows int) {
+	cols = min(cols, maxCols)
+	rows = min(rows, maxRows)
+	c.size.Store(uint64(cols)<<32 | uint64(rows))
+	c.resized.Store(true)
+}
+
+func (c *client) getSize() (int, int) {
+	v := c.size.Load()
+	return int(v >> 32), int(v & 0xffffffff)
+}
+
+func (s *Server) handle(ctx context.Context, conn net.Conn) {
+	remote := conn.RemoteAddr().String()
+	s.log.Info("client connected", "remote", remote, "clients", s.Clients())
+	start := time.Now()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	c := &client{conn: conn}
+	c.setSize(s.config.Cols, s.config.Rows)
+
+	// Closing the connection also unblocks the reader and any pending write
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	go s.readLoop(c, cancel)
+
+	frames, err := s.writeLoop(ctx, c)
+	if err != nil && ctx.Err() == nil {
+		s.log.Debug("client write failed", "remote", remote, "err", err)
+	}
+	cancel()
+	s.log.Info("client disconnected", "remote", remote, "frames", frames,
+		"connected", time.Since(start).Round(time.Second).String())
+}
+
+// Handles keys and window size reports until the client goes away
+func (s *Server) readLoop(c *client, cancel context.CancelFunc) {
+	defer cancel()
+	var parser telnetParser
+	buf := make([]byte, 256)
+	quit := false
+	for !quit {
+		n, err := c.conn.Read(buf)
+		parser.feed(buf[:n], func(b byte) {
+			switch b {
+			case 'q', 'Q', 3, 4: // Ctrl-C, Ctrl-D
+				quit = true
+			}
+		}, c.setSize)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Sends the latest frame whenever one is ready. Frames published while a
+// write is in progress are skipped, so a slow client only slows itself.
+func (s *Server) writeLoop(ctx context.Context, c *client) (int, error) {
+	hello := append([]byte(nil), negotiation...)
+	hello = append(hello, "\x1b[?25l\x1b[2J"...)
+	if err := s.write(c, hello); err != nil {
+		return 0, err
+	}
+	defer func() {
+		c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+		c.conn.Write([]byte("\x1b[0m\x1b[2J\x1b[H\x1b[?25h"))
+	}()
+
+	var seq uint64
+	frames := 0
+	for {
+		frame, next, err := s.src.Next(ctx, seq)
+		if err != nil {
+			return frames, err
+		}
+		seq = next
+
+		var out strings.Builder
+		if c.resized.Swap(false) {
+			out.WriteString("\x1b[0m\x1b[2J")
+		}
+		cols, rows := c.getSize()
+		drawFrame(&out, frame, cols, rows)
+		if err := s.write(c, []byte(out.String())); err != nil {
+			return frames, err
+		}
+		frames++
+	}
+}
+
+func (s *Server) write(c *client, p []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(s.config.IdleTimeout))
+	_, err := c.conn.Write(p)
+	return err
+}
+
+// Renders frame scaled to fit cols x rows cells, centered
+func drawFrame(out *strings.Builder, frame *image.RGBA, cols, rows int) {
+	img := scaleToFit(frame, cols, rows*2)
+	w, h := img.Rect.Dx(), (img.Rect.Dy()+1)/2
+	x := (cols-w)/2 + 1
+	y := (rows-h)/2 + 1
+
+	fmt.Fprintf(out, "\x1b[%d;%dH", y, x)
+	lines := strings.Split(strings.TrimSuffix(renderer.RenderColor(img), "\n"), "\n")
+	for i, line := range lines {
+		if i > 0 {
+			// No trailing newline on the last row, so a full-height frame
+			// doesn't scroll the client's screen
+			fmt.Fprintf(out, "\r\n\x1b[%dG", x)
+		}
+		out.WriteString(line)
+	}
+}
+
+// Scales src with nearest-neighbour sampling to the largest size that fits
+// maxW x maxH pixels and keeps the aspect ratio
+func scaleToFit(src *image.RGBA, maxW, maxH int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if sw == 0 || sh == 0 {
+		return src
+	}
+	w, h := maxW, sh*maxW/sw
+	if h > maxH {
+		w, h = sw*maxH/sh, maxH
+	}
+	w, h = max(w, 1), max(h, 1)
+	if w == sw && h == sh {
+		return src
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		srow := src.Pix[(y*sh/h)*src.Stride:]
+		drow := dst.Pix[y*dst.Stride:]
+		for x := range w {
+			sx := (x * sw / w) * 4
+			copy(drow[x*4:x*4+4], srow[sx:sx+4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"sync"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/video"
+)
+
+// Decodes a video once, in real time and looping, and keeps only the latest
+// frame. Clients that fall behind skip straight to it.
+type Source struct {
+	mu    sync.Mutex
+	frame *image.RGBA
+	seq   uint64
+	ready chan struct{} // closed and replaced on every new frame
+}
+
+func NewSource() *Source {
+	return &Source{ready: make(chan struct{})}
+}
+
+// Decodes path until ctx is cancelled, starting over at the end. Returns
+// an error if a pass produces no frames at all.
+func (s *Source) Run(ctx context.Context, path string, config video.StreamConfig) error {
+	for {
+		start := time.Now()
+		frames := 0
+		err := video.DecodeAll(ctx, path, config, func(f *video.Frame) error {
+			// Pace to the media clock; DecodeAll runs as fast as ffmpeg does
+			due := start.Add(f.Timestamp - config.StartPos)
+			if wait := time.Until(due); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			s.publish(f.Image)
+			frames++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if frames == 0 {
+			return fmt.Errorf("%w: no frames", video.ErrDecodeFailed)
+		}
+	}
+}
+
+// Stores a copy of img as the latest frame and wakes waiting clients
+func (s *Source) publish(img *image.RGBA) {
+	cp := image.NewRGBA(img.Rect)
+	copy(cp.Pix, img.Pix)
+
+	s.mu.Lock()
+	s.frame = cp
+	s.seq++
+	close(s.ready)
+	s.ready = make(chan struct{})
+	s.mu.Unlock()
+}
+
+// Waits for a frame newer than seq. Published frames are never modified,
+// so the caller may keep using the image.
+func (s *Source) Next(ctx context.Context, seq uint64) (*image.RGBA, uint64, error) {
+	for {
+		s.mu.Lock()
+		frame, cur, ready := s.frame, s.seq, s.ready
+		s.mu.Unlock()
+		if cur > seq && frame != nil {
+			return frame, cur, nil
+		}
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return nil, seq, ctx.Err()
+		}
+	}
+}
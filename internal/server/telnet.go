@@ -0,0 +1,101 @@
+package server
+
+// Telnet commands and options (RFC 854, 857, 858, 1073)
+const (
+	iac  = 255
+	dont = 254
+	do   = 253
+	wont = 252
+	will = 251
+	sb   = 250
+	se   = 240
+
+	optEcho = 1
+	optSGA  = 3
+	optNAWS = 31
+)
+
+// Sent on connect: the server echoes (so the client stops echoing keys),
+// suppresses go-ahead (character mode) and asks for the window size
+var negotiation = []byte{
+	iac, will, optEcho,
+	iac, will, optSGA,
+	iac, do, optNAWS,
+}
+
+// Splits a byte stream from a telnet client into keystrokes and window
+// size reports. Raw TCP clients that never send IAC work too.
+type telnetParser struct {
+	state int
+	sub   []byte
+}
+
+const (
+	tsData = iota
+	tsIAC
+	tsOption // after WILL/WONT/DO/DONT
+	tsSub
+	tsSubIAC
+)
+
+// Feeds received bytes. key is called for each data byte, resize for each
+// NAWS report with the client's width and height in cells.
+func (t *telnetParser) feed(p []byte, key func(byte), resize func(w, h int)) {
+	for _, b := range p {
+		switch t.state {
+		case tsData:
+			if b == iac {
+				t.state = tsIAC
+			} else {
+				key(b)
+			}
+		case tsIAC:
+			switch b {
+			case will, wont, do, dont:
+				t.state = tsOption
+			case sb:
+				t.sub = t.sub[:0]
+				t.state = tsSub
+			case iac:
+				// Escaped 255 data byte
+				key(b)
+				t.state = tsData
+			default:
+				t.state = tsData
+			}
+		case tsOption:
+			t.state = tsData
+		case tsSub:
+			if b == iac {
+				t.state = tsSubIAC
+			} else if len(t.sub) < 64 {
+				t.sub = append(t.sub, b)
+			}
+		case tsSubIAC:
+			switch b {
+			case se:
+				t.endSub(resize)
+				t.state = tsData
+			case iac:
+				// Escaped 255 inside the subnegotiation, e.g. a width of 255
+				if len(t.sub) < 64 {
+					t.sub = append(t.sub, b)
+				}
+				t.state = tsSub
+			default:
+				t.state = tsData
+			}
+		}
+	}
+}
+
+func (t *telnetParser) endSub(resize func(w, h int)) {
+	if len(t.sub) != 5 || t.sub[0] != optNAWS {
+		return
+	}
+	w := int(t.sub[1])<<8 | int(t.sub[2])
+	h := int(t.sub[3])<<8 | int(t.sub[4])
+	if w > 0 && h > 0 {
+		resize(w, h)
+	}
+}
@@ -0,0 +1,133 @@
+package sheet
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/bitfont"
+	"github.com/0bVdnt/PixlGo/internal/renderer"
+	"github.com/0bVdnt/PixlGo/internal/video"
+)
+
+// Fraction skipped at each end when spacing thumbnails, to avoid black
+// lead-in frames and credits
+const EdgeSkip = 0.02
+
+// A grid of thumbnails with timestamp labels
+type Sheet struct {
+	Cols   int
+	Frames []*video.Frame
+	Labels []string
+}
+
+// Returns n evenly spaced timestamps inside duration, skipping EdgeSkip at
+// both ends. Each timestamp is the middle of its slice.
+func Timestamps(duration time.Duration, n int) []time.Duration {
+	if n <= 0 {
+		return nil
+	}
+	if duration <= 0 {
+		return []time.Duration{0}
+	}
+	start := time.Duration(float64(duration) * EdgeSkip)
+	span := duration - 2*start
+	step := span / time.Duration(n)
+
+	ts := make([]time.Duration, n)
+	for i := range ts {
+		ts[i] = start + step*time.Duration(i) + step/2
+	}
+	return ts
+}
+
+// Reduces a cols x rows grid so it holds at most maxCells thumbnails,
+// dropping rows first
+func FitGrid(cols, rows, maxCells int) (int, int) {
+	maxCells = max(maxCells, 1)
+	for cols*rows > maxCells && rows > 1 {
+		rows--
+	}
+	if cols*rows > maxCells {
+		cols = maxCells
+	}
+	return cols, rows
+}
+
+var (
+	background = color.RGBA{16, 16, 16, 255}
+	labelBack  = color.RGBA{0, 0, 0, 255}
+	labelText  = color.RGBA{255, 255, 255, 255}
+)
+
+// Draws the sheet as an image with gap pixels around each tile and the
+// label in each tile's bottom-left corner
+func (s *Sheet) Image(gap int) *image.RGBA {
+	if len(s.Frames) == 0 || s.Cols <= 0 {
+		return nil
+	}
+	tile := s.Frames[0].Image.Bounds()
+	cols := min(s.Cols, len(s.Frames))
+	rows := (len(s.Frames) + cols - 1) / cols
+
+	out := image.NewRGBA(image.Rect(0, 0,
+		cols*tile.Dx()+(cols+1)*gap, rows*tile.Dy()+(rows+1)*gap))
+	bitfont.Fill(out, out.Bounds(), background)
+
+	scale := max(tile.Dy()/60, 1)
+	for i, f := range s.Frames {
+		x := gap + (i%cols)*(tile.Dx()+gap)
+		y := gap + (i/cols)*(tile.Dy()+gap)
+		draw.Draw(out, image.Rect(x, y, x+tile.Dx(), y+tile.Dy()), f.Image, tile.Min, draw.Src)
+
+		if i < len(s.Labels) && s.Labels[i] != "" {
+			w, h := bitfont.TextSize(s.Labels[i], scale)
+			pad := scale
+			bottom := y + tile.Dy()
+			bitfont.Fill(out, image.Rect(x, bottom-h-2*pad, x+w+2*pad, bottom), labelBack)
+			bitfont.Draw(out, x+pad, bottom-h-pad, s.Labels[i], scale, labelText)
+		}
+	}
+	return out
+}
+
+// Renders the sheet as ANSI half blocks, one row of thumbnails at a time
+// with the labels printed underneath each thumbnail
+func (s *Sheet) ANSI() string {
+	if len(s.Frames) == 0 || s.Cols <= 0 {
+		return ""
+	}
+	tileW := s.Frames[0].Image.Bounds().Dx()
+	var sb strings.Builder
+
+	for start := 0; start < len(s.Frames); start += s.Cols {
+		row := s.Frames[start:min(start+s.Cols, len(s.Frames))]
+		tiles := make([][]string, len(row))
+		for i, f := range row {
+			tiles[i] = strings.Split(strings.TrimSuffix(renderer.RenderColor(f.Image), "\n"), "\n")
+		}
+		for line := range tiles[0] {
+			for i := range tiles {
+				if i > 0 {
+					sb.WriteByte(' ')
+				}
+				sb.WriteString(tiles[i][line])
+			}
+			sb.WriteByte('\n')
+		}
+		for i := range row {
+			label := ""
+			if start+i < len(s.Labels) {
+				label = renderer.Truncate(s.Labels[start+i], tileW)
+			}
+			if i > 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(label + strings.Repeat(" ", tileW-renderer.TextWidth(label)))
+		}
+		sb.WriteString("\n\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,206 @@
+// Package subtitle reads SubRip (.srt) files into timed cues for drawing
+// over the video, without involving ffmpeg.
+package subtitle
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// A run of cue text in one style
+type Span struct {
+	Text   string
+	Italic bool
+	Bold   bool
+}
+
+// One subtitle: shown from Start until just before End. Each line is a
+// list of styled spans; tags other than <i>, <b> and <u> are dropped.
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Lines [][]Span
+}
+
+// Returns the cue's text without styling, lines joined with \n
+func (c *Cue) Text() string {
+	lines := make([]string, len(c.Lines))
+	for i, line := range c.Lines {
+		var sb strings.Builder
+		for _, s := range line {
+			sb.WriteString(s.Text)
+		}
+		lines[i] = sb.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Cues of a subtitle file, sorted by start time
+type Track struct {
+	Cues []Cue
+}
+
+// Reads an SRT file
+func Load(path string) (*Track, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	track, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return track, nil
+}
+
+// Parses SRT data. Blocks without a valid timing line are skipped; data
+// with no cues at all is an error.
+func Parse(r io.Reader) (*Track, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("not UTF-8 text")
+	}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	track := &Track{}
+	var cue *Cue
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t")
+		if start, end, ok := parseTiming(line); ok {
+			if cue != nil {
+				// Without a blank line before it, the next cue's counter
+				// ended up as this one's last text line
+				if n := len(cue.Lines); n > 0 && isCounter(cue.Lines[n-1]) {
+					cue.Lines = cue.Lines[:n-1]
+				}
+				track.add(cue)
+			}
+			cue = &Cue{Start: start, End: end}
+			continue
+		}
+		if cue == nil {
+			continue
+		}
+		if line == "" {
+			track.add(cue)
+			cue = nil
+			continue
+		}
+		cue.Lines = append(cue.Lines, parseTags(line))
+	}
+	if cue != nil {
+		track.add(cue)
+	}
+	if len(track.Cues) == 0 {
+		return nil, fmt.Errorf("no subtitle cues found")
+	}
+	sort.SliceStable(track.Cues, func(i, j int) bool {
+		return track.Cues[i].Start < track.Cues[j].Start
+	})
+	return track, nil
+}
+
+// Adds cue unless it is empty
+func (t *Track) add(cue *Cue) {
+	if len(cue.Lines) == 0 || cue.End <= cue.Start {
+		return
+	}
+	t.Cues = append(t.Cues, *cue)
+}
+
+func isCounter(line []Span) bool {
+	if len(line) != 1 || line[0].Italic || line[0].Bold {
+		return false
+	}
+	_, err := strconv.Atoi(line[0].Text)
+	return err == nil
+}
+
+// Returns the cues showing at pos, in start order; overlapping cues are all
+// returned
+func (t *Track) At(pos time.Duration) []*Cue {
+	if t == nil {
+		return nil
+	}
+	// Cues starting after pos can't show; earlier ones may still run
+	n := sort.Search(len(t.Cues), func(i int) bool { return t.Cues[i].Start > pos })
+	var active []*Cue
+	for i := range t.Cues[:n] {
+		if t.Cues[i].End > pos {
+			active = append(active, &t.Cues[i])
+		}
+	}
+	return active
+}
+
+var timingRE = regexp.MustCompile(`^\s*(\d+):(\d{1,2}):(\d{1,2})[,.](\d{1,3})\s*-->\s*(\d+):(\d{1,2}):(\d{1,2})[,.](\d{1,3})`)
+
+// Parses "00:01:02,500 --> 00:01:04,000", ignoring position hints after it
+func parseTiming(line string) (start, end time.Duration, ok bool) {
+	m := timingRE.FindStringSubmatch(line)
+	if m == nil {
+		return 0, 0, false
+	}
+	return timestamp(m[1:5]), timestamp(m[5:9]), true
+}
+
+func timestamp(parts []string) time.Duration {
+	h, _ := strconv.Atoi(parts[0])
+	m, _ := strconv.Atoi(parts[1])
+	s, _ := strconv.Atoi(parts[2])
+	// "5" after the comma is 500ms, as some writers drop trailing zeros
+	frac := parts[3] + strings.Repeat("0", 3-len(parts[3]))
+	ms, _ := strconv.Atoi(frac)
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute +
+		time.Duration(s)*time.Second + time.Duration(ms)*time.Millisecond
+}
+
+var tagRE = regexp.MustCompile(`(?i)</?[a-z][^>]*>|\{\\[^}]*\}`)
+
+// Splits a text line into spans at <i> and <b> tags. <u> counts as italic,
+// the closest a terminal cell style reliably shows; <font> and ASS override
+// tags like {\an8} are dropped.
+func parseTags(line string) []Span {
+	var spans []Span
+	var italic, bold bool
+	emit := func(text string) {
+		if text == "" {
+			return
+		}
+		if n := len(spans); n > 0 && spans[n-1].Italic == italic && spans[n-1].Bold == bold {
+			spans[n-1].Text += text
+			return
+		}
+		spans = append(spans, Span{Text: text, Italic: italic, Bold: bold})
+	}
+	last := 0
+	for _, loc := range tagRE.FindAllStringIndex(line, -1) {
+		emit(line[last:loc[0]])
+		last = loc[1]
+		switch strings.ToLower(line[loc[0]:loc[1]]) {
+		case "<i>", "<u>":
+			italic = true
+		case "</i>", "</u>":
+			italic = false
+		case "<b>":
+			bold = true
+		case "</b>":
+			bold = false
+		}
+	}
+	emit(line[last:])
+	return spans
+}
@@ -0,0 +1,95 @@
+// Package timecode parses and formats playback positions as users type
+// them on the command line and in control commands.
+package timecode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parses a position given as seconds ("83.5"), [[h:]m:]s ("1:23:45.5") or
+// a Go duration ("1m23s")
+func Parse(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty timestamp")
+	}
+	if d, err := time.ParseDuration(s); err == nil && strings.ContainsAny(s, "hms") {
+		if d < 0 {
+			return 0, fmt.Errorf("negative timestamp %q", s)
+		}
+		return d, nil
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+
+	var total float64
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("invalid timestamp %q", s)
+		}
+		// Only the last field may have a fraction; minutes/seconds under 60
+		last := i == len(parts)-1
+		if !last && v != float64(int(v)) || i > 0 && v >= 60 {
+			return 0, fmt.Errorf("invalid timestamp %q", s)
+		}
+		total = total*60 + v
+	}
+	return time.Duration(total * float64(time.Second)), nil
+}
+
+// Formats d as [h:]m:ss.mmm, which Parse accepts
+func Format(d time.Duration) string {
+	d = max(d, 0).Round(time.Millisecond)
+	h := d / time.Hour
+	m := (d % time.Hour) / time.Minute
+	s := (d % time.Minute) / time.Second
+	ms := (d % time.Second) / time.Millisecond
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d.%03d", h, m, s, ms)
+	}
+	return fmt.Sprintf("%d:%02d.%03d", m, s, ms)
+}
+
+// Default layout for FormatLayout
+const DefaultLayout = "%H:%M:%S.%L"
+
+// Formats d with a layout of literal text and the tokens %H (hours), %M
+// (minutes), %S (seconds), %L (milliseconds), %s (whole seconds in total)
+// and %%. Fields other than %s are zero-padded.
+func FormatLayout(d time.Duration, layout string) string {
+	d = max(d, 0).Truncate(time.Millisecond)
+	var sb strings.Builder
+	for i := 0; i < len(layout); i++ {
+		c := layout[i]
+		if c != '%' || i+1 == len(layout) {
+			sb.WriteByte(c)
+			continue
+		}
+		i++
+		switch layout[i] {
+		case 'H':
+			fmt.Fprintf(&sb, "%02d", d/time.Hour)
+		case 'M':
+			fmt.Fprintf(&sb, "%02d", (d%time.Hour)/time.Minute)
+		case 'S':
+			fmt.Fprintf(&sb, "%02d", (d%time.Minute)/time.Second)
+		case 'L':
+			fmt.Fprintf(&sb, "%03d", (d%time.Second)/time.Millisecond)
+		case 's':
+			fmt.Fprintf(&sb, "%d", d/time.Second)
+		case '%':
+			sb.WriteByte('%')
+		default:
+			sb.WriteByte('%')
+			sb.WriteByte(layout[i])
+		}
+	}
+	return sb.String()
+}
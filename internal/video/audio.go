@@ -0,0 +1,194 @@
+package video
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Sample rate of the level tap; plenty for metering and cheap to decode
+const tapSampleRate = 8000
+
+// Levels below this read as silence on the meter
+const MeterFloorDB = -48
+
+// How long a peak marker holds before falling back
+const peakHold = time.Second
+
+// Levels a meter reports without fresh samples are treated as stale
+const meterStale = 300 * time.Millisecond
+
+// Per-channel audio levels, 0 (MeterFloorDB or quieter) to 1 (full scale)
+type Levels struct {
+	Channels int // 1 or 2
+	Level    [2]float64
+	Peak     [2]float64
+}
+
+// Turns PCM from an audio tap into meter levels. Safe for concurrent use.
+type LevelMeter struct {
+	mu       sync.Mutex
+	levels   Levels
+	peakAt   [2]time.Time
+	updated  time.Time
+	channels int
+}
+
+func newLevelMeter(channels int) *LevelMeter {
+	return &LevelMeter{channels: channels}
+}
+
+// Returns the latest levels, or false when no tap has delivered samples
+// recently (paused, seeking, or the audio ended). A nil meter is never
+// active.
+func (m *LevelMeter) Current() (Levels, bool) {
+	if m == nil {
+		return Levels{}, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if time.Since(m.updated) > meterStale {
+		return Levels{Channels: m.channels}, false
+	}
+	return m.levels, true
+}
+
+// Records one block of interleaved signed 16-bit samples
+func (m *LevelMeter) add(samples []int16) {
+	var sum [2]float64
+	n := len(samples) / m.channels
+	if n == 0 {
+		return
+	}
+	for i := 0; i < n*m.channels; i++ {
+		v := float64(samples[i]) / 32768
+		sum[i%m.channels] += v * v
+	}
+
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.levels.Channels = m.channels
+	for c := range m.channels {
+		level := meterScale(math.Sqrt(sum[c] / float64(n)))
+		m.levels.Level[c] = level
+		if level >= m.levels.Peak[c] || now.Sub(m.peakAt[c]) > peakHold {
+			m.levels.Peak[c] = level
+			m.peakAt[c] = now
+		}
+	}
+	m.updated = now
+}
+
+// Clears levels and peaks, e.g. when a seek restarts the tap
+func (m *LevelMeter) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.levels = Levels{Channels: m.channels}
+	m.updated = time.Time{}
+}
+
+// Maps an RMS amplitude onto 0..1 on a dB scale from MeterFloorDB to 0dBFS
+func meterScale(rms float64) float64 {
+	if rms <= 0 {
+		return 0
+	}
+	db := 20 * math.Log10(rms)
+	return min(max(1-db/MeterFloorDB, 0), 1)
+}
+
+// An ffmpeg process decoding an audio stream to PCM in real time for a
+// LevelMeter
+type audioTap struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Starts decoding audio stream track from startPos, paced to real time
+// with -re
+func startAudioTap(ctx context.Context, path string, track int, startPos time.Duration, loop int, meter *LevelMeter, logs Logs) (*audioTap, error) {
+	input, err := InputArg(path)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"-re"}
+	if startPos > 0 {
+		args = append(args, "-ss", fmt.Sprintf("%.3f", startPos.Seconds()))
+	}
+	args = append(args, inputOptions(input)...)
+	args = append(args, loopArgs(loop)...)
+	args = append(args,
+		"-i", input,
+		"-map", audioMap(track),
+		"-vn", "-sn",
+		"-ac", strconv.Itoa(meter.channels),
+		"-ar", strconv.Itoa(tapSampleRate),
+		"-f", "s16le",
+		"-loglevel", "error",
+		"-",
+	)
+
+	tapCtx, cancel := context.WithCancel(ctx)
+	cmd := newInputCommand(tapCtx, "ffmpeg", args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("audio tap: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("audio tap: %w", err)
+	}
+
+	meter.reset()
+	t := &audioTap{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(t.done)
+		// About 30 blocks per second, one per render tick
+		block := make([]int16, tapSampleRate/30*meter.channels)
+		reader := bufio.NewReader(stdout)
+		for {
+			if err := binary.Read(reader, binary.LittleEndian, block); err != nil {
+				if err != io.EOF && err != io.ErrUnexpectedEOF && tapCtx.Err() == nil {
+					logs.Debug("Audio tap stopped: %v", err)
+				}
+				break
+			}
+			meter.add(block)
+		}
+		cmd.Wait()
+	}()
+	return t, nil
+}
+
+// Kills the tap's ffmpeg and waits for its reader to finish
+func (t *audioTap) stop() {
+	t.cancel()
+	<-t.done
+}
+
+// Returns the ffmpeg -map specifier for audio stream track, counted from 0
+// among the audio streams
+func audioMap(track int) string {
+	return fmt.Sprintf("0:a:%d", track)
+}
+
+// Returns the audio streams of an input holding only audio, as yt-dlp
+// resolves some web pages to; nil when probing fails
+func probeAudioStreams(ctx context.Context, path string) []StreamInfo {
+	input, err := InputArg(path)
+	if err != nil {
+		return nil
+	}
+	meta := &Metadata{Path: path}
+	if err := probeVideoStream(ctx, input, nil, meta, &frameRates{}); err != nil {
+		return nil
+	}
+	return meta.StreamsOf("audio")
+}
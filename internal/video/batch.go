@@ -0,0 +1,70 @@
+package video
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Decodes one frame at each timestamp with a single ffmpeg process: every
+// timestamp opens the input with its own fast seek and the picked frames
+// are concatenated into one raw stream. Frames come back in timestamp
+// order; timestamps past the last frame yield no frame, so the result may
+// be shorter than timestamps.
+func ExtractFrames(ctx context.Context, path string, meta *Metadata, timestamps []time.Duration, width, height int) ([]*Frame, error) {
+	if len(timestamps) == 0 {
+		return nil, nil
+	}
+	width, height = FitSize(width, height)
+
+	input, err := InputArg(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second+time.Duration(len(timestamps))*time.Second)
+	defer cancel()
+
+	var args []string
+	var filter strings.Builder
+	for i, ts := range timestamps {
+		args = append(args, "-ss", fmt.Sprintf("%.3f", ts.Seconds()))
+		args = append(args, rotateArgs(meta.Rotation)...)
+		args = append(args, "-i", input)
+		fmt.Fprintf(&filter, "[%d:%d]trim=end_frame=1,%s,setsar=1,setpts=PTS-STARTPTS[v%d];",
+			i, meta.StreamIndex, withRotation(meta.Rotation, scaleFilter(width, height, meta.ColorRange)), i)
+	}
+	for i := range timestamps {
+		fmt.Fprintf(&filter, "[v%d]", i)
+	}
+	fmt.Fprintf(&filter, "concat=n=%d:v=1:a=0[out]", len(timestamps))
+
+	args = append(args,
+		"-filter_complex", filter.String(),
+		"-map", "[out]",
+		"-pix_fmt", "rgb24",
+		"-f", "rawvideo",
+		"-loglevel", "error",
+		"-",
+	)
+
+	out, err := newInputCommand(ctx, "ffmpeg", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("extract frames: %w", err)
+	}
+
+	frameSize := width * height * 3
+	frames := make([]*Frame, 0, len(timestamps))
+	for i := 0; i < len(timestamps) && (i+1)*frameSize <= len(out); i++ {
+		frames = append(frames, &Frame{
+			Image:     createRGBAFromRGB24(out[i*frameSize:(i+1)*frameSize], width, height),
+			Timestamp: timestamps[i],
+			Decoded:   time.Now(),
+		})
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("extract frames: no frames decoded")
+	}
+	return frames, nil
+}
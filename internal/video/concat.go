@@ -0,0 +1,198 @@
+package video
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Extension of the concat lists WriteConcatList writes
+const concatExt = ".ffconcat"
+
+var ErrConcatMismatch = errors.New("parts don't match")
+
+// The parts a list written by WriteConcatList joins, and where each starts
+// on the joined timeline
+type concatList struct {
+	key    string
+	parts  []string
+	starts []time.Duration
+	total  time.Duration
+}
+
+// Lists written in this session, by list path. Only these are opened with
+// the concat demuxer; a list from elsewhere could name any file or
+// protocol.
+var (
+	concatMu    sync.Mutex
+	concatLists = map[string]concatList{}
+)
+
+// Probes the local files parts and writes an ffmpeg concat list that plays
+// them end to end as one input, returning its path. The parts must share
+// codec and picture size, and audio codec if they have sound. The list is
+// a new temporary file for this process, which RemoveConcatList deletes;
+// StateKey gives a name for it that stays the same between runs.
+func WriteConcatList(ctx context.Context, parts []string) (string, error) {
+	if len(parts) < 2 {
+		return "", errors.New("concat needs at least two files")
+	}
+
+	var first *Metadata
+	list := concatList{key: concatKey(parts)}
+	var b strings.Builder
+	b.WriteString("ffconcat version 1.0\n")
+	for _, part := range parts {
+		if IsURL(part) || part == StdinPath {
+			return "", fmt.Errorf("concat: %s is not a local file", part)
+		}
+		meta, err := ProbeContext(ctx, part)
+		if err == nil {
+			err = meta.RequireVideo()
+		}
+		if err != nil {
+			return "", fmt.Errorf("concat: %s: %w", part, err)
+		}
+		if meta.Duration <= 0 || meta.Still {
+			return "", fmt.Errorf("concat: %s has no known duration", part)
+		}
+		if first == nil {
+			first = meta
+		} else if err := matchParts(first, meta); err != nil {
+			return "", err
+		}
+
+		input, err := InputArg(part)
+		if err != nil {
+			return "", err
+		}
+		// The durations let ffmpeg map positions to parts without opening
+		// every file first
+		fmt.Fprintf(&b, "file '%s'\nduration %s\n", strings.ReplaceAll(input, "'", `'\''`),
+			strconv.FormatFloat(meta.Duration.Seconds(), 'f', 6, 64))
+		list.parts = append(list.parts, part)
+		list.starts = append(list.starts, list.total)
+		list.total += meta.Duration
+	}
+
+	f, err := os.CreateTemp("", "pixlgo-concat-*"+concatExt)
+	if err != nil {
+		return "", fmt.Errorf("concat list: %w", err)
+	}
+	_, err = f.WriteString(b.String())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	path, aerr := filepath.Abs(f.Name())
+	if err == nil {
+		err = aerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("concat list: %w", err)
+	}
+
+	concatMu.Lock()
+	concatLists[path] = list
+	concatMu.Unlock()
+	return path, nil
+}
+
+// Deletes a list written by WriteConcatList
+func RemoveConcatList(path string) {
+	concatMu.Lock()
+	delete(concatLists, path)
+	concatMu.Unlock()
+	os.Remove(path)
+}
+
+// Returns the list registered for path, given as a path or in the form
+// InputArg turns it into
+func lookupConcat(path string) (concatList, bool) {
+	if p, ok := strings.CutPrefix(path, "file:"); ok {
+		path = filepath.FromSlash(p)
+	}
+	concatMu.Lock()
+	defer concatMu.Unlock()
+	list, ok := concatLists[path]
+	return list, ok
+}
+
+// Returns the name resume points and settings are kept under: a list
+// written by WriteConcatList is known by its parts, as its own path
+// changes between runs, and anything else by its path
+func StateKey(path string) string {
+	if list, ok := lookupConcat(path); ok {
+		return list.key
+	}
+	return path
+}
+
+// Returns a name for a list that depends only on its parts
+func concatKey(parts []string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		abs, err := filepath.Abs(part)
+		if err != nil {
+			abs = part
+		}
+		h.Write([]byte(abs + "\x00"))
+	}
+	return "pixlgo-concat-" + hex.EncodeToString(h.Sum(nil))[:12] + concatExt
+}
+
+// Explains why part b can't follow part a without re-encoding
+func matchParts(a, b *Metadata) error {
+	switch {
+	case a.Codec != b.Codec:
+		return fmt.Errorf("%w: %s is %s video, %s is %s", ErrConcatMismatch,
+			filepath.Base(a.Path), a.Codec, filepath.Base(b.Path), b.Codec)
+	case a.Width != b.Width || a.Height != b.Height:
+		return fmt.Errorf("%w: %s is %dx%d, %s is %dx%d", ErrConcatMismatch,
+			filepath.Base(a.Path), a.Width, a.Height, filepath.Base(b.Path), b.Width, b.Height)
+	case a.HasAudio != b.HasAudio:
+		return fmt.Errorf("%w: only one of %s and %s has sound", ErrConcatMismatch,
+			filepath.Base(a.Path), filepath.Base(b.Path))
+	case a.AudioCodec != b.AudioCodec:
+		return fmt.Errorf("%w: %s has %s audio, %s has %s", ErrConcatMismatch,
+			filepath.Base(a.Path), a.AudioCodec, filepath.Base(b.Path), b.AudioCodec)
+	}
+	return nil
+}
+
+// Input options for the concat demuxer, for lists WriteConcatList wrote.
+// They hold absolute paths, which its default safe mode refuses.
+func concatArgs(input string) []string {
+	if _, ok := lookupConcat(input); !ok {
+		return nil
+	}
+	return []string{"-f", "concat", "-safe", "0"}
+}
+
+// Fills in what the probe of a list can't tell: the summed duration, and
+// a chapter per part in place of any the first part brought
+func (m *Metadata) applyConcat() {
+	list, ok := lookupConcat(m.Path)
+	if !ok {
+		return
+	}
+
+	m.Parts = list.parts
+	m.Duration, m.DurationEstimated = list.total, false
+	m.Chapters = nil
+	for i, part := range list.parts {
+		end := list.total
+		if i+1 < len(list.starts) {
+			end = list.starts[i+1]
+		}
+		m.Chapters = append(m.Chapters, Chapter{Start: list.starts[i], End: end, Title: filepath.Base(part)})
+	}
+}
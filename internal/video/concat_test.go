@@ -0,0 +1,86 @@
+package video
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Writes the parts of a fake two-part recording
+func concatParts(t *testing.T) []string {
+	t.Helper()
+	fixture, _ := filepath.Abs(filepath.Join("testdata", "probe", "mp4.json"))
+	useFakeTools(t, map[string]string{"PROBE": fixture})
+	dir := t.TempDir()
+	var parts []string
+	for _, name := range []string{"part1.mp4", "part2.mp4"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, path)
+	}
+	return parts
+}
+
+// Each list is a file of its own, so removing one leaves another process's
+// list of the same parts in place, and both keep the same state key
+func TestWriteConcatList(t *testing.T) {
+	parts := concatParts(t)
+	a, err := WriteConcatList(context.Background(), parts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer RemoveConcatList(a)
+	b, err := WriteConcatList(context.Background(), parts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer RemoveConcatList(b)
+
+	if a == b {
+		t.Fatalf("both lists written to %s", a)
+	}
+	if StateKey(a) != StateKey(b) || StateKey(a) == a {
+		t.Errorf("state keys %q and %q, want one key for both, not the path", StateKey(a), StateKey(b))
+	}
+	input, err := InputArg(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if concatArgs(input) == nil {
+		t.Errorf("no concat demuxer for the written list %s", input)
+	}
+
+	RemoveConcatList(a)
+	if _, err := os.Stat(a); !os.IsNotExist(err) {
+		t.Errorf("removed list still there: %v", err)
+	}
+	if _, err := os.Stat(b); err != nil {
+		t.Errorf("removing one list took the other: %v", err)
+	}
+	if concatArgs(input) != nil {
+		t.Error("removed list still opened with the concat demuxer")
+	}
+}
+
+// A list this process didn't write could name any file or protocol, so it
+// is not opened with the concat demuxer
+func TestConcatArgsForeignList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "evil.ffconcat")
+	list := "ffconcat version 1.0\nfile '/etc/passwd'\nfile 'http://example.com/x'\n"
+	if err := os.WriteFile(path, []byte(list), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	input, err := InputArg(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args := inputOptions(input); args != nil {
+		t.Errorf("foreign list gets %q", args)
+	}
+	if StateKey(path) != path {
+		t.Errorf("StateKey = %q, want the path", StateKey(path))
+	}
+}
@@ -7,27 +7,91 @@ import (
 	"fmt"
 	"image"
 	"io"
-	"os"
-	"os/exec"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type LogFunc func(format string, args ...any)
 
+// Level-aware log sinks; nil entries are discarded
+type Logs struct {
+	Debug LogFunc
+	Info  LogFunc
+	Error LogFunc
+}
+
+// Routes every level to a single LogFunc
+func SingleLog(fn LogFunc) Logs {
+	return Logs{Debug: fn, Info: fn, Error: fn}
+}
+
+func (l Logs) withDefaults() Logs {
+	nop := func(format string, args ...any) {}
+	if l.Debug == nil {
+		l.Debug = nop
+	}
+	if l.Info == nil {
+		l.Info = nop
+	}
+	if l.Error == nil {
+		l.Error = nop
+	}
+	return l
+}
+
 var (
-	ErrNoVideoStream = errors.New("no video stream found")
-	ErrDecodeFailed  = errors.New("decode failed")
+	ErrNoVideoStream  = errors.New("no video stream found")
+	ErrDecodeFailed   = errors.New("decode failed")
+	ErrFFmpegNotFound = errors.New("ffmpeg not found")
 )
 
 type Decoder struct {
 	path     string
 	metadata Metadata
-	logFn    LogFunc
+	logs     Logs
+	threads  int
+	onPanic  func(r any, stack []byte)
+
+	// Hardware decoding; checked once on the first stream and cleared if
+	// it doesn't work, so later streams go straight to software
+	hwAccel   string
+	hwDevice  string
+	hwChecked bool
+
+	// Subtitle track burned into new streams, -1 for none
+	subtitle int
+	// Extra passes over the input for new streams, -1 forever
+	loop int
+	// Frame-accurate seeks for ExtractFrame and new streams
+	exactSeek bool
+
+	// Piped input, for decoders from NewDecoderFromReader
+	stdin *pipeInput
+
+	// Input of the audio stream: path, unless yt-dlp resolved a web page to
+	// separate video and audio URLs. audioStreams are its audio streams.
+	audioPath    string
+	audioStreams []StreamInfo
 
 	mu      sync.Mutex
 	stream  *Stream
 	running bool
+
+	// Audio level tap and playback, following the video stream when a
+	// meter or audio is enabled
+	meter     *LevelMeter
+	tap       *audioTap
+	playAudio bool
+	audio     *audioPlayer
+	audioCtx  context.Context
+	channels  int
+	// Audio stream played and metered, counted among the audio streams
+	audioTrack int
+
+	// Playback volume in percent; read by the audio player for every block
+	volume atomic.Int32
 }
 
 // Creates a new video decoder
@@ -36,32 +100,86 @@ func NewDecoder(path string) (*Decoder, error) {
 }
 
 func NewDecoderWithLogger(path string, logFn LogFunc) (*Decoder, error) {
-	if logFn == nil {
-		logFn = func(format string, args ...any) {}
-	}
-	info, err := os.Stat(path)
-	if err != nil {
-		return nil, fmt.Errorf("cannot access file: %w", err)
+	return NewDecoderWithLogs(path, SingleLog(logFn))
+}
+
+func NewDecoderWithLogs(path string, logs Logs) (*Decoder, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultProbeTimeout)
+	defer cancel()
+	return NewDecoderContext(ctx, path, logs)
+}
+
+// Like NewDecoderWithLogs with the file check and probing bounded by ctx
+func NewDecoderContext(ctx context.Context, path string, logs Logs) (*Decoder, error) {
+	logs = logs.withDefaults()
+	if IsURL(path) {
+		if _, err := InputArg(path); err != nil {
+			return nil, err
+		}
+		logs.Info("URL: %s", path)
+	} else {
+		info, err := StatContext(ctx, path)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot access file: %w", err)
+		}
+		logs.Info("File: %s (%d bytes)", path, info.Size())
 	}
-	logFn("File: %s (%d bytes)", path, info.Size())
 
-	if _, err := exec.LookPath("ffmpeg"); err != nil {
-		return nil, fmt.Errorf("ffmpeg not found")
+	if !toolAvailable("ffmpeg") {
+		return nil, toolMissing("ffmpeg")
 	}
 
-	meta, err := Probe(path)
+	meta, err := ProbeContext(ctx, path)
+	input, audioPath := path, path
+	if err != nil && isWebPage(path, err) {
+		logs.Info("Not a media URL, resolving with yt-dlp: %v", err)
+		var media webMedia
+		if media, err = resolveWebURL(ctx, path); err == nil {
+			logs.Debug("yt-dlp: %q video=%s audio=%s", media.title, media.video, media.audio)
+			input, audioPath = media.video, media.video
+			if media.audio != "" {
+				audioPath = media.audio
+			}
+			meta, err = ProbeContext(ctx, input)
+		}
+		if err == nil {
+			meta.Path = path
+			if meta.Tag("title") == "" && media.title != "" {
+				if meta.Tags == nil {
+					meta.Tags = map[string]string{}
+				}
+				meta.Tags["title"] = media.title
+			}
+		}
+	}
 	if err != nil {
+		logs.Error("Probe failed: %v", err)
 		return nil, err
 	}
 
-	logFn("Metadata: %dx%d @ %.2f fps, codec=%s, duration=%v",
+	logs.Info("Metadata: %dx%d @ %.2f fps, codec=%s, duration=%v",
 		meta.Width, meta.Height, meta.FPS, meta.Codec, meta.Duration)
+	logs.Debug("Stream: pix_fmt=%s profile=%q level=%d bitrate=%d rotation=%d",
+		meta.PixelFormat, meta.Profile, meta.Level, meta.BitRate, meta.Rotation)
+
+	audioStreams := meta.StreamsOf("audio")
+	if audioPath != input {
+		audioStreams = probeAudioStreams(ctx, audioPath)
+	}
 
-	return &Decoder{
-		path:     path,
-		metadata: *meta,
-		logFn:    logFn,
-	}, nil
+	d := &Decoder{
+		path:         input,
+		metadata:     *meta,
+		logs:         logs,
+		subtitle:     -1,
+		audioPath:    audioPath,
+		audioStreams: audioStreams,
+	}
+	d.volume.Store(100)
+	return d, nil
 }
 
 // Returns video metadata
@@ -74,6 +192,87 @@ func (d *Decoder) Path() string {
 	return d.path
 }
 
+// Sets the function called when a decoder goroutine panics. It is expected
+// to restore the terminal and exit; without one the panic propagates.
+func (d *Decoder) SetPanicHandler(fn func(r any, stack []byte)) {
+	d.onPanic = fn
+}
+
+func (d *Decoder) recoverPanic() {
+	if r := recover(); r != nil {
+		if d.onPanic == nil {
+			panic(r)
+		}
+		d.onPanic(r, debug.Stack())
+	}
+}
+
+// Caps the number of ffmpeg decode threads for new streams (0 means NumCPU)
+func (d *Decoder) SetThreads(n int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.threads = n
+}
+
+// Requests hardware decoding with an -hwaccel method and optional device.
+// If it fails to initialize, streams decode in software instead.
+func (d *Decoder) SetHWAccel(accel, device string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if accel == "none" {
+		accel = ""
+	}
+	d.hwAccel, d.hwDevice, d.hwChecked = accel, device, false
+}
+
+// Returns the hardware decoding method to use, checking it works first
+func (d *Decoder) resolveHWAccel(ctx context.Context) (string, string) {
+	d.mu.Lock()
+	accel, device, checked := d.hwAccel, d.hwDevice, d.hwChecked
+	d.mu.Unlock()
+	if accel == "" || checked {
+		return accel, device
+	}
+
+	err := checkHWAccel(ctx, d.path, &d.metadata, accel, device)
+	if ctx.Err() != nil {
+		// Cancelled, not a verdict on the hardware; check again next time
+		return accel, device
+	}
+	if err != nil {
+		d.logs.Info("Hardware decoding (%s) unavailable, using software: %v", accel, err)
+		accel, device = "", ""
+	}
+	d.mu.Lock()
+	d.hwAccel, d.hwDevice, d.hwChecked = accel, device, true
+	d.mu.Unlock()
+	return accel, device
+}
+
+// Burns subtitle track si into streams started from now on; -1 turns
+// subtitles off
+func (d *Decoder) SetSubtitles(si int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.subtitle = si
+}
+
+// Makes streams started from now on, and their audio, play loop more
+// passes over the input without restarting ffmpeg; -1 repeats forever
+func (d *Decoder) SetLoop(loop int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.loop = loop
+}
+
+// Makes ExtractFrame and streams started from now on decode from before
+// their position and drop frames up to it, as StreamConfig.ExactSeek
+func (d *Decoder) SetExactSeek(exact bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.exactSeek = exact
+}
+
 func (d *Decoder) IsRunning() bool {
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -86,79 +285,318 @@ func (d *Decoder) Stop() {
 	stream := d.stream
 	d.stream = nil
 	d.running = false
+	tap, audio := d.tap, d.audio
+	d.tap, d.audio = nil, nil
 	d.mu.Unlock()
 
 	if stream != nil {
-		stream.Stop(d.logFn)
+		stream.Stop(d.logs.Debug)
+	}
+	if tap != nil {
+		tap.stop()
+	}
+	if audio != nil {
+		audio.stop()
+	}
+}
+
+// Decodes video stream n, counted among the file's video streams, instead
+// of the one probing chose. Call before starting any stream.
+func (d *Decoder) SelectVideoStream(n int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.metadata.SelectVideoStream(n)
+}
+
+// Picks the audio stream for playback and the level meter, counted from 0
+// among the audio streams. Call before EnableLevelMeter and EnableAudio.
+func (d *Decoder) SetAudioTrack(track int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.audioTrack = max(track, 0)
+}
+
+// Makes every stream also run an audio tap feeding the returned meter.
+// Returns nil if the file has no audio stream.
+func (d *Decoder) EnableLevelMeter() *LevelMeter {
+	if d.stdin != nil {
+		return nil
+	}
+	channels := d.audioChannels()
+	if channels == 0 {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.meter = newLevelMeter(channels)
+	return d.meter
+}
+
+// Makes every stream also play the file's audio through ffplay. Returns
+// false if the file has no audio stream, or an error if ffplay is missing.
+func (d *Decoder) EnableAudio() (bool, error) {
+	if d.stdin != nil {
+		return false, pipedError("audio")
+	}
+	channels := d.audioChannels()
+	if channels == 0 {
+		return false, nil
+	}
+	if !toolAvailable("ffplay") {
+		return false, toolMissing("ffplay")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.playAudio, d.channels = true, channels
+	return true, nil
+}
+
+// Returns the channel count of the chosen audio stream, capped at 2, or 0
+// when there is no such stream. An unknown count is taken as stereo.
+func (d *Decoder) audioChannels() int {
+	track := d.currentAudioTrack()
+	if track >= len(d.audioStreams) {
+		return 0
+	}
+	if n := d.audioStreams[track].Channels; n > 0 {
+		return min(n, 2)
+	}
+	return 2
+}
+
+func (d *Decoder) currentAudioTrack() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.audioTrack
+}
+
+// Sets the playback volume in percent, 0 to MaxVolume. It applies to the
+// running audio within a few milliseconds and to every later stream.
+func (d *Decoder) SetVolume(percent int) {
+	d.volume.Store(int32(min(max(percent, 0), MaxVolume)))
+}
+
+// Restarts the audio tap and playback at pos, whichever are enabled
+func (d *Decoder) restartAudio(ctx context.Context, pos time.Duration) {
+	d.mu.Lock()
+	meter, play, channels, track, loop := d.meter, d.playAudio, d.channels, d.audioTrack, d.loop
+	oldTap, oldAudio := d.tap, d.audio
+	d.tap, d.audio = nil, nil
+	d.mu.Unlock()
+	if d.metadata.Live {
+		pos = 0
 	}
+	if oldTap != nil {
+		oldTap.stop()
+	}
+	if oldAudio != nil {
+		oldAudio.stop()
+	}
+
+	var tap *audioTap
+	var audio *audioPlayer
+	var err error
+	if meter != nil {
+		if tap, err = startAudioTap(ctx, d.audioPath, track, pos, loop, meter, d.logs); err != nil {
+			d.logs.Error("Audio tap failed: %v", err)
+		}
+	}
+	if play {
+		if audio, err = startAudioPlayer(ctx, d.audioPath, track, pos, loop, channels, &d.volume, d.logs); err != nil {
+			d.logs.Error("Audio playback failed: %v", err)
+		}
+	}
+	d.mu.Lock()
+	d.tap, d.audio, d.audioCtx = tap, audio, ctx
+	d.mu.Unlock()
 }
 
 func (d *Decoder) Close() {
 	d.Stop()
 }
 
-// Begin decoding video frames
+// Begin decoding video frames. Returns the size frames are actually
+// decoded at, which FitSize may have scaled down from width x height.
 func (d *Decoder) StartStream(ctx context.Context, width, height int,
-	startPos time.Duration, buffer *FrameBuffer, targetFPS float64) error {
-	d.Stop()
+	startPos time.Duration, buffer *FrameBuffer, targetFPS float64) (int, int, error) {
+	// Invalidate the old stream's token first so none of its frames can
+	// land after this point, then tear it down
 	epoch := buffer.Reset()
+	d.Stop()
 
 	if targetFPS <= 0 {
 		targetFPS = DefaultTargetFPS(width, height, d.metadata.FPS)
 	}
 
-	d.logFn("[epoch=%d] StartStream: %dx%d @ %.1f fps, startPos=%v",
+	d.logs.Debug("[epoch=%d] StartStream: %dx%d @ %.1f fps, startPos=%v",
 		epoch, width, height, targetFPS, startPos)
 
+	d.mu.Lock()
+	threads, subtitle, loop, exact := d.threads, d.subtitle, d.loop, d.exactSeek
+	d.mu.Unlock()
+	accel, device := d.resolveHWAccel(ctx)
+	if accel != "" {
+		d.logs.Debug("[epoch=%d] Decoding with hwaccel=%s", epoch, accel)
+	} else {
+		d.logs.Debug("[epoch=%d] Decoding in software", epoch)
+	}
+
 	config := StreamConfig{
 		Width:     width,
 		Height:    height,
 		StartPos:  startPos,
 		TargetFPS: targetFPS,
+		Threads:   threads,
+
+		StreamIndex: d.metadata.StreamIndex,
+		ColorRange:  d.metadata.ColorRange,
+		Rotation:    d.metadata.Rotation,
+
+		HWAccel:       accel,
+		HWAccelDevice: device,
+
+		Subtitles:     subtitle >= 0,
+		SubtitleTrack: max(subtitle, 0),
+
+		Loop:         loop,
+		LoopDuration: d.metadata.Duration,
+		ExactSeek:    exact,
+
+		Live:  d.metadata.Live,
+		stdin: d.stdin,
 	}
 
-	stream, err := StartStream(ctx, d.path, config, epoch, d.logFn)
+	stream, err := StartStream(ctx, d.path, config, epoch, d.logs)
 	if err != nil {
-		return err
+		d.logs.Error("[epoch=%d] StartStream failed: %v", epoch, err)
+		return 0, 0, err
 	}
 
 	d.mu.Lock()
 	d.stream = stream
 	d.running = true
+	if d.playAudio {
+		stream.SetClock(d.audioClock)
+	}
 	d.mu.Unlock()
 
 	go func() {
-		stream.ReadFrames(buffer, d.logFn)
+		defer d.recoverPanic()
+		stream.ReadFrames(buffer, d.logs)
 		d.mu.Lock()
 		if d.stream == stream {
 			d.running = false
 		}
 		d.mu.Unlock()
 	}()
-	return nil
+	d.restartAudio(ctx, startPos)
+	grantedW, grantedH := stream.Size()
+	return grantedW, grantedH, nil
 }
 
-func (d *Decoder) ExtractFrame(timestamp time.Duration, width, height int) (*Frame, error) {
-	return ExtractSingleFrame(d.path, timestamp, width, height)
+// Returns the media time being heard, or false without running audio
+func (d *Decoder) audioClock() (time.Duration, bool) {
+	d.mu.Lock()
+	audio := d.audio
+	d.mu.Unlock()
+	if audio == nil {
+		return 0, false
+	}
+	return audio.clock.now()
 }
 
-func ExtractSingleFrame(path string, timestamp time.Duration, width, height int) (*Frame, error) {
-	width = normalizeEven(width, 4, 4096)
-	height = normalizeEven(height, 4, 4096)
+// Returns the running stream's audio/video sync state, or false when no
+// stream is running
+func (d *Decoder) SyncStats() (SyncStats, bool) {
+	d.mu.Lock()
+	stream, running := d.stream, d.running
+	d.mu.Unlock()
+	if stream == nil || !running {
+		return SyncStats{}, false
+	}
+	return stream.Stats(), true
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// Returns the running stream's decode lead (see Stream.Ahead), or false
+// when no stream is running
+func (d *Decoder) BufferAhead() (time.Duration, bool) {
+	d.mu.Lock()
+	stream, running := d.stream, d.running
+	d.mu.Unlock()
+	if stream == nil || !running {
+		return 0, false
+	}
+	return stream.Ahead(), true
+}
+
+// Skips the running stream forward to target without restarting ffmpeg.
+// Returns false if there is no stream or the jump exceeds maxDelta.
+func (d *Decoder) SkipForward(target, maxDelta time.Duration) bool {
+	d.mu.Lock()
+	stream := d.stream
+	running := d.running
+	d.mu.Unlock()
+
+	if stream == nil || !running || maxDelta <= 0 {
+		return false
+	}
+	if !stream.SkipTo(target, maxDelta) {
+		return false
+	}
+	d.logs.Debug("[epoch=%d] SkipForward: target=%v", stream.Epoch(), target)
+	d.mu.Lock()
+	audioCtx := d.audioCtx
+	d.mu.Unlock()
+	if audioCtx != nil {
+		d.restartAudio(audioCtx, target)
+	}
+	return true
+}
+
+func (d *Decoder) ExtractFrame(ctx context.Context, timestamp time.Duration, width, height int) (*Frame, error) {
+	if d.stdin != nil {
+		return nil, pipedError("extract frame")
+	}
+	d.mu.Lock()
+	exact := d.exactSeek
+	d.mu.Unlock()
+	return extractSingleFrame(ctx, d.path, &d.metadata, timestamp, width, height, exact)
+}
+
+// Decodes one frame of meta's stream at timestamp; cancelling ctx kills
+// ffmpeg
+func ExtractSingleFrame(ctx context.Context, path string, meta *Metadata, timestamp time.Duration, width, height int) (*Frame, error) {
+	return extractSingleFrame(ctx, path, meta, timestamp, width, height, false)
+}
+
+// Like ExtractSingleFrame, seeking as seekArgs does with exact
+func extractSingleFrame(ctx context.Context, path string, meta *Metadata, timestamp time.Duration, width, height int,
+	exact bool) (*Frame, error) {
+	width, height = FitSize(width, height)
+
+	input, err := InputArg(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "ffmpeg",
-		"-ss", fmt.Sprintf("%.3f", timestamp.Seconds()),
-		"-i", path,
+	seekIn, seekOut, _ := seekArgs(timestamp, exact)
+	args := append(seekIn, rotateArgs(meta.Rotation)...)
+	args = append(args, inputOptions(input)...)
+	args = append(args, "-i", input)
+	args = append(args, seekOut...)
+	args = append(args,
+		"-map", meta.MapArg(),
 		"-vframes", "1",
-		"-vf", fmt.Sprintf("scale=%d:%d", width, height),
+		"-vf", withRotation(meta.Rotation, scaleFilter(width, height, meta.ColorRange)),
 		"-pix_fmt", "rgb24",
 		"-f", "rawvideo",
 		"-loglevel", "error",
 		"-",
 	)
+	cmd := newInputCommand(ctx, "ffmpeg", args...)
 
 	out, err := cmd.Output()
 	if err != nil {
@@ -173,6 +611,7 @@ func ExtractSingleFrame(path string, timestamp time.Duration, width, height int)
 	frame := &Frame{
 		Image:     createRGBAFromRGB24(out[:expectedSize], width, height),
 		Timestamp: timestamp,
+		Decoded:   time.Now(),
 	}
 	return frame, nil
 }
@@ -195,17 +634,24 @@ func (d *Decoder) Stream(ctx context.Context, width, height int, startPos time.D
 		height = 2
 	}
 
-	args := []string{
-		"-ss", fmt.Sprintf("%.3f", startPos.Seconds()),
-		"-i", d.path,
-		"-vf", fmt.Sprintf("scale=%d:%d", width, height),
+	input, err := InputArg(d.path)
+	if err != nil {
+		return nil, err
+	}
+
+	args := append([]string{"-ss", fmt.Sprintf("%.3f", startPos.Seconds())}, rotateArgs(d.metadata.Rotation)...)
+	args = append(args, inputOptions(input)...)
+	args = append(args,
+		"-i", input,
+		"-map", d.metadata.MapArg(),
+		"-vf", withRotation(d.metadata.Rotation, scaleFilter(width, height, d.metadata.ColorRange)),
 		"-pix_fmt", "rgba",
 		"-f", "rawvideo",
 		"-loglevel", "quiet",
 		"-", // Output to stdout
-	}
+	)
 
-	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	cmd := newInputCommand(ctx, "ffmpeg", args...)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stdout: %w", err)
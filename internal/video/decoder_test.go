@@ -0,0 +1,100 @@
+package video
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/0bVdnt/PixlGo/internal/fakeff"
+)
+
+// Returns a decoder for a local file the fake ffprobe describes with
+// testdata/probe/<fixture>, and the file recording fake tool runs
+func newTestDecoder(t *testing.T, fixture string) (*Decoder, string) {
+	t.Helper()
+	probe, _ := filepath.Abs(filepath.Join("testdata", "probe", fixture))
+	argsFile := filepath.Join(t.TempDir(), "args")
+	useFakeTools(t, map[string]string{"PROBE": probe, "ARGS": argsFile})
+	path, _ := fakeStreamInput(t)
+	d, err := NewDecoderContext(context.Background(), path, Logs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d, argsFile
+}
+
+// The meter takes the channel count from the probe at open instead of
+// running ffprobe again
+func TestEnableLevelMeter(t *testing.T) {
+	tests := []struct {
+		track    int
+		channels int // 0 for no meter
+	}{
+		{0, 2}, // 5.1, metered as stereo
+		{1, 1},
+		{2, 0},
+	}
+	for _, tt := range tests {
+		d, argsFile := newTestDecoder(t, "mkv.json")
+		d.SetAudioTrack(tt.track)
+		meter := d.EnableLevelMeter()
+		switch {
+		case tt.channels == 0 && meter != nil:
+			t.Errorf("track %d: meter for a missing stream", tt.track)
+		case tt.channels != 0 && (meter == nil || meter.channels != tt.channels):
+			t.Errorf("track %d: meter %+v, want %d channels", tt.track, meter, tt.channels)
+		}
+		if runs := fakeff.Args(t, argsFile); len(runs) != 1 {
+			t.Errorf("track %d: %d ffprobe runs, want only the one at open", tt.track, len(runs))
+		}
+	}
+}
+
+// Audio takes the channel count from the probe at open as well
+func TestEnableAudio(t *testing.T) {
+	// A stand-in ffplay on PATH; enabling only checks that it exists
+	bin := t.TempDir()
+	exe, _ := os.Executable()
+	ffplay := filepath.Join(bin, "ffplay")
+	if runtime.GOOS == "windows" {
+		ffplay += ".exe"
+	}
+	if err := os.Symlink(exe, ffplay); err != nil {
+		t.Skipf("no symlinks: %v", err)
+	}
+	t.Setenv("PATH", bin)
+
+	tests := []struct {
+		track    int
+		channels int // 0 for no audio
+	}{
+		{0, 2},
+		{1, 1},
+		{2, 0},
+	}
+	for _, tt := range tests {
+		d, argsFile := newTestDecoder(t, "mkv.json")
+		d.SetAudioTrack(tt.track)
+		audio, err := d.EnableAudio()
+		if err != nil {
+			t.Fatalf("track %d: %v", tt.track, err)
+		}
+		if audio != (tt.channels > 0) || d.channels != tt.channels {
+			t.Errorf("track %d: audio %v with %d channels, want %d", tt.track, audio, d.channels, tt.channels)
+		}
+		if runs := fakeff.Args(t, argsFile); len(runs) != 1 {
+			t.Errorf("track %d: %d ffprobe runs, want only the one at open", tt.track, len(runs))
+		}
+	}
+}
+
+func TestEnableAudioSingleProbe(t *testing.T) {
+	d, argsFile := newTestDecoder(t, "mp4.json")
+	d.EnableLevelMeter()
+	d.EnableAudio()
+	if runs := fakeff.Args(t, argsFile); len(runs) != 1 {
+		t.Errorf("%d ffprobe runs for the meter and audio, want only the one at open", len(runs))
+	}
+}
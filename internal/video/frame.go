@@ -3,6 +3,7 @@ package video
 import (
 	"image"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -10,51 +11,62 @@ import (
 type Frame struct {
 	Image     *image.RGBA
 	Timestamp time.Duration
+
+	// Wall-clock times for latency metrics
+	Decoded time.Time
+	Stored  time.Time
 }
 
-// Provides thread-safe access to current frame
+// Provides thread-safe access to current frame.
+//
+// The epoch is the ownership token: Reset hands out a new one, and Store
+// only accepts frames carrying the current token. Epoch may be read without
+// the lock as a cheap early-out, but Store's check is authoritative.
 type FrameBuffer struct {
 	mu         sync.RWMutex
 	frame      *Frame
-	epoch      uint64
+	epoch      atomic.Uint64
 	dropped    uint64
 	frameCount uint64
 	lastError  error
+	endReason  EndReason
 }
 
 // Creates a new frame buffer
 func NewFrameBuffer() *FrameBuffer {
-	return &FrameBuffer{epoch: 1}
+	fb := &FrameBuffer{}
+	fb.epoch.Store(1)
+	return fb
 }
 
-// Clears the buffer and increments the epoch
+// Clears the buffer and returns a new epoch token for the next producer
 func (fb *FrameBuffer) Reset() uint64 {
 	fb.mu.Lock()
 	defer fb.mu.Unlock()
 	fb.frame = nil
-	fb.epoch++
+	epoch := fb.epoch.Add(1)
 	fb.dropped = 0
 	fb.frameCount = 0
 	fb.lastError = nil
-	return fb.epoch
+	fb.endReason = EndNone
+	return epoch
 }
 
 // Returns the current epoch
 func (fb *FrameBuffer) Epoch() uint64 {
-	fb.mu.RLock()
-	defer fb.mu.RUnlock()
-	return fb.epoch
+	return fb.epoch.Load()
 }
 
-// Saves a new frame (only if epoch matches)
+// Saves a new frame if epoch is still current (compare-and-store)
 func (fb *FrameBuffer) Store(f *Frame, epoch uint64) bool {
 	fb.mu.Lock()
 	defer fb.mu.Unlock()
 
-	if epoch != fb.epoch {
+	if epoch != fb.epoch.Load() {
 		return false
 	}
 
+	f.Stored = time.Now()
 	fb.frame = f
 	fb.frameCount++
 	return true
@@ -64,6 +76,7 @@ func (fb *FrameBuffer) Store(f *Frame, epoch uint64) bool {
 func (fb *FrameBuffer) StoreForce(f *Frame) {
 	fb.mu.Lock()
 	defer fb.mu.Unlock()
+	f.Stored = time.Now()
 	fb.frame = f
 	fb.frameCount++
 }
@@ -91,16 +104,20 @@ func (fb *FrameBuffer) FrameCount() uint64 {
 
 // Increments the dropped frame counter
 func (fb *FrameBuffer) AddDropped() {
+	Stats.FramesDropped.Add(1)
 	fb.mu.Lock()
 	fb.dropped++
 	fb.mu.Unlock()
 }
 
-// Sets an error state
-func (fb *FrameBuffer) SetError(err error) {
+// Records that the stream for epoch failed; ignored for stale epochs, so a
+// dying stream can't fail the one that replaced it
+func (fb *FrameBuffer) SetError(epoch uint64, err error) {
 	fb.mu.Lock()
-	fb.lastError = err
-	fb.mu.Unlock()
+	defer fb.mu.Unlock()
+	if epoch == fb.epoch.Load() {
+		fb.lastError = err
+	}
 }
 
 // Returns last error
@@ -110,6 +127,22 @@ func (fb *FrameBuffer) GetError() error {
 	return fb.lastError
 }
 
+// Records why the stream for epoch finished; ignored for stale epochs
+func (fb *FrameBuffer) SetEnd(epoch uint64, reason EndReason) {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	if epoch == fb.epoch.Load() {
+		fb.endReason = reason
+	}
+}
+
+// Returns why the current epoch's stream finished, or EndNone
+func (fb *FrameBuffer) EndReason() EndReason {
+	fb.mu.RLock()
+	defer fb.mu.RUnlock()
+	return fb.endReason
+}
+
 // Returns the current frame's timestamp
 func (fb *FrameBuffer) Timestamp() time.Duration {
 	fb.mu.RLock()
@@ -119,3 +152,21 @@ func (fb *FrameBuffer) Timestamp() time.Duration {
 	}
 	return 0
 }
+
+// Returns a copy of the current frame, or nil. The decoder reuses frame
+// images, so callers outside the render loop must not hold on to Load's
+// result; the copy is safe to keep and is taken under the read lock, which
+// Store waits for only as long as the copy takes.
+func (fb *FrameBuffer) Snapshot() *Frame {
+	fb.mu.RLock()
+	defer fb.mu.RUnlock()
+	if fb.frame == nil {
+		return nil
+	}
+	cp := *fb.frame
+	if fb.frame.Image != nil {
+		cp.Image = image.NewRGBA(fb.frame.Image.Rect)
+		copy(cp.Image.Pix, fb.frame.Image.Pix)
+	}
+	return &cp
+}
@@ -0,0 +1,91 @@
+package video
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFrameBufferStaleEpoch(t *testing.T) {
+	fb := NewFrameBuffer()
+	old := fb.Epoch()
+	current := fb.Reset()
+
+	frame := &Frame{Image: image.NewRGBA(image.Rect(0, 0, 2, 2))}
+	if fb.Store(frame, old) || fb.Load() != nil {
+		t.Error("stale Store was kept")
+	}
+	fb.SetError(old, ErrDecodeFailed)
+	fb.SetEnd(old, EndEOF)
+	if fb.GetError() != nil || fb.EndReason() != EndNone {
+		t.Errorf("stale stream set error %v, end %s", fb.GetError(), fb.EndReason())
+	}
+
+	fb.SetError(current, ErrDecodeFailed)
+	if !errors.Is(fb.GetError(), ErrDecodeFailed) {
+		t.Errorf("error %v, want %v", fb.GetError(), ErrDecodeFailed)
+	}
+	if fb.Reset(); fb.GetError() != nil {
+		t.Error("Reset kept the error")
+	}
+}
+
+// Run with -race: two producers take the buffer from each other as
+// restarting streams do, while a reader checks that whatever it sees while
+// the epoch holds still belongs to that epoch
+func TestFrameBufferCompetingStreams(t *testing.T) {
+	fb := NewFrameBuffer()
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				epoch := fb.Reset()
+				for i := range 20 {
+					// Each frame is fresh so the reader never sees it change
+					frame := &Frame{Image: image.NewRGBA(image.Rect(0, 0, 2, 2)), Timestamp: time.Duration(epoch)}
+					if !fb.Store(frame, epoch) {
+						break
+					}
+					if i == 10 {
+						fb.SetError(epoch, fmt.Errorf("epoch %d", epoch))
+					}
+				}
+				fb.SetEnd(epoch, EndEOF)
+			}
+		}()
+	}
+
+	deadline := time.Now().Add(300 * time.Millisecond)
+	checked := 0
+	for time.Now().Before(deadline) {
+		before := fb.Epoch()
+		frame, err := fb.Load(), fb.GetError()
+		if fb.Epoch() != before {
+			continue
+		}
+		checked++
+		if frame != nil && uint64(frame.Timestamp) != before {
+			t.Fatalf("epoch %d shows a frame of epoch %d", before, frame.Timestamp)
+		}
+		if err != nil && err.Error() != fmt.Sprintf("epoch %d", before) {
+			t.Fatalf("epoch %d shows %q", before, err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	if checked == 0 {
+		t.Fatal("no stable epoch observed")
+	}
+}
@@ -0,0 +1,40 @@
+package video
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0bVdnt/PixlGo/internal/fakeff"
+)
+
+func TestMain(m *testing.M) {
+	fakeff.Main()
+	os.Exit(m.Run())
+}
+
+// Makes ffmpeg and ffprobe runs in this test start the fake tool with the
+// given fakeff settings
+func useFakeTools(t *testing.T, env map[string]string) {
+	t.Helper()
+	exe := fakeff.Setenv(t, env)
+	old := currentTools()
+	SetTools(Tools{FFmpeg: exe, FFprobe: exe})
+	t.Cleanup(func() { SetTools(old) })
+}
+
+// Probes a local file with the fake ffprobe printing fixture, a JSON file
+// under testdata/probe or at an absolute path
+func probeFixture(t *testing.T, fixture string) (*Metadata, error) {
+	t.Helper()
+	if !filepath.IsAbs(fixture) {
+		fixture, _ = filepath.Abs(filepath.Join("testdata", "probe", fixture))
+	}
+	path := filepath.Join(t.TempDir(), "clip.mp4")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	useFakeTools(t, map[string]string{"PROBE": fixture})
+	return ProbeContext(context.Background(), path)
+}
@@ -0,0 +1,167 @@
+package video
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Placeholder for option values kept out of logs
+const redacted = "<redacted>"
+
+// Reads a cookie file for Tools.Cookies: a Netscape cookies.txt as browsers
+// and yt-dlp export it, or one Set-Cookie value per line. Returns the
+// cookies as Set-Cookie values, one per line.
+func ReadCookies(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var cookies []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// curl marks HttpOnly cookies with a prefix on an otherwise
+		// commented-out line
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// domain, subdomains, path, secure, expiry, name, value
+		if fields := strings.Split(line, "\t"); len(fields) == 7 {
+			line = fmt.Sprintf("%s=%s; domain=%s; path=%s", fields[5], fields[6], fields[0], fields[2])
+		}
+		cookies = append(cookies, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if len(cookies) == 0 {
+		return "", fmt.Errorf("no cookies in %s", path)
+	}
+	return strings.Join(cookies, "\n"), nil
+}
+
+// Options for the headers and cookies from SetTools on an http(s) input.
+// Headers and cookies without a domain only go to the hosts in
+// Tools.HTTPHosts, not to CDNs that yt-dlp resolves pages to or playlist
+// entries elsewhere; cookies naming a domain go to hosts within it. ffmpeg
+// only sends cookies that name a domain and path, so bare ones get those of
+// the input.
+func httpArgs(input string) []string {
+	t := currentTools()
+	host := ""
+	if u, err := url.Parse(input); err == nil {
+		host = strings.ToLower(u.Hostname())
+	}
+	if host == "" {
+		return nil
+	}
+	trusted := userHost(t, host)
+
+	var args []string
+	if len(t.HTTPHeaders) > 0 && trusted {
+		args = append(args, "-headers", strings.Join(t.HTTPHeaders, "\r\n")+"\r\n")
+	}
+	if t.Cookies == "" {
+		return args
+	}
+	var cookies []string
+	for _, c := range strings.Split(t.Cookies, "\n") {
+		if domain, ok := cookieAttr(c, "domain"); ok {
+			if !inDomain(host, domain) {
+				continue
+			}
+		} else if trusted {
+			c += "; domain=" + host
+		} else {
+			continue
+		}
+		if _, ok := cookieAttr(c, "path"); !ok {
+			c += "; path=/"
+		}
+		cookies = append(cookies, c)
+	}
+	if len(cookies) == 0 {
+		return args
+	}
+	return append(args, "-cookies", strings.Join(cookies, "\n"))
+}
+
+// Reports whether host is one of t.HTTPHosts
+func userHost(t Tools, host string) bool {
+	return slices.ContainsFunc(t.HTTPHosts, func(h string) bool {
+		return strings.EqualFold(h, host)
+	})
+}
+
+// Returns the value of the named attribute of a Set-Cookie value
+func cookieAttr(cookie, name string) (string, bool) {
+	parts := strings.Split(cookie, ";")
+	for _, part := range parts[1:] {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if strings.EqualFold(key, name) {
+			return strings.TrimSpace(value), true
+		}
+	}
+	return "", false
+}
+
+// Reports whether host is domain or one of its subdomains
+func inDomain(host, domain string) bool {
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	return domain != "" && (host == domain || strings.HasSuffix(host, "."+domain))
+}
+
+// Returns the lowercased hosts of the http(s) URLs among paths, for
+// Tools.HTTPHosts
+func URLHosts(paths []string) []string {
+	var hosts []string
+	for _, p := range paths {
+		if scheme, _ := urlScheme(p); scheme != "http" && scheme != "https" {
+			continue
+		}
+		u, err := url.Parse(p)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		if host := strings.ToLower(u.Hostname()); !slices.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// Options for yt-dlp to send the same headers and cookies when resolving
+// page; yt-dlp matches the cookies' domains itself
+func ytdlpHTTPArgs(page string) []string {
+	t := currentTools()
+	var args []string
+	if hosts := URLHosts([]string{page}); len(hosts) > 0 && userHost(t, hosts[0]) {
+		for _, h := range t.HTTPHeaders {
+			args = append(args, "--add-header", h)
+		}
+	}
+	if t.CookieFile != "" {
+		args = append(args, "--cookies", t.CookieFile)
+	}
+	return args
+}
+
+// Returns args with the values of header and cookie options replaced, for
+// logging; they often carry tokens
+func redactArgs(args []string) []string {
+	args = slices.Clone(args)
+	for i := 0; i+1 < len(args); i++ {
+		switch args[i] {
+		case "-headers", "-cookies":
+			args[i+1] = redacted
+		}
+	}
+	return args
+}
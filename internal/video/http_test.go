@@ -0,0 +1,105 @@
+package video
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Sets the HTTP options of SetTools for the rest of t
+func useHTTPTools(t *testing.T, headers []string, cookies string, hosts ...string) {
+	t.Helper()
+	old := currentTools()
+	SetTools(Tools{HTTPHeaders: headers, Cookies: cookies, CookieFile: "cookies.txt", HTTPHosts: hosts})
+	t.Cleanup(func() { SetTools(old) })
+}
+
+// Returns the value following option in args, or "" without it
+func optionValue(args []string, option string) string {
+	if i := slices.Index(args, option); i >= 0 && i+1 < len(args) {
+		return args[i+1]
+	}
+	return ""
+}
+
+func TestHTTPArgsScope(t *testing.T) {
+	cookies := strings.Join([]string{
+		"bare=1",
+		"site=2; domain=.example.com; path=/",
+		"cdn=3; Domain=cdn.net",
+		"other=4; domain=example.org; path=/v",
+	}, "\n")
+	useHTTPTools(t, []string{"Authorization: Bearer secret"}, cookies, "example.com")
+
+	tests := []struct {
+		name    string
+		input   string
+		headers bool
+		cookies []string
+	}{
+		{"given host", "https://example.com/watch",
+			true, []string{"bare=1; domain=example.com; path=/", "site=2; domain=.example.com; path=/"}},
+		{"host case", "HTTPS://Example.COM:8443/live.m3u8",
+			true, []string{"bare=1; domain=example.com; path=/", "site=2; domain=.example.com; path=/"}},
+		// Subdomains only get the cookies that name the parent domain
+		{"subdomain", "https://media.example.com/a.mp4",
+			false, []string{"site=2; domain=.example.com; path=/"}},
+		{"resolved CDN", "https://edge.cdn.net/v.mp4?sig=1",
+			false, []string{"cdn=3; Domain=cdn.net; path=/"}},
+		{"lookalike host", "https://notexample.com/a.mp4", false, nil},
+		{"other host", "http://203.0.113.5/a.mp4", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := httpArgs(tt.input)
+			if got := optionValue(args, "-headers"); (got != "") != tt.headers {
+				t.Errorf("-headers %q, want them sent: %v", got, tt.headers)
+			}
+			var got []string
+			if c := optionValue(args, "-cookies"); c != "" {
+				got = strings.Split(c, "\n")
+			}
+			if !slices.Equal(got, tt.cookies) {
+				t.Errorf("-cookies %q, want %q", got, tt.cookies)
+			}
+		})
+	}
+}
+
+func TestHTTPArgsNoHosts(t *testing.T) {
+	useHTTPTools(t, []string{"Authorization: Bearer secret"}, "bare=1")
+	if args := httpArgs("https://example.com/a.mp4"); len(args) != 0 {
+		t.Errorf("sent %q without any given host", args)
+	}
+}
+
+func TestYTDLPHTTPArgs(t *testing.T) {
+	useHTTPTools(t, []string{"Authorization: Bearer secret"}, "bare=1", "example.com")
+	if args := ytdlpHTTPArgs("https://EXAMPLE.com/watch?v=1"); optionValue(args, "--add-header") == "" {
+		t.Errorf("no header for the given page: %q", args)
+	}
+	args := ytdlpHTTPArgs("https://elsewhere.net/watch?v=1")
+	if slices.Contains(args, "--add-header") {
+		t.Errorf("header sent to another host: %q", args)
+	}
+	// yt-dlp matches the cookie file's domains itself
+	if optionValue(args, "--cookies") != "cookies.txt" {
+		t.Errorf("cookie file not handed over: %q", args)
+	}
+}
+
+func TestURLHosts(t *testing.T) {
+	paths := []string{
+		"https://Example.com/a.mp4",
+		"HTTP://example.com:8080/b.mp4",
+		"http://[2001:db8::1]/c.mp4",
+		"rtsp://cam/stream",
+		"clip.mp4",
+		"playlist.m3u",
+		"https:///nohost",
+	}
+	want := []string{"example.com", "2001:db8::1"}
+	if got := URLHosts(paths); !slices.Equal(got, want) {
+		t.Errorf("URLHosts = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,69 @@
+package video
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// How long the one-frame hardware decode check may take
+const hwaccelCheckTimeout = 5 * time.Second
+
+// Reports whether name can be an -hwaccel value: "none", "auto" or a
+// method such as vaapi, videotoolbox, cuda, qsv or d3d11va. Whether the
+// method works is only known once ffmpeg tries it.
+func ValidHWAccel(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the ffmpeg input options selecting hardware decoding, or none
+// for software decoding
+func hwaccelArgs(accel, device string) []string {
+	if accel == "" || accel == "none" {
+		return nil
+	}
+	args := []string{"-hwaccel", accel}
+	if device != "" {
+		args = append(args, "-hwaccel_device", device)
+	}
+	return args
+}
+
+// Decodes one frame of the video stream with hardware acceleration to see
+// whether the method initializes on this machine and for this codec
+func checkHWAccel(ctx context.Context, path string, meta *Metadata, accel, device string) error {
+	input, err := InputArg(path)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, hwaccelCheckTimeout)
+	defer cancel()
+
+	args := append(hwaccelArgs(accel, device),
+		"-i", input,
+		"-map", meta.MapArg(),
+		"-frames:v", "1",
+		"-f", "null",
+		"-loglevel", "error",
+		"-",
+	)
+	if _, err := newInputCommand(ctx, "ffmpeg", args...).Output(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderrSnippet(exitErr.Stderr)))
+		}
+		return err
+	}
+	return nil
+}
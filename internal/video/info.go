@@ -0,0 +1,211 @@
+package video
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Everything ffprobe reports about a file, for display rather than playback
+type MediaInfo struct {
+	Format     string        `json:"format"`
+	FormatName string        `json:"format_name"`
+	Duration   time.Duration `json:"-"`
+	BitRate    int64         `json:"bit_rate,omitempty"`
+	Size       int64         `json:"size,omitempty"`
+
+	Video     []VideoStreamInfo    `json:"video"`
+	Audio     []AudioStreamInfo    `json:"audio"`
+	Subtitles []SubtitleStreamInfo `json:"subtitles"`
+	Chapters  []Chapter            `json:"chapters"`
+}
+
+type VideoStreamInfo struct {
+	Index       int     `json:"index"`
+	Number      int     `json:"number"` // among the video streams, as in 0:v:N
+	Codec       string  `json:"codec"`
+	Width       int     `json:"width"`
+	Height      int     `json:"height"`
+	FPS         float64 `json:"fps"`
+	PixFmt      string  `json:"pix_fmt,omitempty"`
+	BitRate     int64   `json:"bit_rate,omitempty"`
+	AttachedPic bool    `json:"attached_pic,omitempty"`
+}
+
+type AudioStreamInfo struct {
+	Index         int    `json:"index"`
+	Codec         string `json:"codec"`
+	Language      string `json:"language,omitempty"`
+	Channels      int    `json:"channels"`
+	ChannelLayout string `json:"channel_layout,omitempty"`
+	SampleRate    int    `json:"sample_rate,omitempty"`
+	BitRate       int64  `json:"bit_rate,omitempty"`
+}
+
+type SubtitleStreamInfo struct {
+	Index    int    `json:"index"`
+	Codec    string `json:"codec"`
+	Language string `json:"language,omitempty"`
+	Title    string `json:"title,omitempty"`
+}
+
+type Chapter struct {
+	Start time.Duration `json:"-"`
+	End   time.Duration `json:"-"`
+	Title string        `json:"title,omitempty"`
+}
+
+// Reports whether the file has anything the player can show
+func (m *MediaInfo) Playable() bool {
+	return len(m.Video) > 0 || len(m.Audio) > 0
+}
+
+// Encodes durations as seconds
+func (m MediaInfo) MarshalJSON() ([]byte, error) {
+	type plain MediaInfo
+	return json.Marshal(struct {
+		plain
+		Duration float64 `json:"duration"`
+	}{plain(m), m.Duration.Seconds()})
+}
+
+func (c Chapter) MarshalJSON() ([]byte, error) {
+	type plain Chapter
+	return json.Marshal(struct {
+		plain
+		Start float64 `json:"start"`
+		End   float64 `json:"end"`
+	}{plain(c), c.Start.Seconds(), c.End.Seconds()})
+}
+
+// Runs a full ffprobe of the file: container, all streams and chapters
+func ProbeInfo(ctx context.Context, path string) (*MediaInfo, error) {
+	if err := checkInput(ctx, path); err != nil {
+		return nil, err
+	}
+	input, err := InputArg(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := newInputCommand(ctx, "ffprobe",
+		"-v", "error",
+		"-show_format",
+		"-show_streams",
+		"-show_chapters",
+		"-of", "json",
+		input,
+	)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %w", err)
+	}
+	return parseInfoOutput(out)
+}
+
+// Subset of ffprobe's full JSON output
+type infoDoc struct {
+	Format struct {
+		FormatName     string `json:"format_name"`
+		FormatLongName string `json:"format_long_name"`
+		Duration       string `json:"duration"`
+		BitRate        string `json:"bit_rate"`
+		Size           string `json:"size"`
+	} `json:"format"`
+	Streams  []infoStream `json:"streams"`
+	Chapters []struct {
+		StartTime string            `json:"start_time"`
+		EndTime   string            `json:"end_time"`
+		Tags      map[string]string `json:"tags"`
+	} `json:"chapters"`
+}
+
+type infoStream struct {
+	probeStream
+	ChannelLayout string            `json:"channel_layout"`
+	Tags          map[string]string `json:"tags"`
+}
+
+func parseInfoOutput(output []byte) (*MediaInfo, error) {
+	var doc infoDoc
+	if err := json.Unmarshal(output, &doc); err != nil {
+		return nil, fmt.Errorf("ffprobe output: %w", err)
+	}
+
+	info := &MediaInfo{
+		Format:     doc.Format.FormatName,
+		FormatName: doc.Format.FormatLongName,
+		Duration:   parseSeconds(doc.Format.Duration),
+		BitRate:    parseInt(doc.Format.BitRate),
+		Size:       parseInt(doc.Format.Size),
+	}
+
+	videos := 0
+	for _, s := range doc.Streams {
+		switch s.CodecType {
+		case "video":
+			videos++
+			if s.Width <= 0 || s.Height <= 0 {
+				continue
+			}
+			rates := frameRates{real: parseFPS(s.RFrameRate), avg: parseFPS(s.AvgFrameRate)}
+			rates.nbFrames, _ = strconv.Atoi(s.NbFrames)
+			info.Video = append(info.Video, VideoStreamInfo{
+				Index:       s.Index,
+				Number:      videos - 1,
+				Codec:       s.CodecName,
+				Width:       s.Width,
+				Height:      s.Height,
+				FPS:         rates.choose(info.Duration),
+				PixFmt:      s.PixFmt,
+				BitRate:     parseInt(s.BitRate),
+				AttachedPic: s.Disposition.AttachedPic != 0,
+			})
+		case "audio":
+			info.Audio = append(info.Audio, AudioStreamInfo{
+				Index:         s.Index,
+				Codec:         s.CodecName,
+				Language:      s.Tags["language"],
+				Channels:      s.Channels,
+				ChannelLayout: s.ChannelLayout,
+				SampleRate:    int(parseInt(s.SampleRate)),
+				BitRate:       parseInt(s.BitRate),
+			})
+		case "subtitle":
+			info.Subtitles = append(info.Subtitles, SubtitleStreamInfo{
+				Index:    s.Index,
+				Codec:    s.CodecName,
+				Language: s.Tags["language"],
+				Title:    s.Tags["title"],
+			})
+		}
+	}
+
+	for _, c := range doc.Chapters {
+		info.Chapters = append(info.Chapters, Chapter{
+			Start: parseSeconds(c.StartTime),
+			End:   parseSeconds(c.EndTime),
+			Title: c.Tags["title"],
+		})
+	}
+	return info, nil
+}
+
+// Parses ffprobe's seconds strings; "N/A" and garbage give zero
+func parseSeconds(s string) time.Duration {
+	sec, err := strconv.ParseFloat(s, 64)
+	// Also rules out NaN; anything past the largest Duration is no time
+	// either
+	if err != nil || !(sec > 0) || sec > math.MaxInt64/float64(time.Second) {
+		return 0
+	}
+	return time.Duration(sec * float64(time.Second))
+}
+
+func parseInt(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
@@ -0,0 +1,28 @@
+package video
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSeconds(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"12.012000", 12012 * time.Millisecond},
+		{"0.5", 500 * time.Millisecond},
+		{"N/A", 0},
+		{"", 0},
+		{"0.000000", 0},
+		{"-1.5", 0},
+		{"nan", 0},
+		{"inf", 0},
+		{"1e300", 0},
+	}
+	for _, tt := range tests {
+		if got := parseSeconds(tt.in); got != tt.want {
+			t.Errorf("parseSeconds(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package video
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrUnsupportedScheme = errors.New("unsupported URL scheme")
+
+// Path naming standard input, as in "some-producer | pixlgo -"
+const StdinPath = "-"
+
+// URL schemes passed through to ffmpeg unchanged
+var allowedSchemes = map[string]bool{
+	"http":  true,
+	"https": true,
+	"rtsp":  true,
+	"rtsps": true,
+	"rtmp":  true,
+	"rtmps": true,
+}
+
+// Reports whether path is a URL rather than a local file
+func IsURL(path string) bool {
+	_, ok := urlScheme(path)
+	return ok
+}
+
+// Input options that go before -i: those of the concat demuxer for concat
+// lists, and those of networkArgs
+func inputOptions(input string) []string {
+	if args := concatArgs(input); args != nil {
+		return args
+	}
+	return networkArgs(input)
+}
+
+// Input options for network inputs: HTTP(S) reconnects after dropped
+// connections, also mid-stream, backing off up to 5 seconds, and sends the
+// headers and cookies from SetTools; RTSP uses TCP, since UDP loses packets
+// and often doesn't pass NAT at all
+func networkArgs(input string) []string {
+	scheme, _ := urlScheme(input)
+	switch scheme {
+	case "http", "https":
+		args := []string{"-reconnect", "1", "-reconnect_streamed", "1", "-reconnect_delay_max", "5"}
+		return append(args, httpArgs(input)...)
+	case "rtsp", "rtsps":
+		return []string{"-rtsp_transport", "tcp"}
+	}
+	return nil
+}
+
+// ffmpeg messages meaning the host of a URL couldn't be reached
+var unreachableMessages = []string{
+	"Failed to resolve hostname",
+	"Connection refused",
+	"Connection timed out",
+	"Network is unreachable",
+	"No route to host",
+	"Name or service not known",
+}
+
+// Rewords a failed probe of a URL whose host couldn't be reached
+func urlError(path string, err error) error {
+	var perr *ProbeError
+	if !IsURL(path) || !errors.As(err, &perr) {
+		return err
+	}
+	for _, msg := range unreachableMessages {
+		if strings.Contains(perr.Stderr, msg) {
+			return fmt.Errorf("cannot reach %s: %s", path, perr.Stderr)
+		}
+	}
+	return err
+}
+
+// Converts a user-supplied path into an ffmpeg/ffprobe input argument.
+// Local files become absolute "file:" URLs so names starting with "-" are
+// not read as options and names containing "concat:" or "pipe:" don't
+// select a protocol. URLs are only accepted for allowed schemes, and
+// StdinPath becomes pipe:0.
+func InputArg(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("empty input path")
+	}
+	if path == StdinPath {
+		return "pipe:0", nil
+	}
+
+	if scheme, ok := urlScheme(path); ok {
+		if !allowedSchemes[scheme] {
+			return "", fmt.Errorf("%w: %s", ErrUnsupportedScheme, scheme)
+		}
+		return path, nil
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("resolve path: %w", err)
+	}
+	return "file:" + filepath.ToSlash(abs), nil
+}
+
+// Checks that a local input exists and is readable, so a missing file is
+// reported as such rather than as an ffprobe failure. URLs are not checked.
+// Gives up with ctx.Err() when ctx ends first, e.g. on a hung network mount.
+func checkInput(ctx context.Context, path string) error {
+	if _, ok := urlScheme(path); ok || path == StdinPath {
+		return nil
+	}
+	return withContext(ctx, func() error {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("cannot access file: %w", err)
+		}
+		return f.Close()
+	})
+}
+
+// Like os.Stat, but returns ctx.Err() once ctx ends. The stat itself can't
+// be interrupted and finishes in the background.
+func StatContext(ctx context.Context, path string) (os.FileInfo, error) {
+	var info os.FileInfo
+	err := withContext(ctx, func() error {
+		var err error
+		info, err = os.Stat(path)
+		return err
+	})
+	return info, err
+}
+
+// Runs fn, returning early with ctx.Err() if ctx ends before it does
+func withContext(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Returns the lowercased scheme if path looks like scheme://...
+func urlScheme(path string) (string, bool) {
+	idx := strings.Index(path, "://")
+	if idx <= 0 {
+		return "", false
+	}
+	scheme := path[:idx]
+	for i, c := range scheme {
+		isAlpha := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		isOther := (c >= '0' && c <= '9') || c == '+' || c == '-' || c == '.'
+		if !isAlpha && (i == 0 || !isOther) {
+			return "", false
+		}
+	}
+	return strings.ToLower(scheme), true
+}
@@ -0,0 +1,105 @@
+package video
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0bVdnt/PixlGo/internal/fakeff"
+)
+
+func TestInputArg(t *testing.T) {
+	dir := t.TempDir()
+	local := func(name string) string {
+		return "file:" + filepath.ToSlash(filepath.Join(dir, name))
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want string
+		err  error
+	}{
+		{"dash prefix", filepath.Join(dir, "-i.mp4"), local("-i.mp4"), nil},
+		{"option lookalike", filepath.Join(dir, "-y"), local("-y"), nil},
+		{"spaces", filepath.Join(dir, "my clip  .mp4"), local("my clip  .mp4"), nil},
+		{"newline", filepath.Join(dir, "two\nlines.mp4"), local("two\nlines.mp4"), nil},
+		{"non-UTF-8", filepath.Join(dir, "caf\xe9\xff.mp4"), local("caf\xe9\xff.mp4"), nil},
+		{"protocol lookalike", filepath.Join(dir, "concat:a.mp4|b.mp4"), local("concat:a.mp4|b.mp4"), nil},
+		{"pipe lookalike", filepath.Join(dir, "pipe:1"), local("pipe:1"), nil},
+		{"stdin", StdinPath, "pipe:0", nil},
+		{"https", "https://example.com/a.mp4", "https://example.com/a.mp4", nil},
+		{"scheme case", "RTSP://cam/stream", "RTSP://cam/stream", nil},
+		{"file scheme", "file:///etc/passwd", "", ErrUnsupportedScheme},
+		{"other scheme", "ftp://host/a.mp4", "", ErrUnsupportedScheme},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := InputArg(tt.path)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("InputArg(%q) error = %v, want %v", tt.path, err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("InputArg(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := InputArg(""); err == nil {
+		t.Error("InputArg(\"\") should fail")
+	}
+}
+
+func TestInputArgRelative(t *testing.T) {
+	t.Chdir(t.TempDir())
+	got, err := InputArg("-clip.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, _ := os.Getwd()
+	if want := "file:" + filepath.ToSlash(filepath.Join(wd, "-clip.mp4")); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// ffprobe has to get an unusual name as one argument after -i's position,
+// never split or read as an option
+func TestProbeUnusualNames(t *testing.T) {
+	fixture, _ := filepath.Abs(filepath.Join("testdata", "probe", "mp4.json"))
+	names := []string{"-i.mp4", "-v quiet.mp4", "my clip.mp4", "two\nlines.mp4", "caf\xe9\xff.mp4"}
+
+	for _, name := range names {
+		t.Run(strings.ReplaceAll(name, "\n", `\n`), func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, nil, 0o644); err != nil {
+				t.Skipf("file system refuses the name: %v", err)
+			}
+			argsFile := filepath.Join(dir, "args")
+			useFakeTools(t, map[string]string{"ARGS": argsFile, "PROBE": fixture})
+
+			meta, err := ProbeContext(context.Background(), path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta.Path != path {
+				t.Errorf("Path = %q, want %q", meta.Path, path)
+			}
+
+			want := "file:" + filepath.ToSlash(path)
+			for _, args := range fakeff.Args(t, argsFile) {
+				if args[len(args)-1] != want {
+					t.Errorf("input argument = %q, want %q", args[len(args)-1], want)
+				}
+				for _, arg := range args[:len(args)-1] {
+					if strings.Contains(arg, name) {
+						t.Errorf("name leaked into option %q", arg)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package video
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// Default bound on either side of a decoded frame
+const DefaultMaxDimension = 4096
+
+var maxDimension atomic.Int64
+
+func init() {
+	maxDimension.Store(DefaultMaxDimension)
+}
+
+// Sets the bound on either side of every decoded frame; n <= 0 restores
+// DefaultMaxDimension
+func SetMaxDimension(n int) {
+	if n <= 0 {
+		n = DefaultMaxDimension
+	}
+	maxDimension.Store(int64(n))
+}
+
+// Returns the bound set by SetMaxDimension
+func MaxDimension() int {
+	return int(maxDimension.Load())
+}
+
+// Returns the size a decode of width x height is actually run at: even,
+// at least 4, and scaled down proportionally when either side exceeds
+// MaxDimension, so the aspect ratio survives the cap
+func FitSize(width, height int) (int, int) {
+	w, h, _ := LimitSize(width, height, MaxDimension())
+	return w, h
+}
+
+// Scales width x height down, keeping the aspect ratio, until neither side
+// exceeds maxDim. Dimensions stay even and at least 4. Reports whether the
+// size was scaled; maxDim <= 0 means no bound.
+func LimitSize(width, height, maxDim int) (int, int, bool) {
+	width, height = normalizeEven(width, 4), normalizeEven(height, 4)
+	if maxDim <= 0 || width <= maxDim && height <= maxDim {
+		return width, height, false
+	}
+	scale := float64(maxDim) / float64(max(width, height))
+	w := normalizeEven(min(int(float64(width)*scale), maxDim), 4)
+	h := normalizeEven(min(int(float64(height)*scale), maxDim), 4)
+	return w, h, true
+}
+
+// Rounds v down to an even number, but not below lo
+func normalizeEven(v, lo int) int {
+	return max(v/2*2, lo)
+}
+
+// Default cap on the decoded frame area in pixels, far below the
+// DefaultMaxDimension square. A full frame costs about 23 bytes per pixel while
+// streaming (see StreamMemory), so this keeps one stream under 50MB.
+const DefaultMaxFrameArea = 1920 * 1080
+
+// Scales width x height down, keeping the aspect ratio, until the area is
+// at most maxArea pixels. Dimensions stay even and at least 4. Reports
+// whether the size changed; maxArea <= 0 means no cap.
+func LimitArea(width, height, maxArea int) (int, int, bool) {
+	if maxArea <= 0 || width*height <= maxArea {
+		return width, height, false
+	}
+	scale := math.Sqrt(float64(maxArea) / float64(width*height))
+	w := max(int(float64(width)*scale)/2*2, 4)
+	h := max(int(float64(height)*scale)/2*2, 4)
+	return w, h, true
+}
+
+// Returns the bytes a running stream keeps allocated for frames of this
+// size: its pipe read buffer (four rgb24 frames), the rgb24 scratch frame
+// and the two RGBA images it alternates between
+func StreamMemory(width, height int) int64 {
+	rgb := int64(width) * int64(height) * 3
+	rgba := int64(width) * int64(height) * 4
+	return 4*rgb + rgb + 2*rgba
+}
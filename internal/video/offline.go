@@ -0,0 +1,79 @@
+package video
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+	"time"
+)
+
+// Decodes frames as fast as ffmpeg delivers them and calls fn for each, in
+// order. TargetFPS must be set. The frame's image is reused between calls.
+// Returns ctx.Err() if cancelled, or the first error from fn.
+func DecodeAll(ctx context.Context, path string, config StreamConfig, fn func(*Frame) error) error {
+	if config.TargetFPS <= 0 {
+		return fmt.Errorf("decode: no target fps")
+	}
+	width, height := FitSize(config.Width, config.Height)
+
+	input, err := InputArg(path)
+	if err != nil {
+		return err
+	}
+
+	cmdCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd := newInputCommand(cmdCtx, "ffmpeg", buildFFmpegArgs(input, width, height, config)...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start: %w", err)
+	}
+
+	frameSize := width * height * 3
+	frameDuration := time.Duration(float64(time.Second) / config.TargetFPS)
+	reader := bufio.NewReaderSize(stdout, frameSize*2)
+	rgbBuf := make([]byte, frameSize)
+	frame := &Frame{Image: image.NewRGBA(image.Rect(0, 0, width, height))}
+
+	var fnErr error
+	for n := 0; ; n++ {
+		if _, err := io.ReadFull(reader, rgbBuf); err != nil {
+			break
+		}
+		Stats.FramesDecoded.Add(1)
+		convertRGB24ToRGBA(rgbBuf, frame.Image.Pix)
+		frame.Timestamp = config.StartPos + time.Duration(n)*frameDuration
+		frame.Decoded = time.Now()
+		if fnErr = fn(frame); fnErr != nil {
+			break
+		}
+	}
+
+	if fnErr != nil {
+		// Kill ffmpeg rather than let it block on a full pipe
+		cancel()
+	}
+	waitErr := cmd.Wait()
+	switch {
+	case fnErr != nil:
+		return fnErr
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case waitErr != nil:
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("ffmpeg: %s", msg)
+		}
+		return fmt.Errorf("ffmpeg: %w", waitErr)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package video
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+var errPiped = errors.New("not possible with piped input")
+
+// Hands a reader's data to one ffmpeg or ffprobe at a time. Restarting a
+// stream starts a new feeder; data a killed process never took is kept
+// for the next one, so it continues where the last left off.
+type pipeInput struct {
+	mu      sync.Mutex
+	r       io.Reader
+	pending []byte // read from r, not yet taken by a decoder
+	err     error  // r's read error; io.EOF at its end
+}
+
+// Copies input to w until a write fails or the reader ends, then closes w.
+// With retain set the data stays pending, so a decoder started after a
+// probe gets it again. The lock is held across each read and write, which
+// keeps chunks in order when an old feeder is still waiting on r.
+func (p *pipeInput) feed(w io.WriteCloser, retain bool) {
+	defer w.Close()
+	buf := make([]byte, 64*1024)
+	offset := 0
+	for {
+		p.mu.Lock()
+		var chunk []byte
+		switch {
+		case retain && offset < len(p.pending):
+			chunk = p.pending[offset:]
+		case !retain && len(p.pending) > 0:
+			chunk, p.pending = p.pending, nil
+		case p.err != nil:
+			p.mu.Unlock()
+			return
+		default:
+			n, err := p.r.Read(buf)
+			if err != nil {
+				p.err = err
+			}
+			chunk = append([]byte(nil), buf[:n]...)
+			if retain {
+				p.pending = append(p.pending, chunk...)
+			}
+		}
+		offset += len(chunk)
+		n, err := w.Write(chunk)
+		if err != nil && !retain {
+			p.pending = append(chunk[n:], p.pending...)
+		}
+		p.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Creates a decoder for video piped in through r, such as os.Stdin. The
+// format is probed from the first data; non-zero Width, Height and FPS in
+// hint override what the probe found, and stand in for it when the probe
+// fails. Piped input plays once, without seeking or sound.
+func NewDecoderFromReader(r io.Reader, hint Metadata) (*Decoder, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultProbeTimeout)
+	defer cancel()
+	return NewDecoderFromReaderContext(ctx, r, hint, Logs{})
+}
+
+// Like NewDecoderFromReader with probing bounded by ctx
+func NewDecoderFromReaderContext(ctx context.Context, r io.Reader, hint Metadata, logs Logs) (*Decoder, error) {
+	logs = logs.withDefaults()
+	logs.Info("File: standard input")
+	if !toolAvailable("ffmpeg") {
+		return nil, toolMissing("ffmpeg")
+	}
+
+	stdin := &pipeInput{r: r}
+	meta, err := probeInput(ctx, StdinPath, "pipe:0", stdin)
+	switch {
+	case err == nil:
+	case ctx.Err() == nil && hint.Width > 0 && hint.Height > 0 && hint.FPS > 0:
+		logs.Info("Probe failed, using the given size and rate: %v", err)
+		meta = &Metadata{Path: StdinPath, Live: true}
+	default:
+		logs.Error("Probe failed: %v", err)
+		return nil, err
+	}
+	if hint.Width > 0 && hint.Height > 0 {
+		meta.Width, meta.Height = hint.Width, hint.Height
+		meta.DisplayWidth, meta.DisplayHeight = hint.Width, hint.Height
+	}
+	if hint.FPS > 0 {
+		meta.FPS = hint.FPS
+	}
+
+	logs.Info("Metadata: %dx%d @ %.2f fps, codec=%s, duration=%v",
+		meta.Width, meta.Height, meta.FPS, meta.Codec, meta.Duration)
+
+	d := &Decoder{
+		path:     StdinPath,
+		metadata: *meta,
+		logs:     logs,
+		subtitle: -1,
+		stdin:    stdin,
+	}
+	d.volume.Store(100)
+	return d, nil
+}
+
+// Returns errPiped, naming what, for operations that need to read the
+// input again
+func pipedError(what string) error {
+	return fmt.Errorf("%s: %w", what, errPiped)
+}
@@ -1,9 +1,14 @@
 package video
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os/exec"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -11,11 +16,153 @@ import (
 
 // Contains video file information
 type Metadata struct {
+	// The probed file, and ffprobe's name for its container format, e.g.
+	// "mov,mp4,m4a,3gp,3g2,mj2" or "png_pipe"
+	Path   string
+	Format string
+
 	Width    int
 	Height   int
 	FPS      float64
 	Duration time.Duration
 	Codec    string
+
+	// Duration came from the video stream or its frame count because the
+	// container reported none, as in fragmented MP4s
+	DurationEstimated bool
+	// A network stream without a duration, such as a live HLS playlist,
+	// or piped input. It plays from the live edge and can't seek.
+	Live bool
+
+	// Absolute ffmpeg stream index of the chosen video stream
+	StreamIndex int
+	// The chosen stream is embedded cover art rather than video
+	AttachedPic bool
+	// An animated GIF, APNG or WebP; FPS is then the rate of its shortest
+	// frame delay, so the fps filter keeps every frame
+	Animated bool
+	// A single picture such as a PNG, JPEG or WebP, with no timeline
+	Still bool
+	// Frame count from the container's nb_frames; 0 when it doesn't say
+	Frames int
+	// ffprobe's color_range: "tv" (limited, 16-235), "pc" (full) or empty
+	ColorRange string
+	// Pixel format, profile and level as ffprobe names them, e.g. yuv420p,
+	// High and 40; empty (0 for Level) when not reported
+	PixelFormat string
+	Profile     string
+	Level       int
+	// Bits per second of the video stream, else of the whole file; 0 when
+	// unknown
+	BitRate int64
+
+	// Clockwise turn the frames need to show upright, 0, 90, 180 or 270;
+	// set by phones recording in portrait
+	Rotation int
+
+	// Size at which the video is meant to be shown; differs from
+	// Width/Height for anamorphic video with a non-square sample aspect,
+	// and is swapped for a quarter turn of Rotation
+	DisplayWidth  int
+	DisplayHeight int
+
+	// ffprobe's sample and display aspect ratios, such as "64:45" and
+	// "16:9"; empty when not reported
+	SampleAspect       string
+	DisplayAspectRatio string
+
+	// The first audio stream: codec, samples per second and channel count
+	HasAudio   bool
+	AudioCodec string
+	SampleRate int
+	Channels   int
+
+	// Every stream in the file, in index order
+	Streams []StreamInfo
+	// The first embedded cover image, also when a real video stream was
+	// chosen; nil without one
+	CoverArt *StreamInfo
+
+	// Chapters in start order; nil for files without them
+	Chapters []Chapter
+
+	// The files a concat list joins, in order; nil for other inputs
+	Parts []string
+
+	// Container tags such as title, artist and encoder, keys lowercased
+	// since muxers disagree on TITLE vs title
+	Tags map[string]string
+
+	// The video streams as probed, for SelectVideoStream, and the
+	// container's bit rate for streams that don't report their own
+	videoStreams  []probeStream
+	formatBitRate int64
+}
+
+// Returns a container tag, matching key case-insensitively; "" if unset
+func (m *Metadata) Tag(key string) string {
+	return m.Tags[strings.ToLower(key)]
+}
+
+// Returns the title tag, or the file's base name without one
+func (m *Metadata) Title() string {
+	if title := strings.TrimSpace(m.Tag("title")); title != "" {
+		return title
+	}
+	switch m.Path {
+	case "":
+		return ""
+	case StdinPath:
+		return "stdin"
+	}
+	if len(m.Parts) > 0 {
+		return fmt.Sprintf("%s +%d", filepath.Base(m.Parts[0]), len(m.Parts)-1)
+	}
+	return filepath.Base(m.Path)
+}
+
+// One stream of a file as ffprobe lists it
+type StreamInfo struct {
+	// Absolute stream index, and the position among streams of the same
+	// type as in ffmpeg's 0:a:N specifiers
+	Index     int
+	TypeIndex int
+	// "video", "audio", "subtitle", "data" or "attachment"
+	Type     string
+	Codec    string
+	Language string // from the language tag; empty when untagged
+	// Picture size of video streams; 0 for other types
+	Width  int
+	Height int
+	// Channel count of audio streams; 0 for other types or when unknown
+	Channels int
+}
+
+// Returns the streams of one type, e.g. "audio", in TypeIndex order
+func (m *Metadata) StreamsOf(kind string) []StreamInfo {
+	var streams []StreamInfo
+	for _, s := range m.Streams {
+		if s.Type == kind {
+			streams = append(streams, s)
+		}
+	}
+	return streams
+}
+
+// Returns the display aspect ratio, honoring the sample aspect ratio
+func (m *Metadata) DisplayAspect() float64 {
+	if m.DisplayWidth > 0 && m.DisplayHeight > 0 {
+		return float64(m.DisplayWidth) / float64(m.DisplayHeight)
+	}
+	if m.Width > 0 && m.Height > 0 {
+		return float64(m.Width) / float64(m.Height)
+	}
+	return 0
+}
+
+// Returns the ffmpeg -map specifier for the chosen stream
+func (m *Metadata) MapArg() string {
+	return fmt.Sprintf("0:%d", m.StreamIndex)
 }
 
 // Checks if metadata has all the required fields
@@ -23,81 +170,602 @@ func (m *Metadata) IsValid() bool {
 	return m.Width > 0 && m.Height > 0
 }
 
-// Extracts metadata from the video file
+// Reports whether the file is sound with at most cover art, like a music
+// file
+func (m *Metadata) HasOnlyAudio() bool {
+	return m.HasAudio && (!m.IsValid() || m.AttachedPic)
+}
+
+// Returns ErrNoVideoStream unless there is a picture to decode. Probing
+// succeeds for audio-only files, so callers that need frames check here.
+func (m *Metadata) RequireVideo() error {
+	if m.IsValid() {
+		return nil
+	}
+	if m.HasAudio {
+		return fmt.Errorf("%w: %s has only audio", ErrNoVideoStream, m.Title())
+	}
+	return ErrNoVideoStream
+}
+
+// How long Probe waits for ffprobe
+const DefaultProbeTimeout = 10 * time.Second
+
+// A failed ffprobe run: either it timed out or it exited with an error
+type ProbeError struct {
+	Path    string
+	Timeout time.Duration // set when the deadline ran out
+	Stderr  string        // last lines ffprobe printed, if any
+	Err     error
+}
+
+func (e *ProbeError) Error() string {
+	if e.Timeout > 0 {
+		return fmt.Sprintf("ffprobe timed out after %v", e.Timeout)
+	}
+	if e.Stderr != "" {
+		return fmt.Sprintf("ffprobe failed: %v: %s", e.Err, e.Stderr)
+	}
+	return fmt.Sprintf("ffprobe failed: %v", e.Err)
+}
+
+func (e *ProbeError) Unwrap() error {
+	return e.Err
+}
+
+// Extracts metadata from the video file, giving up after DefaultProbeTimeout
 func Probe(path string) (*Metadata, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultProbeTimeout)
 	defer cancel()
+	return ProbeContext(ctx, path)
+}
 
-	meta := &Metadata{}
-
-	// Probe video stream
-	if err := probeVideoStream(ctx, path, meta); err != nil {
+// Like Probe, bounded by ctx instead of a fixed timeout. Cancelling ctx
+// kills ffprobe and returns ctx.Err().
+func ProbeContext(ctx context.Context, path string) (*Metadata, error) {
+	if err := checkInput(ctx, path); err != nil {
+		return nil, err
+	}
+	input, err := InputArg(path)
+	if err != nil {
 		return nil, err
 	}
+	return probeInput(ctx, path, input, nil)
+}
+
+// Probes input, fed from stdin instead when it is set
+func probeInput(ctx context.Context, path, input string, stdin *pipeInput) (*Metadata, error) {
+	meta := &Metadata{Path: path}
+	rates := &frameRates{}
 
-	// Probe Duration
-	probeDuration(ctx, path, meta)
+	// Streams and duration in one run; each ffprobe start costs a file open
+	// and header parse, which dominates startup for local files
+	if err := probeVideoStream(ctx, input, stdin, meta, rates); err != nil {
+		return nil, urlError(path, err)
+	}
 
-	// Set defaults
-	if meta.FPS <= 0 {
-		meta.FPS = 25
+	meta.FPS = rates.choose(meta.Duration)
+	// Without a container or stream duration, as in some screen
+	// recordings, the frame count still tells
+	if meta.Duration == 0 && rates.nbFrames > 1 && !meta.AttachedPic {
+		meta.Duration = time.Duration(float64(rates.nbFrames) / meta.FPS * float64(time.Second))
+		meta.DurationEstimated = true
 	}
+	meta.applyConcat()
+	meta.Live = meta.Duration == 0 && IsURL(path) || stdin != nil
 
-	if !meta.IsValid() {
-		return nil, fmt.Errorf("no video stream found")
+	// Audio-only files are described all the same; whether to play them
+	// is up to the caller
+	if !meta.IsValid() && !meta.HasAudio {
+		return nil, ErrNoVideoStream
+	}
+
+	// Timing animations takes another pass over the input
+	if animatedCodecs[meta.Codec] && stdin == nil {
+		if err := probeAnimation(ctx, input, meta); err != nil {
+			return nil, err
+		}
+	}
+	// Image demuxers report one frame's duration. Piped pictures still go
+	// through the stream, which is all a pipe can be read as.
+	if (isImageFormat(meta.Format) || animatedCodecs[meta.Codec]) && !meta.Animated && stdin == nil {
+		meta.Still = true
+		meta.Duration, meta.DurationEstimated = 0, false
+		meta.Live = false
 	}
 
 	return meta, nil
 }
 
-func probeVideoStream(ctx context.Context, path string, meta *Metadata) error {
-	// Video stream info
-	cmd := exec.CommandContext(ctx, "ffprobe",
+// Frame rate candidates reported by ffprobe
+type frameRates struct {
+	real     float64 // r_frame_rate
+	avg      float64 // avg_frame_rate
+	nbFrames int
+}
+
+const (
+	minFPS     = 1
+	maxFPS     = 240
+	defaultFPS = 25
+)
+
+// Picks a usable FPS: r_frame_rate unless it is degenerate or far from
+// avg_frame_rate, then avg_frame_rate, then nb_frames/duration, then 25
+func (fr *frameRates) choose(duration time.Duration) float64 {
+	valid := func(f float64) bool { return f >= minFPS && f <= maxFPS }
+
+	r, avg := fr.real, fr.avg
+	if valid(r) && (!valid(avg) || r/avg < 1.5 && avg/r < 1.5) {
+		return r
+	}
+	if valid(avg) {
+		return avg
+	}
+	if fr.nbFrames > 0 && duration > 0 {
+		if derived := float64(fr.nbFrames) / duration.Seconds(); valid(derived) {
+			return derived
+		}
+	}
+	if r > 0 {
+		return min(max(r, minFPS), maxFPS)
+	}
+	return defaultFPS
+}
+
+func probeVideoStream(ctx context.Context, path string, stdin *pipeInput, meta *Metadata, rates *frameRates) error {
+	// All streams: the video ones so cover art can be skipped in favour of
+	// real video, the rest to list audio and subtitle tracks
+	args := append(inputOptions(path),
 		"-v", "error",
-		"-select_streams", "v:0",
-		"-show_entries", "stream=width,height,r_frame_rate,codec_name",
-		"-of", "default=noprint_wrappers=1",
+		"-show_entries", "stream=index,codec_type,duration,width,height,r_frame_rate,avg_frame_rate,nb_frames,codec_name,sample_aspect_ratio,display_aspect_ratio,color_range,pix_fmt,profile,level,bit_rate,sample_rate,channels:stream_tags=language,rotate:stream_side_data=rotation:stream_disposition=attached_pic:format=duration,bit_rate,format_name:format_tags:chapter=start_time,end_time:chapter_tags=title",
+		"-of", "json",
 		path,
 	)
-
-	out, err := cmd.Output()
+	start := time.Now()
+	out, err := runProbe(ctx, args, stdin)
+	if err != nil && ctx.Err() == nil && isOldFFprobe(err) {
+		// Builds from before -show_entries and JSON output only know the
+		// default key=value writer
+		args = append(inputOptions(path), "-v", "error", "-show_streams", "-show_format", path)
+		if flat, flatErr := runProbe(ctx, args, stdin); flatErr == nil {
+			out, err = flatProbeJSON(flat)
+		}
+	}
 	if err != nil {
-		return fmt.Errorf("ffprobe failed: %w", err)
+		return probeError(ctx, path, time.Since(start), err)
 	}
 
-	parseProbeOutput(string(out), meta)
-	return nil
+	return parseProbeOutput(out, meta, rates)
+}
+
+// Runs ffprobe with args, feeding it piped input when there is some
+func runProbe(ctx context.Context, args []string, stdin *pipeInput) ([]byte, error) {
+	cmd := newInputCommand(ctx, "ffprobe", args...)
+	if stdin != nil {
+		w, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, fmt.Errorf("stdin pipe: %w", err)
+		}
+		// The data ffprobe reads is kept for the decoder
+		go stdin.feed(w, true)
+	}
+	return cmd.Output()
+}
+
+// ffprobe messages meaning the build predates -show_entries or -of json
+var oldFFprobeMessages = []string{
+	"Unrecognized option 'show_entries'",
+	"Unrecognized option 'of'",
+	"Unknown output format with name 'json'",
 }
 
-func parseProbeOutput(output string, meta *Metadata) {
-	for _, line := range strings.Split(output, "\n") {
+// Reports whether a failed ffprobe run failed for want of the options
+// that newer builds have
+func isOldFFprobe(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	for _, msg := range oldFFprobeMessages {
+		if bytes.Contains(exitErr.Stderr, []byte(msg)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stream fields of the default writer that the JSON one prints as numbers
+var flatIntFields = map[string]bool{"index": true, "width": true, "height": true, "level": true, "channels": true}
+
+// Converts the output of ffprobe's default writer, sections such as
+//
+//	[STREAM]
+//	index=0
+//	codec_name=h264
+//	TAG:language=und
+//	[/STREAM]
+//
+// into the JSON parseProbeOutput reads. Unknown ("N/A") values are left
+// out, as the JSON writer does.
+func flatProbeJSON(out []byte) ([]byte, error) {
+	var doc struct {
+		Streams []map[string]any `json:"streams"`
+		Format  map[string]any   `json:"format"`
+	}
+	var section map[string]any
+	for _, line := range strings.Split(string(out), "\n") {
 		line = strings.TrimSpace(line)
+		switch line {
+		case "[STREAM]":
+			section = map[string]any{}
+			doc.Streams = append(doc.Streams, section)
+			continue
+		case "[FORMAT]":
+			section = map[string]any{}
+			doc.Format = section
+			continue
+		case "[/STREAM]", "[/FORMAT]":
+			section = nil
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if section == nil || !ok || value == "N/A" {
+			continue
+		}
+		group, name, grouped := strings.Cut(key, ":")
+		switch {
+		case grouped && group == "TAG":
+			tags, _ := section["tags"].(map[string]any)
+			if tags == nil {
+				tags = map[string]any{}
+				section["tags"] = tags
+			}
+			tags[name] = value
+		case grouped && group == "DISPOSITION":
+			disposition, _ := section["disposition"].(map[string]any)
+			if disposition == nil {
+				disposition = map[string]any{}
+				section["disposition"] = disposition
+			}
+			disposition[name], _ = strconv.Atoi(value)
+		case grouped:
+		case flatIntFields[key]:
+			if n, err := strconv.Atoi(value); err == nil {
+				section[key] = n
+			}
+		default:
+			section[key] = value
+		}
+	}
+	if len(doc.Streams) == 0 && doc.Format == nil {
+		return nil, errors.New("no sections in ffprobe output")
+	}
+	return json.Marshal(doc)
+}
+
+// Classifies a failed ffprobe run
+func probeError(ctx context.Context, path string, took time.Duration, err error) error {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return &ProbeError{Path: path, Timeout: took.Round(100 * time.Millisecond), Err: context.DeadlineExceeded}
+	case context.Canceled:
+		return context.Canceled
+	}
+	perr := &ProbeError{Path: path, Err: err}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		perr.Stderr = stderrSnippet(exitErr.Stderr)
+	}
+	return perr
+}
+
+// Returns the last non-empty lines of stderr, at most about 200 bytes
+func stderrSnippet(stderr []byte) string {
+	const limit = 200
+	lines := strings.Split(strings.TrimSpace(string(stderr)), "\n")
+	snippet := ""
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
 		if line == "" {
 			continue
 		}
-		idx := strings.Index(line, "=")
-		if idx <= 0 {
+		if snippet != "" {
+			if len(line)+len(snippet) > limit {
+				break
+			}
+			line += "; " + snippet
+		}
+		snippet = line
+		if len(snippet) >= limit {
+			break
+		}
+	}
+	if len(snippet) > limit {
+		snippet = "…" + snippet[len(snippet)-limit:]
+	}
+	return snippet
+}
+
+// Image codecs that may hold several frames with per-frame delays
+var animatedCodecs = map[string]bool{"gif": true, "apng": true, "webp": true}
+
+// Reports whether ffprobe's format name is one of the image demuxers:
+// image2 for files named like pictures, or a codec-specific one such as
+// png_pipe when the name doesn't tell
+func isImageFormat(format string) bool {
+	return format == "image2" || strings.HasSuffix(format, "_pipe")
+}
+
+// Frame rate cap for animated images; GIF delays are in 10ms steps but
+// browsers treat anything under 20ms as slower, and so do we
+const maxAnimatedFPS = 50
+
+// Counts the frames of an animated image from its packet delays, which
+// ffprobe reports even when the stream has no usable rate or duration.
+// Single-frame images are left as stills.
+func probeAnimation(ctx context.Context, input string, meta *Metadata) error {
+	cmd := newInputCommand(ctx, "ffprobe",
+		"-v", "error",
+		"-select_streams", strconv.Itoa(meta.StreamIndex),
+		"-show_entries", "packet=duration_time",
+		"-of", "csv=p=0",
+		input,
+	)
+	start := time.Now()
+	out, err := cmd.Output()
+	if err != nil {
+		return probeError(ctx, input, time.Since(start), err)
+	}
+
+	frames := 0
+	var total, shortest float64
+	for _, line := range strings.Fields(string(out)) {
+		delay, err := strconv.ParseFloat(strings.Trim(line, ","), 64)
+		if err != nil || delay <= 0 {
 			continue
 		}
+		frames++
+		total += delay
+		if shortest == 0 || delay < shortest {
+			shortest = delay
+		}
+	}
+	if frames < 2 {
+		return nil
+	}
+
+	meta.Animated = true
+	meta.Duration = time.Duration(total * float64(time.Second))
+	meta.DurationEstimated = false
+	meta.FPS = min(max(1/shortest, minFPS), maxAnimatedFPS)
+	return nil
+}
+
+// Subset of ffprobe's JSON stream output
+type probeStream struct {
+	Index         int    `json:"index"`
+	CodecType     string `json:"codec_type"`
+	Duration      string `json:"duration"`
+	Width         int    `json:"width"`
+	Height        int    `json:"height"`
+	RFrameRate    string `json:"r_frame_rate"`
+	AvgFrameRate  string `json:"avg_frame_rate"`
+	NbFrames      string `json:"nb_frames"`
+	CodecName     string `json:"codec_name"`
+	SampleAspect  string `json:"sample_aspect_ratio"`
+	DisplayAspect string `json:"display_aspect_ratio"`
+	ColorRange    string `json:"color_range"`
+	PixFmt        string `json:"pix_fmt"`
+	Profile       string `json:"profile"`
+	Level         int    `json:"level"`
+	BitRate       string `json:"bit_rate"`
+	SampleRate    string `json:"sample_rate"`
+	Channels      int    `json:"channels"`
+	Disposition   struct {
+		AttachedPic int `json:"attached_pic"`
+	} `json:"disposition"`
+	Tags struct {
+		Language string `json:"language"`
+		Rotate   string `json:"rotate"`
+	} `json:"tags"`
+	SideData []probeSideData `json:"side_data_list"`
+}
+
+type probeSideData struct {
+	Rotation *float64 `json:"rotation"`
+}
+
+func parseProbeOutput(output []byte, meta *Metadata, rates *frameRates) error {
+	var doc struct {
+		Streams []probeStream `json:"streams"`
+		Format  struct {
+			Name     string            `json:"format_name"`
+			Duration string            `json:"duration"`
+			BitRate  string            `json:"bit_rate"`
+			Tags     map[string]string `json:"tags"`
+		} `json:"format"`
+		Chapters []struct {
+			StartTime string `json:"start_time"`
+			EndTime   string `json:"end_time"`
+			Tags      struct {
+				Title string `json:"title"`
+			} `json:"tags"`
+		} `json:"chapters"`
+	}
+	if err := json.Unmarshal(output, &doc); err != nil {
+		return fmt.Errorf("ffprobe output: %w", err)
+	}
+	meta.Duration = parseSeconds(doc.Format.Duration)
+
+	for key, value := range doc.Format.Tags {
+		if meta.Tags == nil {
+			meta.Tags = make(map[string]string, len(doc.Format.Tags))
+		}
+		meta.Tags[strings.ToLower(key)] = value
+	}
 
-		key := line[:idx]
-		val := line[idx+1:]
+	for _, c := range doc.Chapters {
+		meta.Chapters = append(meta.Chapters, Chapter{
+			Start: parseSeconds(c.StartTime),
+			End:   parseSeconds(c.EndTime),
+			Title: c.Tags.Title,
+		})
+	}
+	sort.SliceStable(meta.Chapters, func(i, j int) bool {
+		return meta.Chapters[i].Start < meta.Chapters[j].Start
+	})
 
-		switch key {
-		case "width":
-			meta.Width, _ = strconv.Atoi(val)
-		case "height":
-			meta.Height, _ = strconv.Atoi(val)
-		case "r_frame_rate":
-			meta.FPS = parseFPS(val)
-		case "codec_name":
-			meta.Codec = val
+	counts := map[string]int{}
+	for _, s := range doc.Streams {
+		meta.Streams = append(meta.Streams, StreamInfo{
+			Index:     s.Index,
+			TypeIndex: counts[s.CodecType],
+			Type:      s.CodecType,
+			Codec:     s.CodecName,
+			Language:  s.Tags.Language,
+			Width:     s.Width,
+			Height:    s.Height,
+			Channels:  s.Channels,
+		})
+		counts[s.CodecType]++
+		if s.Disposition.AttachedPic != 0 && meta.CoverArt == nil {
+			cover := meta.Streams[len(meta.Streams)-1]
+			meta.CoverArt = &cover
+		}
+		if s.CodecType == "video" {
+			meta.videoStreams = append(meta.videoStreams, s)
 		}
+		if s.CodecType == "audio" && !meta.HasAudio {
+			meta.HasAudio = true
+			meta.AudioCodec = s.CodecName
+			meta.SampleRate = int(parseInt(s.SampleRate))
+			meta.Channels = s.Channels
+		}
+	}
+
+	// An HLS master playlist lists every rendition as a stream; the
+	// smallest is plenty for a terminal and downloads the least
+	meta.Format = doc.Format.Name
+	meta.formatBitRate = parseInt(doc.Format.BitRate)
+	chosen := selectVideoStream(doc.Streams, doc.Format.Name == "hls")
+	if chosen == nil {
+		// Audio only; the caller tells that from an invalid size
+		return nil
+	}
+	meta.useVideoStream(chosen, rates)
+	// "N/A" format durations fall back to the stream's
+	if meta.Duration == 0 {
+		meta.Duration = parseSeconds(chosen.Duration)
+		meta.DurationEstimated = meta.Duration > 0
+	}
+	return nil
+}
+
+// Fills in the fields describing the chosen video stream s, and its frame
+// rate candidates
+func (m *Metadata) useVideoStream(s *probeStream, rates *frameRates) {
+	m.StreamIndex = s.Index
+	m.Width = s.Width
+	m.Height = s.Height
+	m.Codec = s.CodecName
+	m.AttachedPic = s.Disposition.AttachedPic != 0
+	m.SampleAspect, m.DisplayAspectRatio = s.SampleAspect, s.DisplayAspect
+	m.DisplayWidth, m.DisplayHeight = displaySize(s.Width, s.Height, s.SampleAspect, s.DisplayAspect)
+	m.Rotation = streamRotation(s.Tags.Rotate, s.SideData)
+	if m.Rotation == 90 || m.Rotation == 270 {
+		m.DisplayWidth, m.DisplayHeight = m.DisplayHeight, m.DisplayWidth
+	}
+	rates.real = parseFPS(s.RFrameRate)
+	rates.avg = parseFPS(s.AvgFrameRate)
+	rates.nbFrames, _ = strconv.Atoi(s.NbFrames)
+	m.Frames = max(rates.nbFrames, 0)
+	m.ColorRange = s.ColorRange
+	m.PixelFormat = s.PixFmt
+	m.Profile = s.Profile
+	m.Level = max(s.Level, 0)
+	m.BitRate = parseInt(s.BitRate)
+	if m.BitRate == 0 {
+		m.BitRate = m.formatBitRate
 	}
 }
 
+// Switches to video stream n, counted among the file's video streams as in
+// ffmpeg's 0:v:N, instead of the one Probe chose. Streams the file doesn't
+// have fail with a list of those it does.
+func (m *Metadata) SelectVideoStream(n int) error {
+	if n < 0 || n >= len(m.videoStreams) {
+		return fmt.Errorf("video stream %d does not exist; %s", n, m.videoStreamList())
+	}
+	s := &m.videoStreams[n]
+	if s.Width <= 0 || s.Height <= 0 {
+		return fmt.Errorf("video stream %d has no picture size; %s", n, m.videoStreamList())
+	}
+	rates := &frameRates{}
+	m.useVideoStream(s, rates)
+	m.FPS = rates.choose(m.Duration)
+	return nil
+}
+
+// Describes the video streams for error messages
+func (m *Metadata) videoStreamList() string {
+	if len(m.videoStreams) == 0 {
+		return "the file has no video streams"
+	}
+	list := make([]string, len(m.videoStreams))
+	for i, s := range m.videoStreams {
+		list[i] = fmt.Sprintf("%d: %s %dx%d", i, s.CodecName, s.Width, s.Height)
+		if s.Disposition.AttachedPic != 0 {
+			list[i] += " (cover art)"
+		}
+	}
+	return "available: " + strings.Join(list, ", ")
+}
+
+// Returns the first real video stream, or the one with the fewest pixels
+// when smallest is set, falling back to cover art when the file has
+// nothing else (audio files with embedded artwork)
+func selectVideoStream(streams []probeStream, smallest bool) *probeStream {
+	var art, best *probeStream
+	for i := range streams {
+		s := &streams[i]
+		// Bitmap subtitles have a size too
+		if s.CodecType != "video" || s.Width <= 0 || s.Height <= 0 {
+			continue
+		}
+		if s.Disposition.AttachedPic != 0 {
+			if art == nil {
+				art = s
+			}
+			continue
+		}
+		if !smallest {
+			return s
+		}
+		if best == nil || s.Width*s.Height < best.Width*best.Height {
+			best = s
+		}
+	}
+	if best != nil {
+		return best
+	}
+	return art
+}
+
+// Returns the container duration, or 0 if unknown. Used to re-check files
+// that are still being written.
+func ProbeDuration(ctx context.Context, path string) time.Duration {
+	input, err := InputArg(path)
+	if err != nil {
+		return 0
+	}
+	var meta Metadata
+	probeDuration(ctx, input, &meta)
+	return meta.Duration
+}
+
 func probeDuration(ctx context.Context, path string, meta *Metadata) {
-	cmd := exec.CommandContext(ctx, "ffprobe",
+	cmd := newInputCommand(ctx, "ffprobe",
 		"-v", "error",
 		"-show_entries", "format=duration",
 		"-of", "default=noprint_wrappers=1:nokey=1",
@@ -109,9 +777,37 @@ func probeDuration(ctx context.Context, path string, meta *Metadata) {
 		return
 	}
 
-	if dur, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64); err == nil && dur > 0 {
-		meta.Duration = time.Duration(dur * float64(time.Second))
+	if dur := parseSeconds(strings.TrimSpace(string(out))); dur > 0 {
+		meta.Duration = dur
+	}
+}
+
+// Applies a sample aspect ratio like "64:45" to the storage size. Where
+// the sample aspect is missing, unknown ("0:1") or malformed, a display
+// aspect ratio like "16:9" still tells the shape; without either the
+// pixels are square.
+func displaySize(width, height int, sar, dar string) (int, int) {
+	if n, d, ok := parseRatio(sar); ok {
+		return int(float64(width)*float64(n)/float64(d) + 0.5), height
+	}
+	if n, d, ok := parseRatio(dar); ok && height > 0 {
+		return int(float64(height)*float64(n)/float64(d) + 0.5), height
+	}
+	return width, height
+}
+
+// Parses a ratio like "16:9" with both sides positive
+func parseRatio(s string) (num, den int, ok bool) {
+	a, b, found := strings.Cut(s, ":")
+	if !found {
+		return 0, 0, false
+	}
+	num, err1 := strconv.Atoi(a)
+	den, err2 := strconv.Atoi(b)
+	if err1 != nil || err2 != nil || num <= 0 || den <= 0 {
+		return 0, 0, false
 	}
+	return num, den, true
 }
 
 func parseFPS(s string) float64 {
@@ -0,0 +1,613 @@
+package video
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/fakeff"
+)
+
+func TestParseFPS(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"25/1", 25},
+		{"24", 24},
+		{" 60/1\n", 60},
+		// Degenerate rates seen in WebM and MKV files
+		{"0/0", 0},
+		{"1/0", 0},
+		{"0/1", 0},
+		{"1000/1", 1000},
+		{"90000/1", 90000},
+		{"/1", 0},
+		{"abc/def", 0},
+		// NTSC rates stay fractional
+		{"30000/1001", 30000.0 / 1001},
+		{"24000/1001", 24000.0 / 1001},
+		{"60000/1001", 60000.0 / 1001},
+		{"N/A", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := parseFPS(tt.in); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("parseFPS(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestChooseFPS(t *testing.T) {
+	tests := []struct {
+		name     string
+		rates    frameRates
+		duration time.Duration
+		want     float64
+	}{
+		{"r", frameRates{real: 25, avg: 25}, 0, 25},
+		{"r close to avg", frameRates{real: 30, avg: 29.5}, 0, 30},
+		{"r 0/0", frameRates{real: 0, avg: 24}, 0, 24},
+		{"r 1000/1", frameRates{real: 1000, avg: 30}, 0, 30},
+		{"r far from avg", frameRates{real: 60, avg: 20}, 0, 20},
+		{"r without avg", frameRates{real: 50}, 0, 50},
+		{"frame count", frameRates{nbFrames: 300}, 10 * time.Second, 30},
+		{"frame count degenerate rates", frameRates{real: 1000, avg: 0.1, nbFrames: 250}, 10 * time.Second, 25},
+		{"frame count without duration", frameRates{nbFrames: 300}, 0, defaultFPS},
+		{"derived rate out of range", frameRates{nbFrames: 5000}, time.Second, defaultFPS},
+		{"r clamped high", frameRates{real: 1000}, 0, maxFPS},
+		{"r clamped low", frameRates{real: 0.2}, 0, minFPS},
+		{"nothing", frameRates{}, 0, defaultFPS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.rates.choose(tt.duration)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("choose = %v, want %v", got, tt.want)
+			}
+			if got < minFPS || got > maxFPS {
+				t.Errorf("choose = %v, outside %d-%d", got, minFPS, maxFPS)
+			}
+		})
+	}
+}
+
+// Animated image timing comes from the packet delays: the shortest sets the
+// rate, so no frame is lost to the fps filter, and together they set the
+// duration
+func TestProbeAnimationDelays(t *testing.T) {
+	fixture, _ := filepath.Abs(filepath.Join("testdata", "probe", "webp.json"))
+	path := filepath.Join(t.TempDir(), "sticker.webp")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		packets  string
+		animated bool
+		fps      float64
+		duration time.Duration
+	}{
+		{"variable delays", "0.050000,\n0.050000,\n0.200000,\n0.200000,\n0.500000,\n", true, 20, time.Second},
+		{"GIF-style 10ms delays", "0.010000,\n0.010000,\n0.010000,\n", true, maxAnimatedFPS, 30 * time.Millisecond},
+		{"unknown delays skipped", "N/A,\n0.100000,\n0.100000,\n", true, 10, 200 * time.Millisecond},
+		{"single frame", "0.040000,\n", false, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packets := filepath.Join(t.TempDir(), "packets.csv")
+			if err := os.WriteFile(packets, []byte(tt.packets), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			useFakeTools(t, map[string]string{"PROBE": fixture, "PACKETS": packets})
+			meta, err := ProbeContext(context.Background(), path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta.Animated != tt.animated || meta.Still == tt.animated {
+				t.Fatalf("animated %v, still %v; want animated %v", meta.Animated, meta.Still, tt.animated)
+			}
+			if !tt.animated {
+				return
+			}
+			if math.Abs(meta.FPS-tt.fps) > 1e-9 || meta.Duration != tt.duration {
+				t.Errorf("FPS %v, duration %v; want %v and %v", meta.FPS, meta.Duration, tt.fps, tt.duration)
+			}
+		})
+	}
+}
+
+// Returns the real ffmpeg, skipping the test when it or ffprobe is missing
+func realFFmpeg(t *testing.T) string {
+	t.Helper()
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+	if _, err := exec.LookPath("ffprobe"); err != nil {
+		t.Skip("ffprobe not installed")
+	}
+	return ffmpeg
+}
+
+// Encodes frames of a 10fps test pattern with ffmpeg, skipping the test
+// when this build lacks the encoder
+func generateAnimation(t *testing.T, ffmpeg, name string, frames int, args ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	gen := exec.Command(ffmpeg, "-v", "error", "-f", "lavfi", "-i", "testsrc=size=32x24:rate=10",
+		"-frames:v", strconv.Itoa(frames))
+	gen.Args = append(append(gen.Args, args...), path)
+	if out, err := gen.CombinedOutput(); err != nil {
+		t.Skipf("generating %s failed: %v: %s", name, err, out)
+	}
+	return path
+}
+
+func TestProbeAnimated(t *testing.T) {
+	ffmpeg := realFFmpeg(t)
+	formats := []struct {
+		name  string
+		codec string
+		args  []string
+	}{
+		{"clip.apng", "apng", []string{"-f", "apng", "-plays", "0"}},
+		{"clip.webp", "webp", []string{"-c:v", "libwebp", "-loop", "0"}},
+	}
+	for _, f := range formats {
+		t.Run(f.codec, func(t *testing.T) {
+			path := generateAnimation(t, ffmpeg, f.name, 10, f.args...)
+			meta, err := ProbeContext(context.Background(), path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta.Codec != f.codec || !meta.Animated || meta.Still {
+				t.Fatalf("codec %q, animated %v, still %v; want an animated %s", meta.Codec, meta.Animated, meta.Still, f.codec)
+			}
+			if math.Abs(meta.FPS-10) > 0.5 {
+				t.Errorf("FPS = %v, want 10", meta.FPS)
+			}
+			if d := meta.Duration - time.Second; d < -150*time.Millisecond || d > 150*time.Millisecond {
+				t.Errorf("Duration = %v, want about 1s", meta.Duration)
+			}
+
+			// Decoded at the native rate every frame comes through; the fps
+			// filter may round the last one either way
+			config := StreamConfig{Width: 32, Height: 24, TargetFPS: meta.FPS, StreamIndex: meta.StreamIndex}
+			buffer := NewFrameBuffer()
+			s, err := StartStream(context.Background(), path, config, buffer.Epoch(), Logs{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.ReadFrames(buffer, Logs{})
+			if n := buffer.FrameCount() + buffer.DroppedFrames(); n < 9 || n > 11 {
+				t.Errorf("decoded %d frames, want 10", n)
+			}
+		})
+	}
+
+	// A single frame is a still picture, not a one-frame animation
+	t.Run("single frame", func(t *testing.T) {
+		path := generateAnimation(t, ffmpeg, "still.apng", 1, "-f", "apng")
+		meta, err := ProbeContext(context.Background(), path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if meta.Animated || !meta.Still {
+			t.Errorf("animated %v, still %v; want a still", meta.Animated, meta.Still)
+		}
+	})
+}
+
+func TestDisplaySize(t *testing.T) {
+	tests := []struct {
+		name     string
+		w, h     int
+		sar, dar string
+		wantW    int
+	}{
+		{"PAL 16:9", 720, 576, "64:45", "16:9", 1024},
+		{"PAL 4:3", 720, 576, "16:15", "4:3", 768},
+		{"NTSC 16:9", 720, 480, "32:27", "16:9", 853},
+		{"NTSC 4:3", 720, 480, "8:9", "4:3", 640},
+		{"HDV", 1440, 1080, "4:3", "16:9", 1920},
+		{"anamorphic 2.39", 1920, 1080, "40:33", "64:27", 2327},
+		{"square", 1920, 1080, "1:1", "16:9", 1920},
+		// Without a usable sample aspect the display aspect decides
+		{"unknown SAR", 720, 576, "0:1", "16:9", 1024},
+		{"SAR only missing", 720, 576, "", "4:3", 768},
+		{"neither", 720, 576, "0:1", "0:1", 720},
+		{"malformed", 720, 576, "wide", "16/9", 720},
+		{"nothing", 640, 360, "", "", 640},
+	}
+	for _, tt := range tests {
+		w, h := displaySize(tt.w, tt.h, tt.sar, tt.dar)
+		if w != tt.wantW || h != tt.h {
+			t.Errorf("%s: displaySize(%d, %d, %q, %q) = %dx%d, want %dx%d", tt.name, tt.w, tt.h, tt.sar, tt.dar, w, h, tt.wantW, tt.h)
+		}
+	}
+}
+
+func TestProbeFixtures(t *testing.T) {
+	type want struct {
+		format            string
+		codec             string
+		w, h              int
+		fps               float64
+		duration          time.Duration
+		estimated         bool
+		pixFmt, profile   string
+		level             int
+		bitRate           int64
+		audioCodec        string
+		channels, streams int
+	}
+	tests := []struct {
+		fixture string
+		want    want
+	}{
+		{"mp4.json", want{"mov,mp4,m4a,3gp,3g2,mj2", "h264", 1920, 1080, 30000.0 / 1001, 12012 * time.Millisecond, false,
+			"yuv420p", "High", 40, 4000000, "aac", 2, 2}},
+		// No stream bit rate, so the container's
+		{"mkv.json", want{"matroska,webm", "hevc", 1280, 720, 24000.0 / 1001, 1425120 * time.Millisecond, false,
+			"yuv420p10le", "Main 10", 93, 2450000, "ac3", 6, 4}},
+		// r_frame_rate 1000/1 is the timebase, not a rate; the average is
+		// used instead. Level -99 means unknown.
+		{"webm.json", want{"matroska,webm", "vp9", 1280, 720, 30, 60033 * time.Millisecond, false,
+			"yuv420p", "Profile 0", 0, 1850000, "opus", 2, 2}},
+		// r_frame_rate 0/0 falls back to the average too
+		{"vfr.json", want{"mov,mp4,m4a,3gp,3g2,mj2", "h264", 1280, 720, 24000.0 / 1001, 30030 * time.Millisecond, false,
+			"yuv420p", "Main", 31, 2500000, "", 0, 1}},
+		// Neither rate usable: nb_frames over the duration
+		{"screenrec.json", want{"mov,mp4,m4a,3gp,3g2,mj2", "h264", 2560, 1440, 30, 20 * time.Second, false,
+			"yuv444p", "High 4:4:4 Predictive", 51, 0, "", 0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			meta, err := probeFixture(t, tt.fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := want{meta.Format, meta.Codec, meta.Width, meta.Height, meta.FPS, meta.Duration, meta.DurationEstimated,
+				meta.PixelFormat, meta.Profile, meta.Level, meta.BitRate, meta.AudioCodec, meta.Channels, len(meta.Streams)}
+			if math.Abs(got.fps-tt.want.fps) < 1e-9 {
+				got.fps = tt.want.fps
+			}
+			if got != tt.want {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// A file ffprobe can't parse fails with its complaint attached, not as a
+// file without video
+func TestProbeCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clip.mp4")
+	if err := os.WriteFile(path, []byte("not a movie"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, _ := filepath.Abs(filepath.Join("testdata", "probe", "corrupt.json"))
+	stderr, _ := filepath.Abs(filepath.Join("testdata", "probe", "corrupt.stderr"))
+	useFakeTools(t, map[string]string{"STDOUT": stdout, "STDERR": stderr, "EXIT": "1"})
+
+	_, err := ProbeContext(context.Background(), path)
+	var perr *ProbeError
+	if !errors.As(err, &perr) {
+		t.Fatalf("error %v, want a ProbeError", err)
+	}
+	if errors.Is(err, ErrNoVideoStream) || perr.Timeout != 0 {
+		t.Errorf("error %v reads as no video or a timeout", err)
+	}
+	if !strings.Contains(perr.Stderr, "Invalid data found when processing input") {
+		t.Errorf("Stderr = %q, want ffprobe's complaint", perr.Stderr)
+	}
+}
+
+// Audio files probe like any other; only the callers needing frames turn
+// them down
+func TestProbeAudioOnly(t *testing.T) {
+	tests := []struct {
+		fixture   string
+		valid     bool
+		onlyAudio bool
+		cover     bool
+	}{
+		{"audio.json", false, true, false},
+		// The cover is shown as a still, but it is still music
+		{"audio_cover.json", true, true, true},
+		{"mp4.json", true, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			meta, err := probeFixture(t, tt.fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta.IsValid() != tt.valid || meta.HasOnlyAudio() != tt.onlyAudio || (meta.CoverArt != nil) != tt.cover {
+				t.Errorf("valid %v, only audio %v, cover %v; want %v, %v, %v",
+					meta.IsValid(), meta.HasOnlyAudio(), meta.CoverArt != nil, tt.valid, tt.onlyAudio, tt.cover)
+			}
+			if err := meta.RequireVideo(); (err == nil) != tt.valid || err != nil && !errors.Is(err, ErrNoVideoStream) {
+				t.Errorf("RequireVideo = %v", err)
+			}
+			if !meta.HasAudio || meta.SampleRate == 0 || meta.Channels != 2 {
+				t.Errorf("audio %v at %d Hz, %d channels; want stereo", meta.HasAudio, meta.SampleRate, meta.Channels)
+			}
+		})
+	}
+
+	meta, _ := probeFixture(t, "audio.json")
+	if meta.AudioCodec != "mp3" || meta.SampleRate != 44100 || meta.Duration.Round(time.Millisecond) != 215327*time.Millisecond {
+		t.Errorf("codec %q at %d Hz for %v", meta.AudioCodec, meta.SampleRate, meta.Duration)
+	}
+
+	// Without sound or picture there is nothing to describe
+	if _, err := probeFixture(t, "data.json"); !errors.Is(err, ErrNoVideoStream) {
+		t.Errorf("data only: error %v, want ErrNoVideoStream", err)
+	}
+}
+
+// Output ffprobe gives for files it can't time or describe
+func TestParseProbeOutputDegenerate(t *testing.T) {
+	for _, out := range []string{"", "\n", "N/A"} {
+		var meta Metadata
+		if err := parseProbeOutput([]byte(out), &meta, &frameRates{}); err == nil {
+			t.Errorf("parseProbeOutput(%q) succeeded", out)
+		}
+	}
+
+	// N/A everywhere a number goes leaves them unknown, not garbage
+	out := `{"streams": [{"index": 0, "codec_type": "video", "codec_name": "h264", "width": 640, "height": 360,
+		"r_frame_rate": "0/0", "avg_frame_rate": "0/0", "duration": "N/A", "nb_frames": "N/A", "bit_rate": "N/A"}],
+		"format": {"format_name": "mpegts", "duration": "N/A", "bit_rate": "N/A"}}`
+	var meta Metadata
+	rates := &frameRates{}
+	if err := parseProbeOutput([]byte(out), &meta, rates); err != nil {
+		t.Fatal(err)
+	}
+	if meta.Duration != 0 || meta.DurationEstimated || meta.BitRate != 0 || meta.Frames != 0 {
+		t.Errorf("duration %v (estimated %v), bit rate %d, frames %d; want all unknown",
+			meta.Duration, meta.DurationEstimated, meta.BitRate, meta.Frames)
+	}
+	if fps := rates.choose(meta.Duration); fps != defaultFPS {
+		t.Errorf("FPS = %v, want the default %d", fps, defaultFPS)
+	}
+}
+
+func TestProbeDuration(t *testing.T) {
+	tests := []struct {
+		out  string
+		want time.Duration
+	}{
+		{"12.012000\n", 12012 * time.Millisecond},
+		{"N/A\n", 0},
+		{"", 0},
+		{"0.000000\n", 0},
+	}
+	path := filepath.Join(t.TempDir(), "growing.mp4")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		out := filepath.Join(t.TempDir(), "duration.txt")
+		if err := os.WriteFile(out, []byte(tt.out), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		useFakeTools(t, map[string]string{"PROBE": out})
+		if got := ProbeDuration(context.Background(), path); got != tt.want {
+			t.Errorf("ffprobe printing %q: ProbeDuration = %v, want %v", tt.out, got, tt.want)
+		}
+	}
+}
+
+// Cancelling a probe stuck in ffprobe, as on a dead network mount, kills
+// and reaps it instead of leaving it running or a zombie
+func TestProbeCancelReapsChild(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clip.mp4")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Hangs ignoring SIGINT and SIGTERM, so only the kill ends it
+	useFakeTools(t, map[string]string{"HANG": "1"})
+	before := fakeff.ChildProcesses(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := ProbeContext(ctx, path)
+		done <- err
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(fakeff.ChildProcesses(t)) == len(before) {
+		if time.Now().After(deadline) {
+			t.Fatal("ffprobe never started")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("probe returned %v before the cancel", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("error %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("probe still running after cancel")
+	}
+	if after := fakeff.ChildProcesses(t); !slices.Equal(after, before) {
+		t.Errorf("children %v, want %v", after, before)
+	}
+}
+
+// Each level of the duration fallback chain, from canned ffprobe output
+func TestProbeDurationFallback(t *testing.T) {
+	tests := []struct {
+		fixture   string
+		duration  time.Duration
+		estimated bool
+	}{
+		{"vfr.json", 30030 * time.Millisecond, false},   // format duration
+		{"fragmented.json", 10 * time.Second, true},     // video stream duration
+		{"nbframes.json", 10 * time.Second, true},       // nb_frames / fps
+		{"noduration.json", 0, false},                   // unknown
+		{"mkv.json", 1425120 * time.Millisecond, false}, // format only, streams have none
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			meta, err := probeFixture(t, tt.fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta.Duration != tt.duration || meta.DurationEstimated != tt.estimated {
+				t.Errorf("duration %v (estimated %v), want %v (estimated %v)",
+					meta.Duration, meta.DurationEstimated, tt.duration, tt.estimated)
+			}
+			// A local file of unknown length is still no live stream
+			if meta.Live {
+				t.Error("local file reported live")
+			}
+		})
+	}
+}
+
+// Cover art never stands in for real video, and HLS master playlists get
+// their smallest rendition; the decode maps exactly the chosen stream
+func TestProbeStreamSelection(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		index       int
+		w, h        int
+		attachedPic bool
+		cover       int // CoverArt.Index, -1 for none
+	}{
+		{"mp4.json", 0, 1920, 1080, false, -1},
+		// The poster comes first, the bitmap subtitles have a size too
+		{"mkv_poster.json", 1, 1920, 800, false, 0},
+		// Nothing but the cover to show
+		{"audio_cover.json", 1, 600, 600, true, 1},
+		{"hls.json", 4, 640, 360, false, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			meta, err := probeFixture(t, tt.fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta.StreamIndex != tt.index || meta.Width != tt.w || meta.Height != tt.h || meta.AttachedPic != tt.attachedPic {
+				t.Errorf("stream %d, %dx%d, attached %v; want %d, %dx%d, %v",
+					meta.StreamIndex, meta.Width, meta.Height, meta.AttachedPic, tt.index, tt.w, tt.h, tt.attachedPic)
+			}
+			cover := -1
+			if meta.CoverArt != nil {
+				cover = meta.CoverArt.Index
+			}
+			if cover != tt.cover {
+				t.Errorf("cover art stream %d, want %d", cover, tt.cover)
+			}
+
+			config := StreamConfig{TargetFPS: 25, Threads: 1, StreamIndex: meta.StreamIndex}
+			args := buildFFmpegArgs("file:/clip", 64, 36, config)
+			if i := slices.Index(args, "-map"); i < 0 || args[i+1] != meta.MapArg() || meta.MapArg() != "0:"+strconv.Itoa(tt.index) {
+				t.Errorf("ffmpeg args %q don't map stream %d", args, tt.index)
+			}
+		})
+	}
+}
+
+func TestSelectVideoStream(t *testing.T) {
+	video := func(index, w, h int, art bool) probeStream {
+		s := probeStream{Index: index, CodecType: "video", Width: w, Height: h}
+		if art {
+			s.Disposition.AttachedPic = 1
+		}
+		return s
+	}
+	subtitle := probeStream{Index: 9, CodecType: "subtitle", Width: 720, Height: 480}
+	tests := []struct {
+		name     string
+		streams  []probeStream
+		smallest bool
+		want     int // chosen index, -1 for none
+	}{
+		{"none", nil, false, -1},
+		{"only subtitles", []probeStream{subtitle}, false, -1},
+		{"first video", []probeStream{video(0, 1920, 1080, false), video(1, 640, 360, false)}, false, 0},
+		{"zero size skipped", []probeStream{video(0, 0, 0, false), video(1, 640, 360, false)}, false, 1},
+		{"art skipped", []probeStream{video(0, 500, 500, true), video(1, 1280, 720, false)}, false, 1},
+		{"art as fallback", []probeStream{subtitle, video(2, 500, 500, true), video(3, 300, 300, true)}, false, 2},
+		{"smallest variant", []probeStream{video(0, 1920, 1080, false), video(2, 640, 360, false), video(4, 1280, 720, false)}, true, 2},
+		{"smallest skips art", []probeStream{video(0, 100, 100, true), video(1, 1280, 720, false)}, true, 1},
+	}
+	for _, tt := range tests {
+		got := -1
+		if s := selectVideoStream(tt.streams, tt.smallest); s != nil {
+			got = s.Index
+		}
+		if got != tt.want {
+			t.Errorf("%s: chose stream %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// ffprobe builds without -show_entries or JSON output are probed again
+// with the default key=value writer, which gives the same metadata
+func TestProbeOldFFprobe(t *testing.T) {
+	want, err := probeFixture(t, "mp4.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "clip.mp4")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	flat, _ := filepath.Abs(filepath.Join("testdata", "probe", "mp4.flat"))
+	argsFile := filepath.Join(dir, "args")
+	useFakeTools(t, map[string]string{"FLAT": flat, "ARGS": argsFile})
+	got, err := ProbeContext(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got.Path = want.Path
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("from key=value output:\n%+v\nfrom JSON:\n%+v", got, want)
+	}
+	runs := fakeff.Args(t, argsFile)
+	if len(runs) < 2 || !slices.Contains(runs[1], "-show_streams") || slices.Contains(runs[1], "-of") {
+		t.Errorf("ffprobe runs %q, want a -show_streams run without -of after the failed one", runs)
+	}
+}
+
+func TestFlatProbeJSON(t *testing.T) {
+	out := "[STREAM]\nindex=0\ncodec_type=video\nwidth=720\nheight=N/A\nlevel=-99\nDISPOSITION:attached_pic=1\nTAG:rotate=90\n[/STREAM]\n" +
+		"[FORMAT]\nduration=N/A\nformat_name=matroska,webm\n[/FORMAT]\n"
+	data, err := flatProbeJSON([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"streams":[{"codec_type":"video","disposition":{"attached_pic":1},"index":0,"level":-99,"tags":{"rotate":"90"},"width":720}],"format":{"format_name":"matroska,webm"}}`
+	if string(data) != want {
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+
+	if _, err := flatProbeJSON([]byte("Unrecognized option 'show_streams'.\n")); err == nil {
+		t.Error("output without sections converted")
+	}
+}
@@ -0,0 +1,156 @@
+package video
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// Binaries and extra options for every ffmpeg/ffprobe run, set once at
+// startup
+type Tools struct {
+	// Paths of the binaries; empty means the default lookup
+	FFmpeg  string
+	FFprobe string
+
+	// Global options such as -probesize or -hwaccel_device. ffmpeg gets
+	// them before -i, ffprobe before its options.
+	FFmpegArgs  []string
+	FFprobeArgs []string
+
+	// yt-dlp binary for web video pages; empty means the default lookup,
+	// "none" disables it
+	YTDLP string
+
+	// Request headers ("Key: Value") and cookies (Set-Cookie values, one
+	// per line, see ReadCookies) for http(s) inputs; CookieFile is the file
+	// they came from, handed to yt-dlp. The headers and cookies without a
+	// domain are only sent to HTTPHosts, the hosts of the URLs the user
+	// gave (see URLHosts).
+	HTTPHeaders []string
+	Cookies     string
+	CookieFile  string
+	HTTPHosts   []string
+}
+
+var (
+	toolsMu sync.RWMutex
+	tools   Tools
+)
+
+// Sets the binaries and options used from now on
+func SetTools(t Tools) {
+	toolsMu.Lock()
+	defer toolsMu.Unlock()
+	tools = t
+}
+
+func currentTools() Tools {
+	toolsMu.RLock()
+	defer toolsMu.RUnlock()
+	return tools
+}
+
+// An ffmpeg tool that is missing or doesn't run, naming the binary that was
+// tried. Matches ErrFFmpegNotFound with errors.Is.
+type ToolError struct {
+	Name string
+	Path string
+	Err  error
+}
+
+func (e *ToolError) Error() string {
+	if errors.Is(e.Err, exec.ErrNotFound) {
+		if e.Path == e.Name || e.Path == e.Name+".exe" {
+			return fmt.Sprintf("%s not found on PATH or next to pixlgo", e.Name)
+		}
+		return fmt.Sprintf("%s not found at %s", e.Name, e.Path)
+	}
+	return fmt.Sprintf("%s at %s doesn't run: %v", e.Name, e.Path, e.Err)
+}
+
+func (e *ToolError) Unwrap() []error {
+	return []error{ErrFFmpegNotFound, e.Err}
+}
+
+// Resolves an ffmpeg tool: a path set with SetTools, then a copy next to
+// the pixlgo binary, then PATH
+func toolPath(name string) string {
+	t := currentTools()
+	switch {
+	case name == "ffmpeg" && t.FFmpeg != "":
+		return t.FFmpeg
+	case name == "ffprobe" && t.FFprobe != "":
+		return t.FFprobe
+	case name == "yt-dlp" && t.YTDLP != "" && t.YTDLP != "none":
+		return t.YTDLP
+	}
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	if exe, err := os.Executable(); err == nil {
+		local := filepath.Join(filepath.Dir(exe), name)
+		if info, err := os.Stat(local); err == nil && !info.IsDir() {
+			return local
+		}
+	}
+	if path, err := exec.LookPath(name); err == nil {
+		return path
+	}
+	return name
+}
+
+// Reports whether an ffmpeg tool can be found
+func toolAvailable(name string) bool {
+	_, err := exec.LookPath(toolPath(name))
+	return err == nil
+}
+
+// Returns a ToolError for a tool that can't be found
+func toolMissing(name string) error {
+	return &ToolError{Name: name, Path: toolPath(name), Err: exec.ErrNotFound}
+}
+
+// Checks that a tool exists and runs, returning the path used
+func CheckTool(ctx context.Context, name string) (string, error) {
+	path := toolPath(name)
+	if _, err := exec.LookPath(path); err != nil {
+		return path, &ToolError{Name: name, Path: path, Err: exec.ErrNotFound}
+	}
+	if err := newCommand(ctx, name, "-hide_banner", "-version").Run(); err != nil {
+		return path, &ToolError{Name: name, Path: path, Err: err}
+	}
+	return path, nil
+}
+
+// Builds an ffmpeg/ffprobe command whose whole process tree is killed when
+// ctx is cancelled
+func newCommand(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, toolPath(name), args...)
+	setProcAttrs(cmd)
+	cmd.Cancel = func() error {
+		return killProcessTree(cmd.Process)
+	}
+	return cmd
+}
+
+// Like newCommand for runs that open an input, adding the global options
+// from SetTools: before the first -i for ffmpeg, in front for ffprobe
+func newInputCommand(ctx context.Context, name string, args ...string) *exec.Cmd {
+	t := currentTools()
+	switch name {
+	case "ffmpeg":
+		if i := slices.Index(args, "-i"); i >= 0 && len(t.FFmpegArgs) > 0 {
+			args = slices.Concat(args[:i], t.FFmpegArgs, args[i:])
+		}
+	case "ffprobe":
+		args = slices.Concat(t.FFprobeArgs, args)
+	}
+	return newCommand(ctx, name, args...)
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+
+package video
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Starts the child in its own process group so it can be killed as a tree
+func setProcAttrs(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// Kills the process group, falling back to the single process
+func killProcessTree(p *os.Process) error {
+	if p == nil {
+		return nil
+	}
+	if err := syscall.Kill(-p.Pid, syscall.SIGKILL); err != nil {
+		return p.Kill()
+	}
+	return nil
+}
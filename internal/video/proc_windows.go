@@ -0,0 +1,27 @@
+//go:build windows
+
+package video
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// Detaches the child from our console group so Ctrl-C reaches pixlgo first
+func setProcAttrs(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// Kills the process and its children with taskkill, falling back to Kill
+func killProcessTree(p *os.Process) error {
+	if p == nil {
+		return nil
+	}
+	kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(p.Pid))
+	if err := kill.Run(); err != nil {
+		return p.Kill()
+	}
+	return nil
+}
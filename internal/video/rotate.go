@@ -0,0 +1,63 @@
+package video
+
+import (
+	"math"
+	"strconv"
+)
+
+// Returns a rotation in degrees clockwise as 0, 90, 180 or 270, snapped to
+// the nearest quarter turn
+func normalizeRotation(degrees float64) int {
+	quarter := int(math.Round(degrees/90)) % 4
+	if quarter < 0 {
+		quarter += 4
+	}
+	return quarter * 90
+}
+
+// Returns the clockwise rotation a stream needs to display upright. The
+// display matrix side data counts counterclockwise and wins over the older
+// rotate tag, which counts clockwise.
+func streamRotation(tag string, sideData []probeSideData) int {
+	for _, sd := range sideData {
+		if sd.Rotation != nil {
+			return normalizeRotation(-*sd.Rotation)
+		}
+	}
+	if deg, err := strconv.ParseFloat(tag, 64); err == nil {
+		return normalizeRotation(deg)
+	}
+	return 0
+}
+
+// Input options for decoding a stream rotated by rotation degrees: ffmpeg's
+// own autorotation is turned off when rotateFilter does the turning, so it
+// isn't applied twice
+func rotateArgs(rotation int) []string {
+	if rotateFilter(rotation) == "" {
+		return nil
+	}
+	return []string{"-noautorotate"}
+}
+
+// Returns the filters turning frames rotated by rotation degrees clockwise
+// upright, or "" for none
+func rotateFilter(rotation int) string {
+	switch rotation {
+	case 90:
+		return "transpose=clock"
+	case 180:
+		return "hflip,vflip"
+	case 270:
+		return "transpose=cclock"
+	}
+	return ""
+}
+
+// Prefixes filter with the rotation filters, if any
+func withRotation(rotation int, filter string) string {
+	if rf := rotateFilter(rotation); rf != "" {
+		return rf + "," + filter
+	}
+	return filter
+}
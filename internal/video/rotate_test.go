@@ -0,0 +1,113 @@
+package video
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// Writes a copy of the iPhone fixture whose video stream carries rotation
+// as set, and returns its path
+func rotatedFixture(t *testing.T, set func(stream map[string]any)) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "probe", "iphone.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	stream := doc["streams"].([]any)[0].(map[string]any)
+	delete(stream, "side_data_list")
+	set(stream)
+	data, _ = json.Marshal(doc)
+	path := filepath.Join(t.TempDir(), "probe.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProbeRotation(t *testing.T) {
+	type rotationCase struct {
+		name     string
+		fixture  func(t *testing.T) string
+		rotation int
+		w, h     int
+	}
+	fixed := func(name string) func(*testing.T) string {
+		return func(*testing.T) string { return name }
+	}
+	edited := func(set func(stream map[string]any)) func(*testing.T) string {
+		return func(t *testing.T) string { return rotatedFixture(t, set) }
+	}
+	tests := []rotationCase{
+		{"iPhone display matrix", fixed("iphone.json"), 90, 1080, 1920},
+		{"Android rotate tag", fixed("android.json"), 90, 720, 1280},
+		{"display matrix wins over tag", edited(func(s map[string]any) {
+			s["tags"] = map[string]any{"rotate": "90"}
+			s["side_data_list"] = []any{map[string]any{"side_data_type": "Display Matrix", "rotation": 180}}
+		}), 180, 1920, 1080},
+		{"off a quarter turn", edited(func(s map[string]any) {
+			s["side_data_list"] = []any{map[string]any{"side_data_type": "Display Matrix", "rotation": 89.97}}
+		}), 270, 1080, 1920},
+	}
+	// The display matrix counts counterclockwise, the tag clockwise
+	for _, deg := range []int{0, 90, 180, 270} {
+		w, h := 1920, 1080
+		if deg%180 != 0 {
+			w, h = h, w
+		}
+		tests = append(tests,
+			rotationCase{"tag " + strconv.Itoa(deg), edited(func(s map[string]any) {
+				s["tags"] = map[string]any{"rotate": strconv.Itoa(deg)}
+			}), deg, w, h},
+			rotationCase{"display matrix " + strconv.Itoa(deg), edited(func(s map[string]any) {
+				s["side_data_list"] = []any{map[string]any{"side_data_type": "Display Matrix", "rotation": -deg}}
+			}), deg, w, h},
+		)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, err := probeFixture(t, tt.fixture(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta.Rotation != tt.rotation {
+				t.Errorf("Rotation = %d, want %d", meta.Rotation, tt.rotation)
+			}
+			if meta.DisplayWidth != tt.w || meta.DisplayHeight != tt.h {
+				t.Errorf("display size %dx%d, want %dx%d", meta.DisplayWidth, meta.DisplayHeight, tt.w, tt.h)
+			}
+		})
+	}
+}
+
+// The decode turns the frames itself, once: autorotation off before -i and
+// the turn ahead of every other filter
+func TestStreamRotationArgs(t *testing.T) {
+	tests := []struct {
+		rotation int
+		filter   string
+	}{
+		{0, "fps=30.00,scale=36:64"},
+		{90, "transpose=clock,fps=30.00,scale=36:64"},
+		{180, "hflip,vflip,fps=30.00,scale=36:64"},
+		{270, "transpose=cclock,fps=30.00,scale=36:64"},
+	}
+	for _, tt := range tests {
+		config := StreamConfig{TargetFPS: 30, Threads: 1, Rotation: tt.rotation}
+		args := buildFFmpegArgs("file:/clip.mp4", 36, 64, config)
+		input := slices.Index(args, "-i")
+		if got := slices.Contains(args[:input], "-noautorotate"); got != (tt.rotation != 0) {
+			t.Errorf("rotation %d: -noautorotate before -i is %v", tt.rotation, got)
+		}
+		if vf := args[slices.Index(args, "-vf")+1]; vf != tt.filter {
+			t.Errorf("rotation %d: -vf %q, want %q", tt.rotation, vf, tt.filter)
+		}
+	}
+}
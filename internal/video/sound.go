@@ -0,0 +1,234 @@
+package video
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sample rate audio is played at
+const playbackRate = 48000
+
+// Largest volume SetVolume accepts, in percent
+const MaxVolume = 150
+
+// The first audio stream decoded to WAV by ffmpeg and played from stdin
+// by ffplay. Samples pass through pixlgo on the way, so volume changes
+// apply to the next block without restarting either process.
+type audioPlayer struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	clock  *audioClock
+}
+
+// How long the audio clock runs before it is trusted: ffplay reads ahead
+// in a burst at first, then only as fast as the device plays
+const clockWarmup = 2 * time.Second
+
+// Weight of each new reading in the audio clock, as 1/clockSmoothing
+const clockSmoothing = 64
+
+// Media time of the audio being heard, counted from samples handed to
+// ffplay. ffplay's read-ahead makes that count lead by a roughly constant
+// amount, measured once after clockWarmup and subtracted from then on, so
+// the clock follows the sound card's rate rather than the system clock.
+// ffplay reads the pipe in bursts, so readings are smoothed into an
+// estimate that otherwise advances with wall time.
+type audioClock struct {
+	mu      sync.Mutex
+	start   time.Duration
+	bps     int64 // data bytes per second of audio
+	written int64
+	began   time.Time
+	lead    time.Duration
+	warm    bool
+	est     time.Duration
+	estAt   time.Time
+}
+
+func newAudioClock(start time.Duration, channels int) *audioClock {
+	return &audioClock{start: start, bps: int64(playbackRate * channels * 2)}
+}
+
+// Records n more bytes handed to ffplay
+func (c *audioClock) add(n int) {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.began.IsZero() {
+		c.began = now
+	}
+	c.written += int64(n)
+	switch {
+	case c.warm:
+		predicted := c.est + now.Sub(c.estAt)
+		reading := c.start + c.fed() - c.lead
+		c.est, c.estAt = predicted+(reading-predicted)/clockSmoothing, now
+	case now.Sub(c.began) >= clockWarmup:
+		c.lead = c.fed() - now.Sub(c.began)
+		c.est, c.estAt = c.start+now.Sub(c.began), now
+		c.warm = true
+	}
+}
+
+// Returns the media time handed over so far. Caller holds c.mu.
+func (c *audioClock) fed() time.Duration {
+	return time.Duration(c.written * int64(time.Second) / c.bps)
+}
+
+// Returns the media time being heard, or false while warming up
+func (c *audioClock) now() (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.warm {
+		return 0, false
+	}
+	return c.est + time.Since(c.estAt), true
+}
+
+// Starts playing audio stream track from startPos with channels channels,
+// scaled by volume in percent
+func startAudioPlayer(ctx context.Context, path string, track int, startPos time.Duration, loop, channels int,
+	volume *atomic.Int32, logs Logs) (*audioPlayer, error) {
+	input, err := InputArg(path)
+	if err != nil {
+		return nil, err
+	}
+	var args []string
+	if startPos > 0 {
+		args = append(args, "-ss", fmt.Sprintf("%.3f", startPos.Seconds()))
+	}
+	args = append(args, inputOptions(input)...)
+	args = append(args, loopArgs(loop)...)
+	args = append(args,
+		"-i", input,
+		"-map", audioMap(track),
+		"-vn", "-sn",
+		"-ac", strconv.Itoa(channels),
+		"-ar", strconv.Itoa(playbackRate),
+		"-c:a", "pcm_s16le",
+		// No LIST chunk or encoder tag, just the format and the samples
+		"-fflags", "+bitexact",
+		"-map_metadata", "-1",
+		"-f", "wav",
+		"-loglevel", "error",
+		"-",
+	)
+
+	playCtx, cancel := context.WithCancel(ctx)
+	decode := newInputCommand(playCtx, "ffmpeg", args...)
+	pcm, err := decode.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("audio playback: %w", err)
+	}
+	// ffplay's output goes nowhere so its status line can't disturb the screen
+	play := newCommand(playCtx, "ffplay", "-nodisp", "-autoexit", "-loglevel", "error", "-f", "wav", "pipe:0")
+	out, err := play.StdinPipe()
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("audio playback: %w", err)
+	}
+	if err := decode.Start(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("audio playback: %w", err)
+	}
+	if err := play.Start(); err != nil {
+		cancel()
+		decode.Wait()
+		return nil, fmt.Errorf("audio playback: %w", err)
+	}
+
+	a := &audioPlayer{cancel: cancel, done: make(chan struct{}), clock: newAudioClock(startPos, channels)}
+	go func() {
+		defer close(a.done)
+		err := copyWithGain(out, pcm, volume, a.clock.add)
+		out.Close()
+		if err != nil {
+			if playCtx.Err() == nil {
+				logs.Debug("Audio playback stopped: %v", err)
+			}
+			// Don't leave ffmpeg blocked on a pipe nobody reads
+			cancel()
+		}
+		decode.Wait()
+		play.Wait()
+	}()
+	return a, nil
+}
+
+// Kills both processes and waits for them to exit
+func (a *audioPlayer) stop() {
+	a.cancel()
+	<-a.done
+}
+
+// Bytes scaled and forwarded at a time: about 20ms of 48kHz stereo
+const gainBlock = 4096
+
+// Copies a WAV stream from src to dst, scaling its 16-bit samples by the
+// current volume and reporting each block of samples written to played.
+// The header chunks pass through unchanged.
+func copyWithGain(dst io.Writer, src io.Reader, volume *atomic.Int32, played func(int)) error {
+	r := bufio.NewReader(src)
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return err
+	}
+	if _, err := dst.Write(riff[:]); err != nil {
+		return err
+	}
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return err
+		}
+		if _, err := dst.Write(chunk[:]); err != nil {
+			return err
+		}
+		if string(chunk[:4]) == "data" {
+			break
+		}
+		// Chunks are padded to an even size
+		size := int64(binary.LittleEndian.Uint32(chunk[4:]))
+		if _, err := io.CopyN(dst, r, size+size&1); err != nil {
+			return err
+		}
+	}
+
+	buf := make([]byte, gainBlock)
+	for {
+		n, err := io.ReadFull(r, buf)
+		n &^= 1
+		applyGain(buf[:n], volume.Load())
+		if _, werr := dst.Write(buf[:n]); werr != nil {
+			return werr
+		}
+		played(n)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Scales little-endian 16-bit samples in place, clipping at full scale
+func applyGain(samples []byte, percent int32) {
+	if percent == 100 {
+		return
+	}
+	for i := 0; i+1 < len(samples); i += 2 {
+		v := int32(int16(binary.LittleEndian.Uint16(samples[i:]))) * percent / 100
+		v = min(max(v, -32768), 32767)
+		binary.LittleEndian.PutUint16(samples[i:], uint16(int16(v)))
+	}
+}
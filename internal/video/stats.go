@@ -0,0 +1,12 @@
+package video
+
+import "sync/atomic"
+
+// Process-wide decode counters for metrics endpoints. Updated with atomic
+// adds on the decode path, so they cost next to nothing when unread.
+var Stats struct {
+	FramesDecoded atomic.Uint64 // frames read from ffmpeg
+	FramesDropped atomic.Uint64 // frames skipped for running late
+	Respawns      atomic.Uint64 // ffmpeg decode processes started
+	Errors        atomic.Uint64 // streams that failed to start or decode
+}
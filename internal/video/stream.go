@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"image"
 	"io"
+	"math"
 	"os/exec"
 	"runtime"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -18,6 +20,47 @@ type StreamConfig struct {
 	Height    int
 	StartPos  time.Duration
 	TargetFPS float64
+	Threads   int // ffmpeg decode threads, 0 means NumCPU
+
+	// Absolute index of the video stream to decode (Metadata.StreamIndex)
+	StreamIndex int
+
+	// Stops decoding after this much media time. Zero means to the end.
+	Duration time.Duration
+
+	// Metadata.ColorRange, so limited-range video is expanded to full
+	ColorRange string
+
+	// Metadata.Rotation, so rotated phone video comes out upright
+	Rotation int
+
+	// Hardware decoding method for -hwaccel (auto, vaapi, videotoolbox,
+	// cuda, ...) and optional -hwaccel_device. Empty or "none" decodes in
+	// software.
+	HWAccel       string
+	HWAccelDevice string
+
+	// Burns text subtitle track SubtitleTrack (counted among subtitle
+	// streams) into the frames; needs an ffmpeg built with libass
+	Subtitles     bool
+	SubtitleTrack int
+
+	// Metadata.Live: decoding joins the live edge instead of seeking to
+	// StartPos, which then only offsets the frame timestamps
+	Live bool
+
+	// Passes over the input after the first, as ffmpeg's -stream_loop; -1
+	// repeats forever. Frame timestamps wrap at LoopDuration, the length of
+	// one pass, when it is known.
+	Loop         int
+	LoopDuration time.Duration
+
+	// Start exactly at StartPos, as in seekArgs, instead of trusting the
+	// input seek
+	ExactSeek bool
+
+	// Data for a StdinPath input
+	stdin *pipeInput
 }
 
 // Calculates an appropriate FPS based on frame size
@@ -52,25 +95,79 @@ type Stream struct {
 	fps       float64
 	epoch     uint64
 	startPos  time.Duration
+	loopLen   time.Duration // wrap for timestamps of a looping input
+
+	mu       sync.Mutex
+	stopped  bool
+	position time.Duration
+	skipTo   time.Duration
+	skipped  uint64
+	ahead    time.Duration
+	done     chan struct{}
+	kill     chan struct{} // closed by Stop, ends a reap's grace period
+
+	// Audio clock to pace against instead of the system clock, and the
+	// sync state it produced
+	clock   func() (time.Duration, bool)
+	drift   time.Duration
+	resyncs int
+
+	reapOnce sync.Once
+	waitErr  error
+	reason   EndReason
+}
+
+// Why a stream's read loop finished
+type EndReason int
 
-	mu      sync.Mutex
-	stopped bool
-	done    chan struct{}
+const (
+	EndNone     EndReason = iota // still running
+	EndEOF                       // ffmpeg produced all frames and exited cleanly
+	EndStopped                   // Stop was called
+	EndReplaced                  // a newer epoch took over the buffer
+	EndEmpty                     // ffmpeg exited cleanly without any frames
+	EndError                     // ffmpeg failed
+)
+
+func (r EndReason) String() string {
+	switch r {
+	case EndNone:
+		return "running"
+	case EndEOF:
+		return "eof"
+	case EndStopped:
+		return "stopped"
+	case EndReplaced:
+		return "replaced"
+	case EndEmpty:
+		return "empty"
+	case EndError:
+		return "error"
+	default:
+		return "unknown"
+	}
 }
 
+// How long ffmpeg gets to exit on its own once its output ended, before
+// it is killed as hung
+const exitGrace = 2 * time.Second
+
 // Creates and starts a new decode stream
 func StartStream(ctx context.Context, path string, config StreamConfig,
-	epoch uint64, logFn func(string, ...any)) (*Stream, error) {
-	width := normalizeEven(config.Width, 4, 4096)
-	height := normalizeEven(config.Height, 4, 4096)
+	epoch uint64, logs Logs) (*Stream, error) {
+	logs = logs.withDefaults()
+	width, height := FitSize(config.Width, config.Height)
 
-	args := buildFFmpegArgs(path, width, height, config.StartPos, config.TargetFPS)
-	if logFn != nil {
-		logFn("[epoch=%d] FFmpeg args: %v", epoch, args)
+	input, err := InputArg(path)
+	if err != nil {
+		return nil, err
 	}
 
+	args := buildFFmpegArgs(input, width, height, config)
+	logs.Debug("[epoch=%d] FFmpeg args: %v", epoch, redactArgs(args))
+
 	cmdCtx, cancel := context.WithCancel(ctx)
-	cmd := exec.CommandContext(cmdCtx, "ffmpeg", args...)
+	cmd := newInputCommand(cmdCtx, "ffmpeg", args...)
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -85,17 +182,30 @@ func StartStream(ctx context.Context, path string, config StreamConfig,
 		return nil, fmt.Errorf("stderr pipe: %w", err)
 	}
 
+	var stdin io.WriteCloser
+	if config.stdin != nil {
+		if stdin, err = cmd.StdinPipe(); err != nil {
+			cancel()
+			stdout.Close()
+			stderr.Close()
+			return nil, fmt.Errorf("stdin pipe: %w", err)
+		}
+	}
+
 	if err := cmd.Start(); err != nil {
 		cancel()
 		stdout.Close()
 		stderr.Close()
+		Stats.Errors.Add(1)
 		return nil, fmt.Errorf("start: %w", err)
 	}
-
-	if logFn != nil {
-		logFn("[epoch=%d] FFmpeg started, PID=%d", epoch, cmd.Process.Pid)
+	Stats.Respawns.Add(1)
+	if stdin != nil {
+		go config.stdin.feed(stdin, false)
 	}
 
+	logs.Debug("[epoch=%d] FFmpeg started, PID=%d", epoch, cmd.Process.Pid)
+
 	return &Stream{
 		cmd:       cmd,
 		cancel:    cancel,
@@ -104,26 +214,53 @@ func StartStream(ctx context.Context, path string, config StreamConfig,
 		width:     width,
 		height:    height,
 		frameSize: width * height * 3,
-		fps:       config.TargetFPS,
+		fps:       filterFPS(config.TargetFPS),
 		epoch:     epoch,
 		startPos:  config.StartPos,
+		loopLen:   loopLength(config),
+		position:  config.StartPos,
 		done:      make(chan struct{}),
+		kill:      make(chan struct{}),
 	}, nil
 }
 
 // Builds arguments for FFmpeg
-func buildFFmpegArgs(path string, width, height int, startPos time.Duration, fps float64) []string {
+func buildFFmpegArgs(input string, width, height int, config StreamConfig) []string {
+	startPos, fps, threads := config.StartPos, config.TargetFPS, config.Threads
+	if config.Live {
+		startPos = 0
+	}
+	if threads <= 0 {
+		threads = runtime.NumCPU()
+	}
 	args := []string{
-		"-threads", fmt.Sprintf("%d", runtime.NumCPU()),
+		"-threads", fmt.Sprintf("%d", threads),
 	}
 
-	if startPos > 0 {
-		args = append(args, "-ss", fmt.Sprintf("%.3f", startPos.Seconds()))
+	args = append(args, hwaccelArgs(config.HWAccel, config.HWAccelDevice)...)
+	seekIn, seekOut, inputPos := seekArgs(startPos, config.ExactSeek)
+	args = append(args, seekIn...)
+
+	args = append(args, rotateArgs(config.Rotation)...)
+	args = append(args, inputOptions(input)...)
+	args = append(args, loopArgs(config.Loop)...)
+	args = append(args, "-i", input)
+	args = append(args, seekOut...)
+	if config.Duration > 0 {
+		args = append(args, "-t", fmt.Sprintf("%.3f", config.Duration.Seconds()))
 	}
 
+	filter := fmt.Sprintf("fps=%.2f,%s", filterFPS(fps), scaleFilter(width, height, config.ColorRange))
+	if config.Subtitles {
+		// Drawn at source resolution, before scaling, so text stays legible
+		filter = subtitleFilter(input, config.SubtitleTrack, inputPos) + "," + filter
+	}
+	// Turned first, so subtitles are drawn upright and the scale fits the
+	// display size
+	filter = withRotation(config.Rotation, filter)
 	args = append(args,
-		"-i", path,
-		"-vf", fmt.Sprintf("fps=%.2f,scale=%d:%d", fps, width, height),
+		"-map", fmt.Sprintf("0:%d", config.StreamIndex),
+		"-vf", filter,
 		"-pix_fmt", "rgb24",
 		"-f", "rawvideo",
 		"-an",
@@ -134,19 +271,111 @@ func buildFFmpegArgs(path string, width, height int, startPos time.Duration, fps
 	return args
 }
 
+// How far before the target an exact seek puts the input seek
+const exactSeekMargin = 2 * time.Second
+
+// Returns the -ss options that start decoding at pos, for before and after
+// -i, and the position the input seek goes to. The fast form only seeks
+// the input. The exact one seeks the input to exactSeekMargin before pos
+// and has ffmpeg decode and drop the rest, so the first frame is the one
+// at pos even where the input seek lands late, as in files with sparse
+// keyframe indexes.
+func seekArgs(pos time.Duration, exact bool) (before, after []string, inputPos time.Duration) {
+	if pos <= 0 {
+		return nil, nil, 0
+	}
+	inputPos = pos
+	if exact {
+		inputPos = max(pos-exactSeekMargin, 0)
+		after = []string{"-ss", fmt.Sprintf("%.3f", (pos - inputPos).Seconds())}
+	}
+	if inputPos > 0 {
+		before = []string{"-ss", fmt.Sprintf("%.3f", inputPos.Seconds())}
+	}
+	return before, after, inputPos
+}
+
+// Returns fps as the fps filter receives it, rounded to two decimals
+func filterFPS(fps float64) float64 {
+	return math.Round(fps*100) / 100
+}
+
+// Returns the scale filter for width x height. Limited-range (tv) video is
+// expanded explicitly so its black lands on 0 and white on 255 instead of
+// staying grey and dim; full-range and untagged sources pass through as is.
+func scaleFilter(width, height int, colorRange string) string {
+	filter := fmt.Sprintf("scale=%d:%d", width, height)
+	if colorRange == "tv" {
+		filter += ":in_range=limited:out_range=full"
+	}
+	return filter
+}
+
+// Returns the media time of the n-th frame read from the pipe. It is derived
+// from the count rather than accumulated, so neither rounding nor dropped
+// frames make the reported position drift from what was actually decoded.
+// A looping input starts over at 0 after each pass.
+func (s *Stream) frameTime(n int) time.Duration {
+	t := s.startPos + time.Duration(float64(n)*float64(time.Second)/s.fps)
+	if s.loopLen > 0 {
+		t %= s.loopLen
+	}
+	return t
+}
+
+// Returns the -stream_loop input option for loop extra passes
+func loopArgs(loop int) []string {
+	if loop == 0 {
+		return nil
+	}
+	return []string{"-stream_loop", strconv.Itoa(loop)}
+}
+
+// Returns the length timestamps wrap at, 0 for an input played once
+func loopLength(config StreamConfig) time.Duration {
+	if config.Loop == 0 {
+		return 0
+	}
+	return config.LoopDuration
+}
+
 // Reads frames from the stream and sends to buffer
-func (s *Stream) ReadFrames(buffer *FrameBuffer, logFn func(string, ...any)) {
+func (s *Stream) ReadFrames(buffer *FrameBuffer, logs Logs) {
+	logs = logs.withDefaults()
+	reason := EndEOF
+	received := 0
+	var readErr error
 	defer func() {
-		close(s.done)
-		s.stdout.Close()
-		s.cmd.Wait()
-		if logFn != nil {
-			logFn("[epoch=%d] Stream read loop exited", s.epoch)
+		// A process whose output is no longer wanted is killed; one that
+		// ended its output exits by itself, and its status tells a clean
+		// finish from a failure
+		s.reap(reason == EndStopped || reason == EndReplaced)
+
+		s.mu.Lock()
+		if s.stopped {
+			reason = EndStopped
 		}
+		if reason == EndEOF && s.waitErr != nil {
+			reason = EndError
+		} else if reason == EndEOF && received == 0 {
+			// Usually a start position past the last frame; the player decides
+			reason = EndEmpty
+		}
+		s.reason = reason
+		s.mu.Unlock()
+
+		if reason == EndError && received == 0 {
+			logs.Error("[epoch=%d] Decode failed before first frame: %v (exit: %v)", s.epoch, readErr, s.waitErr)
+			buffer.SetError(s.epoch, ErrDecodeFailed)
+			Stats.Errors.Add(1)
+		}
+		buffer.SetEnd(s.epoch, reason)
+		close(s.done)
+		logs.Debug("[epoch=%d] Stream read loop exited: %s", s.epoch, reason)
 	}()
 
 	// Start stderr reader
-	go s.drainStderr(logFn)
+	go s.drainStderr(logs.Debug)
 
 	frameDuration := time.Duration(float64(time.Second) / s.fps)
 
@@ -160,56 +389,103 @@ func (s *Stream) ReadFrames(buffer *FrameBuffer, logFn func(string, ...any)) {
 	frameIdx := 0
 
 	rgbBuf := make([]byte, s.frameSize)
-	currentTime := s.startPos
 	playbackStart := time.Now()
 	frameNum := 0
 
 	for {
-		// Check if stopped
+		// Check if stopped and publish position for SkipTo
 		s.mu.Lock()
 		stopped := s.stopped
+		skipTo := s.skipTo
+		s.position = s.frameTime(received)
 		s.mu.Unlock()
 		if stopped {
+			reason = EndStopped
 			return
 		}
 
 		// Check epoch before reading
 		if buffer.Epoch() != s.epoch {
+			reason = EndReplaced
 			return
 		}
 		_, err := io.ReadFull(reader, rgbBuf)
+		decoded := time.Now()
 		if err != nil {
-			if frameNum == 0 {
-				buffer.SetError(ErrDecodeFailed)
-			}
+			readErr = err
 			return
 		}
+		currentTime := s.frameTime(received)
+		received++
+		Stats.FramesDecoded.Add(1)
+		if received == 1 {
+			// Pace from the first frame, not from process start, so ffmpeg
+			// startup latency never makes the opening frames look late
+			playbackStart = decoded
+		}
+
+		// Fast-forward: discard frames unpaced until the skip target
+		if currentTime < skipTo {
+			s.mu.Lock()
+			s.skipped++
+			s.mu.Unlock()
+			continue
+		}
+		if skipTo > 0 {
+			s.mu.Lock()
+			if s.skipTo == skipTo {
+				s.skipTo = 0
+			}
+			s.mu.Unlock()
+			logs.Debug("[epoch=%d] Skip reached %v", s.epoch, currentTime)
+			playbackStart = time.Now()
+			frameNum = 0
+		}
 
 		// Timing check for frame dropping
 		expectedTime := playbackStart.Add(time.Duration(frameNum) * frameDuration)
 		now := time.Now()
 		lag := now.Sub(expectedTime)
 
+		// Follow the audio: a frame due later than the sound it belongs to
+		// shifts the schedule earlier (dropping to catch up), one due too
+		// soon shifts it later
+		if drift, ok := s.audioDrift(currentTime, lag); ok && (drift > syncThreshold || drift < -syncThreshold) {
+			playbackStart = playbackStart.Add(drift)
+			lag -= drift
+			s.mu.Lock()
+			s.resyncs++
+			s.mu.Unlock()
+			logs.Debug("[epoch=%d] A/V resync at %v: video %v ahead of audio", s.epoch, currentTime, drift)
+		}
+
 		if lag > frameDuration*5 {
 			buffer.AddDropped()
 			frameNum++
-			currentTime += frameDuration
 			continue
 		}
 
+		// Decoded media time ready beyond this frame: what sits unread in the
+		// pipe buffer plus how early this frame arrived for its slot
+		ahead := time.Duration(reader.Buffered()/s.frameSize)*frameDuration - lag
+		s.mu.Lock()
+		s.ahead = max(ahead, 0)
+		s.mu.Unlock()
+
 		// Convert RGB24 to RGBA
 		frame := frames[frameIdx]
 		frameIdx = 1 - frameIdx
 		convertRGB24ToRGBA(rgbBuf, frame.Image.Pix)
 		frame.Timestamp = currentTime
+		frame.Decoded = decoded
 
 		// Store with epoch check
 		if !buffer.Store(frame, s.epoch) {
+			reason = EndReplaced
 			return
 		}
 
 		frameNum++
-		currentTime += frameDuration
 
 		// Pace control
 		if lag < -5*time.Millisecond {
@@ -240,19 +516,146 @@ func (s *Stream) Stop(logFn func(string, ...any)) {
 		return
 	}
 	s.stopped = true
+	close(s.kill)
 	s.mu.Unlock()
-	if s.cancel != nil {
-		s.cancel()
-	}
-	if s.cmd != nil && s.cmd.Process != nil {
-		s.cmd.Process.Kill()
+	if logFn != nil {
+		logFn("[epoch=%d] Stopping FFmpeg", s.epoch)
 	}
 
-	// Wait for read loop to finish
+	// Killing ffmpeg and closing stdout unblocks the read loop, which then
+	// finds the process already reaped
+	s.reap(true)
+	<-s.done
+}
+
+// Closes stdout and waits for the process exactly once. kill ends it first;
+// otherwise it is only killed if it doesn't exit within exitGrace, or when
+// Stop is called meanwhile. The kill goes through cancel, whose cmd.Cancel
+// takes the whole tree.
+func (s *Stream) reap(kill bool) {
+	s.reapOnce.Do(func() {
+		s.stdout.Close()
+		if kill {
+			s.cancel()
+		}
+		exited := make(chan error, 1)
+		go func() { exited <- s.cmd.Wait() }()
+		select {
+		case s.waitErr = <-exited:
+		case <-time.After(exitGrace):
+			s.cancel()
+			s.waitErr = <-exited
+		case <-s.kill:
+			s.cancel()
+			s.waitErr = <-exited
+		}
+		s.cancel()
+	})
+}
+
+// Returns why the stream finished, or EndNone while it is running
+func (s *Stream) EndReason() EndReason {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reason
+}
+
+// Fast-forwards a running stream to target by discarding frames instead of
+// restarting ffmpeg. Returns false if the stream has stopped or the jump is
+// backwards or further than maxDelta from the current position.
+func (s *Stream) SkipTo(target, maxDelta time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped || s.loopLen > 0 {
+		// Timestamps of a looping stream wrap before a target near the end
+		return false
+	}
 	select {
 	case <-s.done:
-	case <-time.After(500 * time.Millisecond):
+		return false
+	default:
+	}
+
+	from := s.position
+	if s.skipTo > from {
+		from = s.skipTo
 	}
+	delta := target - from
+	if delta <= 0 || delta > maxDelta {
+		return false
+	}
+	s.skipTo = target
+	return true
+}
+
+// Returns the number of frames discarded by SkipTo
+func (s *Stream) SkippedFrames() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.skipped
+}
+
+// Drift beyond which the video schedule is moved to the audio clock
+const syncThreshold = 80 * time.Millisecond
+
+// Returns by how much frame t, due in -lag, would be shown ahead of the
+// audio clock, and false without a running clock
+func (s *Stream) audioDrift(t, lag time.Duration) (time.Duration, bool) {
+	s.mu.Lock()
+	clock := s.clock
+	s.mu.Unlock()
+	if clock == nil {
+		return 0, false
+	}
+	heard, ok := clock()
+	if !ok {
+		return 0, false
+	}
+	// The frame shows after -lag; the audio will be that much further on
+	drift := t - (heard - lag)
+	if s.loopLen > 0 {
+		// Either clock may have wrapped first; take the nearer distance
+		drift %= s.loopLen
+		if drift > s.loopLen/2 {
+			drift -= s.loopLen
+		} else if drift < -s.loopLen/2 {
+			drift += s.loopLen
+		}
+	}
+	s.mu.Lock()
+	s.drift = drift
+	s.mu.Unlock()
+	return drift, true
+}
+
+// Makes the stream pace frames against an audio clock
+func (s *Stream) SetClock(clock func() (time.Duration, bool)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = clock
+}
+
+// Audio/video sync state of a stream
+type SyncStats struct {
+	Synced  bool          // pacing follows an audio clock
+	Drift   time.Duration // video ahead of audio at the last frame; negative is behind
+	Resyncs int           // schedule corrections so far
+}
+
+// Returns the stream's audio/video sync state
+func (s *Stream) Stats() SyncStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SyncStats{Synced: s.clock != nil, Drift: s.drift, Resyncs: s.resyncs}
+}
+
+// Returns how much media time the stream had decoded ahead of the frame it
+// last delivered. Near zero means decoding or input can't keep up.
+func (s *Stream) Ahead() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ahead
 }
 
 // Returns a channel that's closed when the stream finishes
@@ -260,6 +663,11 @@ func (s *Stream) Done() <-chan struct{} {
 	return s.done
 }
 
+// Returns the frame size the stream decodes at, after FitSize
+func (s *Stream) Size() (int, int) {
+	return s.width, s.height
+}
+
 // Epoch returns the stream's epoch
 func (s *Stream) Epoch() uint64 {
 	return s.epoch
@@ -273,14 +681,3 @@ func convertRGB24ToRGBA(src, dst []byte) {
 		dst[j+3] = 255
 	}
 }
-
-func normalizeEven(v, min, max int) int {
-	v = (v / 2) * 2
-	if v < min {
-		return min
-	}
-	if v > max {
-		return max
-	}
-	return v
-}
@@ -0,0 +1,302 @@
+package video
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/fakeff"
+)
+
+// Returns a local input and a config for a small fake stream
+func fakeStreamInput(t *testing.T) (string, StreamConfig) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "clip.mp4")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path, StreamConfig{Width: 4, Height: 2, TargetFPS: 100}
+}
+
+// Runs a stream to its end and returns it and the buffer it filled
+func runStream(t *testing.T) (*Stream, *FrameBuffer) {
+	t.Helper()
+	path, config := fakeStreamInput(t)
+	buffer := NewFrameBuffer()
+	s, err := StartStream(context.Background(), path, config, buffer.Epoch(), Logs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.ReadFrames(buffer, Logs{})
+	return s, buffer
+}
+
+func TestStreamCleanExit(t *testing.T) {
+	useFakeTools(t, map[string]string{"FRAMES": "3"})
+	s, buffer := runStream(t)
+
+	// A finished ffmpeg is waited for, not killed, so its clean exit counts
+	if s.EndReason() != EndEOF || s.waitErr != nil {
+		t.Errorf("reason %s, exit %v; want eof and a clean exit", s.EndReason(), s.waitErr)
+	}
+	if buffer.FrameCount() != 3 || buffer.EndReason() != EndEOF {
+		t.Errorf("buffer has %d frames and reason %s", buffer.FrameCount(), buffer.EndReason())
+	}
+}
+
+func TestStreamFailedExit(t *testing.T) {
+	useFakeTools(t, map[string]string{"FRAMES": "2", "EXIT": "1"})
+	s, _ := runStream(t)
+	if s.EndReason() != EndError {
+		t.Errorf("reason %s, want error", s.EndReason())
+	}
+}
+
+func TestStreamHungAfterOutput(t *testing.T) {
+	useFakeTools(t, map[string]string{"FRAMES": "2", "HANG": "1"})
+	start := time.Now()
+	s, _ := runStream(t)
+
+	// Killed once exitGrace runs out, which is no clean exit
+	if took := time.Since(start); took < exitGrace || took > exitGrace+5*time.Second {
+		t.Errorf("reaped after %v, want about %v", took, exitGrace)
+	}
+	if s.EndReason() != EndError {
+		t.Errorf("reason %s, want error", s.EndReason())
+	}
+	if pids := fakeff.ChildProcesses(t); len(pids) != 0 {
+		t.Errorf("children left: %v", pids)
+	}
+}
+
+// Run with -race: starting and stopping mid-stream leaves no processes,
+// zombies included, and no goroutines behind
+func TestStreamStartStopStress(t *testing.T) {
+	useFakeTools(t, map[string]string{"FRAMES": "100000", "INTERVAL": "5ms"})
+	path, config := fakeStreamInput(t)
+	before := fakeff.ChildProcesses(t)
+	goroutines := runtime.NumGoroutine()
+
+	buffer := NewFrameBuffer()
+	for i := range 50 {
+		epoch := buffer.Reset()
+		s, err := StartStream(context.Background(), path, config, epoch, Logs{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		go s.ReadFrames(buffer, Logs{})
+		if i%2 == 0 {
+			time.Sleep(time.Duration(i%5) * time.Millisecond)
+		}
+		s.Stop(nil)
+		if s.EndReason() != EndStopped {
+			t.Fatalf("run %d: reason %s, want stopped", i, s.EndReason())
+		}
+	}
+
+	if after := fakeff.ChildProcesses(t); !slices.Equal(after, before) {
+		t.Errorf("children %v, want %v", after, before)
+	}
+	fakeff.WaitGoroutines(t, goroutines)
+}
+
+func TestStreamStopDuringExitGrace(t *testing.T) {
+	useFakeTools(t, map[string]string{"FRAMES": "2", "HANG": "1"})
+	path, config := fakeStreamInput(t)
+	buffer := NewFrameBuffer()
+	s, err := StartStream(context.Background(), path, config, buffer.Epoch(), Logs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.ReadFrames(buffer, Logs{})
+	for buffer.FrameCount() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	// Time for the read loop to see EOF and wait for the hung process
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	s.Stop(nil)
+	if took := time.Since(start); took > exitGrace/2 {
+		t.Errorf("Stop waited %v for the grace period", took)
+	}
+	if s.EndReason() != EndStopped {
+		t.Errorf("reason %s, want stopped", s.EndReason())
+	}
+}
+
+// A stall in the pipe drops a run of frames; the timestamps after it still
+// come from the count of frames read, never running ahead of the media
+func TestStreamTimestampAfterDrops(t *testing.T) {
+	const frames = 100
+	useFakeTools(t, map[string]string{"FRAMES": strconv.Itoa(frames), "INTERVAL": "5ms", "STALL": "400ms"})
+	path, config := fakeStreamInput(t)
+	config.StartPos = 5 * time.Second
+	buffer := NewFrameBuffer()
+	s, err := StartStream(context.Background(), path, config, buffer.Epoch(), Logs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.ReadFrames(buffer, Logs{})
+
+	if buffer.DroppedFrames() == 0 {
+		t.Fatal("the stall dropped no frames")
+	}
+	if got := buffer.FrameCount() + buffer.DroppedFrames(); got != frames {
+		t.Errorf("%d frames shown or dropped, want %d", got, frames)
+	}
+
+	// The fake fills frame i with byte i, so the last frame tells its index
+	frameDuration := time.Second / time.Duration(config.TargetFPS)
+	last := buffer.Load()
+	index := time.Duration(last.Image.Pix[0])
+	if index != frames-1 {
+		t.Fatalf("last frame shown is %d, want %d", index, frames-1)
+	}
+	if want := config.StartPos + index*frameDuration; last.Timestamp != want {
+		t.Errorf("last timestamp %v, want %v", last.Timestamp, want)
+	}
+	s.mu.Lock()
+	position := s.position
+	s.mu.Unlock()
+	if want := config.StartPos + frames*frameDuration; position != want {
+		t.Errorf("position at the end %v, want %v", position, want)
+	}
+}
+
+// Returns the values of the -ss options before and after -i in args
+func seekOptions(t *testing.T, args []string) (input, output []string) {
+	t.Helper()
+	at := slices.Index(args, "-i")
+	if at < 0 {
+		t.Fatalf("no -i in %q", args)
+	}
+	for i, arg := range args[:len(args)-1] {
+		if arg != "-ss" {
+			continue
+		}
+		if i < at {
+			input = append(input, args[i+1])
+		} else {
+			output = append(output, args[i+1])
+		}
+	}
+	return input, output
+}
+
+// The fast seek only seeks the input; the exact one splits at
+// exactSeekMargin, with the input -ss before -i and the rest after it
+func TestBuildFFmpegArgsSeek(t *testing.T) {
+	tests := []struct {
+		name   string
+		config StreamConfig
+		input  []string
+		output []string
+	}{
+		{"start", StreamConfig{}, nil, nil},
+		{"fast", StreamConfig{StartPos: 90 * time.Second}, []string{"90.000"}, nil},
+		{"fast fraction", StreamConfig{StartPos: 1500 * time.Millisecond}, []string{"1.500"}, nil},
+		{"exact", StreamConfig{StartPos: 90 * time.Second, ExactSeek: true}, []string{"88.000"}, []string{"2.000"}},
+		{"exact fraction", StreamConfig{StartPos: 12345 * time.Millisecond, ExactSeek: true}, []string{"10.345"}, []string{"2.000"}},
+		// Within the margin of the start there is no input seek at all
+		{"exact at margin", StreamConfig{StartPos: exactSeekMargin, ExactSeek: true}, nil, []string{"2.000"}},
+		{"exact near start", StreamConfig{StartPos: 1500 * time.Millisecond, ExactSeek: true}, nil, []string{"1.500"}},
+		{"exact with duration", StreamConfig{StartPos: 30 * time.Second, ExactSeek: true, Duration: 5 * time.Second}, []string{"28.000"}, []string{"2.000"}},
+		// Live input joins the live edge; StartPos only offsets timestamps
+		{"live", StreamConfig{StartPos: 90 * time.Second, ExactSeek: true, Live: true}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.TargetFPS = 25
+			args := buildFFmpegArgs("file:/clip.mp4", 80, 40, tt.config)
+			input, output := seekOptions(t, args)
+			if !slices.Equal(input, tt.input) || !slices.Equal(output, tt.output) {
+				t.Errorf("input -ss %q, output -ss %q; want %q and %q in %q", input, output, tt.input, tt.output, args)
+			}
+
+			// -t is an output option too, after -i and both seeks
+			if tt.config.Duration > 0 {
+				last := slices.Index(args, "-i")
+				for i, arg := range args {
+					if arg == "-ss" {
+						last = max(last, i)
+					}
+				}
+				if i := slices.Index(args, "-t"); i < last || args[i+1] != "5.000" {
+					t.Errorf("-t misplaced in %q", args)
+				}
+			}
+		})
+	}
+}
+
+func TestScaleFilter(t *testing.T) {
+	tests := []struct {
+		colorRange string
+		want       string
+	}{
+		{"tv", "scale=64:16:in_range=limited:out_range=full"},
+		{"pc", "scale=64:16"},
+		{"", "scale=64:16"},
+		{"unknown", "scale=64:16"},
+	}
+	for _, tt := range tests {
+		if got := scaleFilter(64, 16, tt.colorRange); got != tt.want {
+			t.Errorf("scaleFilter(%q) = %q, want %q", tt.colorRange, got, tt.want)
+		}
+	}
+}
+
+// A gray ramp over the whole range of its tag decodes to black at 0 and
+// white at 255: limited range is expanded, full range passes through
+func TestStreamColorRange(t *testing.T) {
+	ffmpeg := realFFmpeg(t)
+	tests := []struct {
+		colorRange string
+		luma       string // geq expression for the ramp across 64 columns
+	}{
+		{"tv", "16+X*219/63"},
+		{"pc", "X*255/63"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.colorRange, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ramp.mkv")
+			gen := exec.Command(ffmpeg, "-v", "error", "-f", "lavfi",
+				"-i", "nullsrc=s=64x16:d=0.2,format=yuv444p,geq=lum='"+tt.luma+"':cb=128:cr=128",
+				"-color_range", tt.colorRange, "-c:v", "ffv1", path)
+			if out, err := gen.CombinedOutput(); err != nil {
+				t.Skipf("generating the ramp failed: %v: %s", err, out)
+			}
+			meta, err := ProbeContext(context.Background(), path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta.ColorRange != tt.colorRange {
+				t.Fatalf("ColorRange = %q, want %q", meta.ColorRange, tt.colorRange)
+			}
+
+			config := StreamConfig{Width: 64, Height: 16, TargetFPS: 25, ColorRange: meta.ColorRange}
+			buffer := NewFrameBuffer()
+			s, err := StartStream(context.Background(), path, config, buffer.Epoch(), Logs{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.ReadFrames(buffer, Logs{})
+			frame := buffer.Load()
+			if frame == nil {
+				t.Fatal("no frame decoded")
+			}
+			pix := frame.Image.Pix
+			black, white := pix[0], pix[63*4]
+			if black > 2 || white < 253 {
+				t.Errorf("black %d, white %d; want 0 and 255", black, white)
+			}
+		})
+	}
+}
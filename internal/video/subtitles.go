@@ -0,0 +1,49 @@
+package video
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Subtitle codecs drawn as pictures; the subtitles filter only renders
+// text formats through libass
+var bitmapSubtitleCodecs = map[string]bool{
+	"hdmv_pgs_subtitle": true,
+	"dvd_subtitle":      true,
+	"dvb_subtitle":      true,
+	"xsub":              true,
+}
+
+// Reports whether the subtitles filter can render the stream, a subtitle
+// stream in a text format
+func (s StreamInfo) TextSubtitle() bool {
+	return s.Type == "subtitle" && !bitmapSubtitleCodecs[s.Codec]
+}
+
+// Returns the filters burning subtitle track si of input into the frames.
+// The filter times subtitles by frame timestamps, which restart at 0 after
+// an input seek, so they are shifted to file time around it.
+func subtitleFilter(input string, si int, startPos time.Duration) string {
+	filter := fmt.Sprintf("subtitles=filename=%s:si=%d", escapeFilterValue(input), si)
+	if startPos <= 0 {
+		return filter
+	}
+	return fmt.Sprintf("setpts=PTS+%.3f/TB,%s,setpts=PTS-STARTPTS", startPos.Seconds(), filter)
+}
+
+// Escapes a filter option value, then the result for the filtergraph, so
+// paths with colons, quotes or commas (and Windows drive letters) survive
+func escapeFilterValue(s string) string {
+	escape := func(s, special string) string {
+		var sb strings.Builder
+		for _, c := range s {
+			if strings.ContainsRune(special, c) {
+				sb.WriteByte('\\')
+			}
+			sb.WriteRune(c)
+		}
+		return sb.String()
+	}
+	return escape(escape(s, `\':`), `\'[],;`)
+}
@@ -0,0 +1,114 @@
+package video
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Oldest ffmpeg major version pixlgo is tested with
+const MinFFmpegMajor = 4
+
+// Filters the decode, batch and convert paths depend on
+var RequiredFilters = []string{"fps", "scale", "trim", "concat", "setsar", "setpts"}
+
+// Result of looking for ffmpeg or ffprobe
+type ToolInfo struct {
+	Name    string `json:"name"`
+	Found   bool   `json:"found"`
+	Path    string `json:"path,omitempty"`
+	Version string `json:"version,omitempty"`
+
+	// Parsed from Version; zero for git snapshots ("N-12345-g...")
+	Major int `json:"major,omitempty"`
+	Minor int `json:"minor,omitempty"`
+}
+
+// Reports whether the version is new enough. Unparseable versions, such as
+// git snapshot builds, are assumed to be recent.
+func (t ToolInfo) MeetsMinimum() bool {
+	return t.Found && (t.Major == 0 || t.Major >= MinFFmpegMajor)
+}
+
+// Locates a tool and reads its version. A missing tool is not an error;
+// Found is false instead.
+func DetectTool(ctx context.Context, name string) ToolInfo {
+	info := ToolInfo{Name: name}
+	path, err := exec.LookPath(toolPath(name))
+	if err != nil {
+		return info
+	}
+	info.Found = true
+	info.Path = path
+
+	out, err := newCommand(ctx, name, "-hide_banner", "-version").Output()
+	if err != nil {
+		return info
+	}
+	info.Version, info.Major, info.Minor = parseToolVersion(out)
+	return info
+}
+
+// Parses the first line of -version output, e.g.
+// "ffmpeg version n6.1.1-3 Copyright ..." or "ffprobe version 4.4.2-0ubuntu..."
+func parseToolVersion(out []byte) (string, int, int) {
+	line, _, _ := bytes.Cut(out, []byte("\n"))
+	_, rest, ok := strings.Cut(string(line), " version ")
+	if !ok {
+		return "", 0, 0
+	}
+	version, _, _ := strings.Cut(rest, " ")
+
+	nums := strings.TrimPrefix(version, "n")
+	majorStr, rest, _ := strings.Cut(nums, ".")
+	major, err := strconv.Atoi(majorStr)
+	if err != nil {
+		return version, 0, 0
+	}
+	minorStr := strings.FieldsFunc(rest, func(r rune) bool { return r < '0' || r > '9' })
+	minor := 0
+	if len(minorStr) > 0 {
+		minor, _ = strconv.Atoi(minorStr[0])
+	}
+	return version, major, minor
+}
+
+// Returns the names of the filters ffmpeg was built with
+func FFmpegFilters(ctx context.Context) (map[string]bool, error) {
+	out, err := newCommand(ctx, "ffmpeg", "-hide_banner", "-filters").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	// Lines look like " TSC scale  V->V  Scale the input video size..."
+	filters := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 3 && strings.Contains(fields[2], "->") {
+			filters[fields[1]] = true
+		}
+	}
+	return filters, nil
+}
+
+// Returns the hardware acceleration methods ffmpeg supports
+func FFmpegHWAccels(ctx context.Context) ([]string, error) {
+	out, err := newCommand(ctx, "ffmpeg", "-hide_banner", "-hwaccels").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var accels []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasSuffix(line, ":") {
+			accels = append(accels, line)
+		}
+	}
+	return accels, nil
+}
@@ -0,0 +1,92 @@
+package video
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Format asked of yt-dlp: the best video up to 480 lines plus the best
+// audio, else the best single file. The terminal shows far fewer pixels.
+const ytdlpFormat = "bv*[height<=480]+ba/b"
+
+// Direct media URLs yt-dlp found for a web page; audio is empty when the
+// video URL carries the sound too
+type webMedia struct {
+	title string
+	video string
+	audio string
+}
+
+// Resolved pages, so replays in the same session don't run yt-dlp again
+var (
+	webMu    sync.Mutex
+	webCache = map[string]webMedia{}
+)
+
+// Reports whether a failed probe of path may be a web page that yt-dlp
+// can resolve: an http(s) URL that ffprobe reached but couldn't read
+func isWebPage(path string, err error) bool {
+	var perr *ProbeError
+	if !errors.As(err, &perr) || perr.Timeout > 0 {
+		return false
+	}
+	scheme, _ := urlScheme(path)
+	return scheme == "http" || scheme == "https"
+}
+
+// Runs yt-dlp to find the media behind a web page such as a YouTube watch
+// URL. Disabled with a Tools.YTDLP of "none".
+func resolveWebURL(ctx context.Context, page string) (webMedia, error) {
+	webMu.Lock()
+	media, ok := webCache[page]
+	webMu.Unlock()
+	if ok {
+		return media, nil
+	}
+
+	if currentTools().YTDLP == "none" {
+		return webMedia{}, errors.New("not a media URL, and yt-dlp is disabled")
+	}
+	if !toolAvailable("yt-dlp") {
+		return webMedia{}, errors.New("not a media URL, and yt-dlp isn't installed to resolve web pages")
+	}
+
+	// --print urls is what -g prints; the title comes first
+	args := append(ytdlpHTTPArgs(page),
+		"--no-playlist", "--no-warnings",
+		"-f", ytdlpFormat,
+		"--print", "title", "--print", "urls",
+		page,
+	)
+	cmd := newCommand(ctx, "yt-dlp", args...)
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return webMedia{}, ctx.Err()
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return webMedia{}, fmt.Errorf("yt-dlp: %s", stderrSnippet(exitErr.Stderr))
+		}
+		return webMedia{}, fmt.Errorf("yt-dlp: %w", err)
+	}
+
+	title, urls, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	lines := strings.Fields(urls)
+	if len(lines) == 0 {
+		return webMedia{}, errors.New("yt-dlp found no media URL")
+	}
+	media = webMedia{title: strings.TrimSpace(title), video: lines[0]}
+	if len(lines) > 1 {
+		media.audio = lines[1]
+	}
+
+	webMu.Lock()
+	webCache[page] = media
+	webMu.Unlock()
+	return media, nil
+}
@@ -0,0 +1,110 @@
+package web
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/0bVdnt/PixlGo/internal/player"
+	"github.com/0bVdnt/PixlGo/internal/video"
+)
+
+// One exported value. Counters only go up; gauges are read on each scrape.
+type metric struct {
+	name    string
+	help    string
+	counter bool
+	value   func() float64
+}
+
+// Returns the built-in metrics followed by any added with AddGauge
+func (s *Server) metrics() []metric {
+	gauge := func(fn func(player.Status) float64) func() float64 {
+		return func() float64 {
+			if p := s.current(); p != nil {
+				return fn(p.Status())
+			}
+			return 0
+		}
+	}
+	counter := func(v interface{ Load() uint64 }) func() float64 {
+		return func() float64 { return float64(v.Load()) }
+	}
+
+	list := []metric{
+		{"pixlgo_frames_decoded_total", "Frames read from ffmpeg.", true, counter(&video.Stats.FramesDecoded)},
+		{"pixlgo_frames_rendered_total", "Distinct frames drawn to the terminal.", true, counter(&player.Stats.FramesRendered)},
+		{"pixlgo_frames_dropped_total", "Frames skipped for running late.", true, counter(&video.Stats.FramesDropped)},
+		{"pixlgo_restarts_total", "Decode restarts for seeks, resizes and resumes.", true, counter(&player.Stats.Restarts)},
+		{"pixlgo_ffmpeg_spawns_total", "FFmpeg decode processes started.", true, counter(&video.Stats.Respawns)},
+		{"pixlgo_decode_errors_total", "Streams that failed to start or decode.", true, counter(&video.Stats.Errors)},
+		{"pixlgo_playback_errors_total", "Playbacks that ended in the error state.", true, counter(&player.Stats.Errors)},
+		{"pixlgo_fps", "Frames drawn per second over the last second.", false, player.FPS},
+		{"pixlgo_buffer_frames", "Decoded frames waiting to be drawn.", false,
+			gauge(func(st player.Status) float64 { return float64(st.Buffered) })},
+		{"pixlgo_position_seconds", "Playback position.", false,
+			gauge(func(st player.Status) float64 { return st.Position.Seconds() })},
+	}
+	s.extraMu.Lock()
+	list = append(list, s.extra...)
+	s.extraMu.Unlock()
+	return list
+}
+
+// Adds a gauge to /metrics and /debug/vars, e.g. connected clients. name
+// gets the pixlgo_ prefix.
+func (s *Server) AddGauge(name, help string, fn func() float64) {
+	s.extraMu.Lock()
+	defer s.extraMu.Unlock()
+	s.extra = append(s.extra, metric{"pixlgo_" + name, help, false, fn})
+}
+
+// Serves all metrics in the Prometheus text exposition format
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetrics(w, s.metrics())
+}
+
+func writeMetrics(w io.Writer, list []metric) {
+	for _, m := range list {
+		kind := "gauge"
+		if m.counter {
+			kind = "counter"
+		}
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n",
+			m.name, m.help, m.name, kind, m.name,
+			strconv.FormatFloat(m.value(), 'g', -1, 64))
+	}
+}
+
+// expvar names are process-global and Publish panics on duplicates, so the
+// variable is registered once and reads whichever server started last
+var (
+	publishOnce sync.Once
+	published   struct {
+		sync.Mutex
+		s *Server
+	}
+)
+
+// Publishes the metrics as the "pixlgo" expvar map, served at /debug/vars
+func (s *Server) publishExpvar() {
+	published.Lock()
+	published.s = s
+	published.Unlock()
+	publishOnce.Do(func() {
+		expvar.Publish("pixlgo", expvar.Func(func() any {
+			published.Lock()
+			s := published.s
+			published.Unlock()
+			vars := map[string]float64{}
+			for _, m := range s.metrics() {
+				vars[m.name] = m.value()
+			}
+			return vars
+		}))
+	})
+}
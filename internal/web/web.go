@@ -0,0 +1,197 @@
+// Package web serves the playing video's status, current frame and metrics
+// over HTTP, for peeking at a player running on a headless box.
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"expvar"
+	"image/png"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/0bVdnt/PixlGo/internal/logger"
+	"github.com/0bVdnt/PixlGo/internal/player"
+	"github.com/0bVdnt/PixlGo/internal/renderer"
+)
+
+// How long an encoded PNG is reused, so polling in a loop stays cheap
+const pngCacheTTL = time.Second
+
+// Serves /status, /frame.png, /frame.txt, /metrics and /debug/vars for
+// whichever player current returns; nil means nothing is playing right now
+type Server struct {
+	current func() *player.Player
+	log     *logger.Logger
+	srv     *http.Server
+
+	mu       sync.Mutex
+	png      []byte
+	pngAt    time.Time
+	pngOwner *player.Player
+
+	extraMu sync.Mutex
+	extra   []metric
+}
+
+// Listens on addr and serves in the background until Close
+func Start(addr string, current func() *player.Player, log *logger.Logger) (*Server, error) {
+	if log == nil {
+		log = logger.Noop()
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{current: current, log: log}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("GET /frame.png", s.handlePNG)
+	mux.HandleFunc("GET /frame.txt", s.handleText)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	mux.Handle("GET /debug/vars", expvar.Handler())
+	s.publishExpvar()
+	s.srv = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	log.Info("http server listening", "addr", ln.Addr().String())
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Error("http server failed", "err", err)
+		}
+	}()
+	return s, nil
+}
+
+// Stops the server, giving in-flight requests a moment to finish
+func (s *Server) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.srv.Shutdown(ctx); err != nil {
+		s.srv.Close()
+	}
+}
+
+// JSON form of player.Status; times are in seconds
+type status struct {
+	File     string   `json:"file,omitempty"`
+	State    string   `json:"state"`
+	Error    string   `json:"error,omitempty"`
+	Position float64  `json:"position"`
+	Duration *float64 `json:"duration"`
+
+	Codec  string  `json:"codec,omitempty"`
+	Width  int     `json:"width,omitempty"`
+	Height int     `json:"height,omitempty"`
+	FPS    float64 `json:"fps,omitempty"`
+
+	Stats stats `json:"stats"`
+}
+
+type stats struct {
+	Frames   uint64  `json:"frames"`
+	Dropped  uint64  `json:"dropped"`
+	Buffered int     `json:"buffered"`
+	FPS      float64 `json:"fps"`
+	FrameW   int     `json:"frame_width"`
+	FrameH   int     `json:"frame_height"`
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	out := status{State: player.StateStopped.String()}
+	if p := s.current(); p != nil {
+		st := p.Status()
+		out = status{
+			File:     st.File,
+			State:    st.State.String(),
+			Error:    st.Error,
+			Position: st.Position.Seconds(),
+			Codec:    st.Codec,
+			Width:    st.Width,
+			Height:   st.Height,
+			FPS:      st.FPS,
+			Stats: stats{
+				Frames:   st.Frames,
+				Dropped:  st.Dropped,
+				Buffered: st.Buffered,
+				FPS:      player.FPS(),
+				FrameW:   st.FrameW,
+				FrameH:   st.FrameH,
+			},
+		}
+		if st.DurationKnown {
+			d := st.Duration.Seconds()
+			out.Duration = &d
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(out)
+}
+
+func (s *Server) handlePNG(w http.ResponseWriter, r *http.Request) {
+	p := s.current()
+	if p == nil {
+		http.Error(w, "nothing playing", http.StatusServiceUnavailable)
+		return
+	}
+	data, err := s.encodePNG(p)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if data == nil {
+		http.Error(w, "no frame yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(data)
+}
+
+// Returns the current frame as PNG, re-encoding at most once per
+// pngCacheTTL. Holding the lock while encoding makes concurrent requests
+// share one encode instead of each starting their own.
+func (s *Server) encodePNG(p *player.Player) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pngOwner == p && time.Since(s.pngAt) < pngCacheTTL {
+		return s.png, nil
+	}
+
+	frame := p.Snapshot()
+	if frame == nil || frame.Image == nil {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, frame.Image); err != nil {
+		return nil, err
+	}
+	s.png, s.pngAt, s.pngOwner = buf.Bytes(), time.Now(), p
+	return s.png, nil
+}
+
+func (s *Server) handleText(w http.ResponseWriter, r *http.Request) {
+	p := s.current()
+	if p == nil {
+		http.Error(w, "nothing playing", http.StatusServiceUnavailable)
+		return
+	}
+	frame := p.Snapshot()
+	if frame == nil || frame.Image == nil {
+		http.Error(w, "no frame yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte(renderer.RenderColor(frame.Image)))
+}